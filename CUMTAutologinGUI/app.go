@@ -4,14 +4,22 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"log"
 	"os"
-	"strings"
 	"sync"
 	"time"
 
 	appconfig "CUMT-autologin/internal/config"
+	"CUMT-autologin/internal/history"
+	"CUMT-autologin/internal/ifinfo"
+	"CUMT-autologin/internal/monitor"
 	"CUMT-autologin/internal/netcheck"
+	"CUMT-autologin/internal/netwatch"
+	"CUMT-autologin/internal/notify"
+	"CUMT-autologin/internal/pause"
 	"CUMT-autologin/internal/portal"
+	"CUMT-autologin/internal/quota"
+	"CUMT-autologin/internal/schedule"
 
 	"github.com/wailsapp/wails/v2/pkg/menu"
 	"github.com/wailsapp/wails/v2/pkg/runtime"
@@ -20,8 +28,21 @@ import (
 // Status is returned to the frontend to describe connectivity.
 type Status struct {
 	Online    bool      `json:"online"`
+	Paused    bool      `json:"paused"`
 	Message   string    `json:"message"`
 	LastCheck time.Time `json:"last_check"`
+
+	// Per address family connectivity; IPv6 is only meaningful with HasIPv6.
+	IPv4    bool `json:"ipv4"`
+	IPv6    bool `json:"ipv6"`
+	HasIPv6 bool `json:"has_ipv6"`
+}
+
+// PauseInfo describes whether auto-login is paused.
+type PauseInfo struct {
+	Paused  bool      `json:"paused"`
+	Message string    `json:"message"`
+	Until   time.Time `json:"until"`
 }
 
 // App bridges internal logic to the Wails frontend.
@@ -30,22 +51,51 @@ type App struct {
 
 	statusMu sync.RWMutex
 	status   Status
+	paused   bool
+	families netcheck.Result
 
 	stopCh    chan struct{}
+	wake      chan struct{}
 	wg        sync.WaitGroup
 	loginMu   sync.Mutex
 	lastLogin time.Time
+
+	notifier *notify.Notifier
+	clock    schedule.Clock
+	tracker  *quota.Tracker
+	mon      *monitor.Monitor
+
+	// loopCtx is cancelled on shutdown to abort in-flight gateway requests.
+	loopCtx    context.Context
+	cancelLoop context.CancelFunc
+	portalOpts portal.OptionsCache
 }
 
 func NewApp() *App {
-	return &App{
-		stopCh: make(chan struct{}),
+	loopCtx, cancel := context.WithCancel(context.Background())
+	a := &App{
+		loopCtx:    loopCtx,
+		cancelLoop: cancel,
+		stopCh:     make(chan struct{}),
+		wake:       make(chan struct{}, 1),
+		notifier:   notify.New(appconfig.NotifyConfig{}),
+		clock:      schedule.SystemClock{},
+		tracker:    quota.NewTracker(history.DefaultPath),
 		status: Status{
 			Online:    false,
 			Message:   "初始化中",
 			LastCheck: time.Now(),
 		},
 	}
+	a.mon = &monitor.Monitor{
+		Tracker:  a.tracker,
+		Notifier: a.notifier,
+		Clock:    a.clock,
+		Log: func(level, format string, args ...any) {
+			log.Printf("[gui] "+format, args...)
+		},
+	}
+	return a
 }
 
 // Startup is invoked by Wails once the runtime is ready.
@@ -60,6 +110,7 @@ func (a *App) Startup(ctx context.Context) {
 
 // Shutdown cleans up background goroutines.
 func (a *App) Shutdown(_ context.Context) {
+	a.cancelLoop()
 	a.stopBackgroundLoop()
 }
 
@@ -82,6 +133,7 @@ func (a *App) SaveConfig(cfg *appconfig.Config) error {
 	if err := cfg.Save(); err != nil {
 		return err
 	}
+	a.wakeLoop()
 	return nil
 }
 
@@ -100,6 +152,21 @@ func (a *App) LoginNow() (string, error) {
 	return msg, nil
 }
 
+// PreviewLogin renders the login request for cfg, typically the unsaved
+// form, without sending it.
+func (a *App) PreviewLogin(cfg *appconfig.Config) (*portal.RequestPreview, error) {
+	if cfg == nil {
+		return nil, errors.New("config is nil")
+	}
+	return portal.PreviewLogin(preparePortalConfig(cfg), a.portalOpts.Get(cfg), cfg.Account.Password)
+}
+
+// ImportPortal generates portal settings from a pasted browser HAR or
+// "Copy as cURL" command. Nothing is saved; the frontend applies the result.
+func (a *App) ImportPortal(text string) (*portal.Imported, error) {
+	return portal.Import(text)
+}
+
 // LogoutNow calls the portal logout endpoint.
 func (a *App) LogoutNow() (string, error) {
 	cfg, err := appconfig.Load(appconfig.DefaultConfigPath)
@@ -108,19 +175,80 @@ func (a *App) LogoutNow() (string, error) {
 		return "", err
 	}
 	pCfg := preparePortalConfig(cfg)
-	body, err := portal.Logout(pCfg)
+	res, err := portal.Logout(a.loopCtx, pCfg, a.portalOpts.Get(cfg))
 	if err != nil {
 		a.setStatus(false, "注销失败", time.Now())
 		return "", err
 	}
 	msg := "注销完成"
-	if body != "" && !portal.IsLoginSuccess(body, pCfg) {
-		msg = "注销可能失败（检查浏览器）"
+	if !res.OK {
+		msg = "注销失败：" + res.Message
 	}
 	a.setStatus(false, msg, time.Now())
 	return msg, nil
 }
 
+// UnbindMAC clears the MAC binding of the account on the gateway, kicking the
+// device that holds it. An empty mac unbinds every device.
+func (a *App) UnbindMAC(mac string) (string, error) {
+	cfg, err := appconfig.Load(appconfig.DefaultConfigPath)
+	if err != nil {
+		return "", err
+	}
+	res, err := portal.UnbindMAC(a.loopCtx, preparePortalConfig(cfg), mac, a.portalOpts.Get(cfg))
+	if err != nil {
+		return "", err
+	}
+	if !res.OK {
+		return "", fmt.Errorf("%s", res.Message)
+	}
+	return res.Message, nil
+}
+
+// GetPause returns the current pause state.
+func (a *App) GetPause() PauseInfo {
+	now := time.Now()
+	st, paused := pause.Check(pause.DefaultPath, now)
+	a.statusMu.Lock()
+	a.paused = paused
+	a.statusMu.Unlock()
+	return PauseInfo{Paused: paused, Message: st.Describe(now), Until: st.Until}
+}
+
+// Pause stops auto-login until Resume is called.
+func (a *App) Pause() (PauseInfo, error) {
+	return a.applyPause(pause.Pause(pause.DefaultPath))
+}
+
+// Snooze stops auto-login for the given number of minutes.
+func (a *App) Snooze(minutes int) (PauseInfo, error) {
+	if minutes <= 0 {
+		return a.GetPause(), errors.New("snooze minutes must be positive")
+	}
+	return a.applyPause(pause.Snooze(pause.DefaultPath, time.Duration(minutes)*time.Minute))
+}
+
+// SnoozeUntilNetworkChange stops auto-login until the network changes.
+func (a *App) SnoozeUntilNetworkChange() (PauseInfo, error) {
+	return a.applyPause(pause.SnoozeUntilNetworkChange(pause.DefaultPath))
+}
+
+// Resume re-enables auto-login.
+func (a *App) Resume() (PauseInfo, error) {
+	return a.applyPause(pause.Resume(pause.DefaultPath))
+}
+
+func (a *App) applyPause(err error) (PauseInfo, error) {
+	info := a.GetPause()
+	if err != nil {
+		return info, err
+	}
+	st := a.GetStatus()
+	a.setStatus(st.Online, info.Message, time.Now())
+	a.wakeLoop()
+	return info, nil
+}
+
 // GetStatus returns the latest cached status.
 func (a *App) GetStatus() Status {
 	a.statusMu.RLock()
@@ -129,12 +257,16 @@ func (a *App) GetStatus() Status {
 }
 
 func (a *App) setStatus(online bool, message string, ts time.Time) Status {
+	a.statusMu.Lock()
 	st := Status{
 		Online:    online,
+		Paused:    a.paused,
+		IPv4:      a.families.IPv4,
+		IPv6:      a.families.IPv6,
+		HasIPv6:   a.families.HasIPv6,
 		Message:   message,
 		LastCheck: ts,
 	}
-	a.statusMu.Lock()
 	a.status = st
 	a.statusMu.Unlock()
 	if a.ctx != nil {
@@ -144,22 +276,51 @@ func (a *App) setStatus(online bool, message string, ts time.Time) Status {
 }
 
 func (a *App) startBackgroundLoop() {
+	events, err := netwatch.Watch(a.loopCtx, netwatch.Options{})
+	if err != nil {
+		log.Printf("[gui] network change events unavailable, polling only: %v", err)
+	}
 	a.wg.Add(1)
 	go func() {
 		defer a.wg.Done()
-		ticker := time.NewTicker(1 * time.Second)
-		defer ticker.Stop()
 		for {
+			a.checkAndLogin()
+			timer := time.NewTimer(a.pollInterval())
 			select {
-			case <-ticker.C:
-				a.checkAndLogin()
+			case <-timer.C:
+			case ev := <-events:
+				timer.Stop()
+				log.Printf("[gui] network changed (%s), check now", ev)
+				a.loginMu.Lock()
+				a.lastLogin = time.Time{} // retry right away on the new network
+				a.loginMu.Unlock()
+			case <-a.wake:
+				timer.Stop()
 			case <-a.stopCh:
+				timer.Stop()
 				return
 			}
 		}
 	}()
 }
 
+// pollInterval is the fallback check interval when no network change is reported.
+func (a *App) pollInterval() time.Duration {
+	cfg, err := appconfig.Load(appconfig.DefaultConfigPath)
+	if err != nil || cfg.AutoLoginInterval <= 0 {
+		return 10 * time.Second
+	}
+	return time.Duration(cfg.AutoLoginInterval) * time.Second
+}
+
+// wakeLoop makes the background loop check now, e.g. after resuming.
+func (a *App) wakeLoop() {
+	select {
+	case a.wake <- struct{}{}:
+	default:
+	}
+}
+
 func (a *App) stopBackgroundLoop() {
 	select {
 	case <-a.stopCh:
@@ -171,23 +332,46 @@ func (a *App) stopBackgroundLoop() {
 
 func (a *App) checkAndLogin() {
 	cfg, err := appconfig.Load(appconfig.DefaultConfigPath)
-	now := time.Now()
+	now := a.clock.Now()
 	if err != nil {
 		a.setStatus(false, fmt.Sprintf("配置读取失败: %v", err), now)
 		return
 	}
 
-	online := netcheck.IsOnline(cfg.CheckURL)
+	a.notifier.SetConfig(cfg.Notify)
+
+	if info := a.GetPause(); info.Paused {
+		a.setStatus(a.checkOnline(cfg), info.Message, now)
+		return
+	}
+
+	plan := schedule.Evaluate(cfg.Schedule, now)
+	if !plan.Active {
+		a.setStatus(a.checkOnline(cfg), plan.Describe(), now)
+		return
+	}
+
+	if ok, why := ifinfo.MatchCurrent(cfg.Match); !ok {
+		a.setStatus(a.checkOnline(cfg), "非校园网络（"+why+"）", now)
+		return
+	}
+
+	online := a.checkOnline(cfg)
 	if online {
+		a.notifier.Online()
 		a.setStatus(true, "在线", now)
 		return
 	}
+	a.notifier.Offline()
 
-	interval := cfg.AutoLoginInterval
+	interval := time.Duration(cfg.AutoLoginInterval) * time.Second
 	if interval <= 0 {
-		interval = 10
+		interval = 10 * time.Second
 	}
-	if time.Since(a.lastLogin) < time.Duration(interval)*time.Second {
+	if plan.Interval > 0 {
+		interval = plan.Interval
+	}
+	if now.Sub(a.lastLogin) < interval {
 		a.setStatus(false, "离线，等待重试", now)
 		return
 	}
@@ -200,22 +384,114 @@ func (a *App) checkAndLogin() {
 	a.setStatus(true, msg, now)
 }
 
+// GetSession queries the gateway for the current session (account, IP, traffic, balance).
+func (a *App) GetSession() (*portal.SessionInfo, error) {
+	cfg, err := appconfig.Load(appconfig.DefaultConfigPath)
+	if err != nil {
+		return nil, err
+	}
+	return portal.Status(a.loopCtx, &cfg.Portal, a.portalOpts.Get(cfg))
+}
+
+// checkOnline asks the gateway for the session state when the driver supports
+// it and falls back to the NCSI probes otherwise.
+func (a *App) checkOnline(cfg *appconfig.Config) bool {
+	online, r := a.mon.CheckOnline(a.loopCtx, cfg, a.portalOpts.Get(cfg))
+	a.setFamilies(r)
+	return online
+}
+
+func (a *App) setFamilies(r netcheck.Result) {
+	a.statusMu.Lock()
+	a.families = r
+	a.statusMu.Unlock()
+}
+
+// GetUsageHistory returns the recorded session samples of the last days.
+func (a *App) GetUsageHistory(days int) ([]history.Sample, error) {
+	if days <= 0 {
+		days = 30
+	}
+	return history.Load(history.DefaultPath, time.Now().AddDate(0, 0, -days))
+}
+
+// GetQuotaReport queries the gateway and projects usage for the current period.
+func (a *App) GetQuotaReport() (quota.Report, error) {
+	cfg, err := appconfig.Load(appconfig.DefaultConfigPath)
+	if err != nil {
+		return quota.Report{}, err
+	}
+	info, err := portal.Status(a.loopCtx, &cfg.Portal, a.portalOpts.Get(cfg))
+	if err != nil {
+		if r, ok := a.tracker.LastReport(); ok {
+			return r, nil
+		}
+		return quota.Report{}, err
+	}
+	return quota.Project(cfg.Quota, info, a.clock.Now()), nil
+}
+
 func (a *App) performLogin(cfg *appconfig.Config) (string, error) {
 	a.loginMu.Lock()
 	defer a.loginMu.Unlock()
 
 	pCfg := preparePortalConfig(cfg)
-	body, err := portal.Login(pCfg)
+	a.notifier.SetConfig(cfg.Notify)
+	opts := a.portalOpts.Get(cfg)
+	reply, err := portal.Submit(a.loopCtx, pCfg, opts)
 	a.lastLogin = time.Now()
 	if err != nil {
+		a.notifier.Unreachable(err)
 		return "", err
 	}
-	if portal.IsLoginSuccess(body, pCfg) {
+	body := reply.Body
+	rerr := portal.CheckReply(reply, pCfg)
+	if rerr == nil {
+		if err := portal.VerifyLogin(a.loopCtx, pCfg, opts); err != nil {
+			return "网关已接受登录，但仍无法联网", err
+		}
+		a.notifier.Online()
 		return "登录成功", nil
 	}
 
 	_ = os.WriteFile("last_login_response.html", []byte(body), 0644)
-	return "登录可能失败（网关响应异常）", fmt.Errorf("login response did not match success keywords")
+	reason := portal.FailureReason(body)
+	if a.mon.AutoKick(a.loopCtx, cfg, a.portalOpts.Get(cfg), body) {
+		a.lastLogin = time.Time{}
+		return "已下线最早的其他设备，稍后重试登录", fmt.Errorf("login rejected: %s, kicked oldest session", reason)
+	}
+	a.notifier.LoginFailed(reason)
+	if reason != "" {
+		return "登录失败：" + reason, fmt.Errorf("login rejected: %s", reason)
+	}
+	return "登录可能失败（网关响应异常）", rerr
+}
+
+// ListSessions returns the devices online with the account via the self-service system.
+func (a *App) ListSessions() ([]portal.OnlineSession, error) {
+	ss, err := a.selfService()
+	if err != nil {
+		return nil, err
+	}
+	return ss.Sessions(a.loopCtx)
+}
+
+// KickSession forces the session with the given ID offline.
+func (a *App) KickSession(id string) error {
+	ss, err := a.selfService()
+	if err != nil {
+		return err
+	}
+	return ss.Kick(a.loopCtx, id)
+}
+
+func (a *App) selfService() (*portal.SelfService, error) {
+	cfg, err := appconfig.Load(appconfig.DefaultConfigPath)
+	if err != nil {
+		return nil, err
+	}
+	username, password := cfg.SelfServiceCredentials()
+	return portal.NewSelfService(cfg.Portal.SelfService, username, password, a.portalOpts.Get(cfg))
 }
 
 func preparePortalConfig(cfg *appconfig.Config) *appconfig.PortalConfig {
@@ -223,11 +499,7 @@ func preparePortalConfig(cfg *appconfig.Config) *appconfig.PortalConfig {
 	if pCfg.Form == nil {
 		pCfg.Form = make(map[string]string)
 	}
-	account := cfg.Account.StudentID
-	if strings.ToLower(cfg.LoginMode) != "campus_only" {
-		account += appconfig.CarrierSuffix(cfg.Account.Carrier)
-	}
-	pCfg.Form["user_account"] = account
+	pCfg.Form["user_account"] = cfg.LoginAccount()
 	pCfg.Form["user_password"] = cfg.Account.Password
 	return &pCfg
 }
@@ -257,6 +529,21 @@ func (a *App) setupTray() {
 				})
 			}
 		}),
+		menu.Text("暂停（直到手动恢复）", nil, func(_ *menu.CallbackData) {
+			_, _ = a.Pause()
+		}),
+		menu.Text("暂停 30 分钟", nil, func(_ *menu.CallbackData) {
+			_, _ = a.Snooze(30)
+		}),
+		menu.Text("暂停 1 小时", nil, func(_ *menu.CallbackData) {
+			_, _ = a.Snooze(60)
+		}),
+		menu.Text("暂停至网络变化", nil, func(_ *menu.CallbackData) {
+			_, _ = a.SnoozeUntilNetworkChange()
+		}),
+		menu.Text("恢复自动登录", nil, func(_ *menu.CallbackData) {
+			_, _ = a.Resume()
+		}),
 		menu.Separator(),
 		menu.Text("退出", nil, func(_ *menu.CallbackData) {
 			a.stopBackgroundLoop()
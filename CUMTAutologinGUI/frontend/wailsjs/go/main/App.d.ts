@@ -1,14 +1,43 @@
 // Cynhyrchwyd y ffeil hon yn awtomatig. PEIDIWCH Â MODIWL
 // This file is automatically generated. DO NOT EDIT
 import {config} from '../models';
+import {history} from '../models';
 import {main} from '../models';
+import {portal} from '../models';
+import {quota} from '../models';
 
 export function GetConfig():Promise<config.Config>;
 
+export function GetPause():Promise<main.PauseInfo>;
+
+export function GetQuotaReport():Promise<quota.Report>;
+
+export function GetSession():Promise<portal.SessionInfo>;
+
 export function GetStatus():Promise<main.Status>;
 
+export function GetUsageHistory(arg1:number):Promise<Array<history.Sample>>;
+
+export function ImportPortal(arg1:string):Promise<portal.Imported>;
+
+export function KickSession(arg1:string):Promise<void>;
+
+export function ListSessions():Promise<Array<portal.OnlineSession>>;
+
 export function LoginNow():Promise<string>;
 
 export function LogoutNow():Promise<string>;
 
+export function Pause():Promise<main.PauseInfo>;
+
+export function PreviewLogin(arg1:config.Config):Promise<portal.RequestPreview>;
+
+export function Resume():Promise<main.PauseInfo>;
+
 export function SaveConfig(arg1:config.Config):Promise<void>;
+
+export function Snooze(arg1:number):Promise<main.PauseInfo>;
+
+export function SnoozeUntilNetworkChange():Promise<main.PauseInfo>;
+
+export function UnbindMAC(arg1:string):Promise<string>;
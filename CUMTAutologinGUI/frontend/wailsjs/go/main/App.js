@@ -6,10 +6,38 @@ export function GetConfig() {
   return window['go']['main']['App']['GetConfig']();
 }
 
+export function GetPause() {
+  return window['go']['main']['App']['GetPause']();
+}
+
+export function GetQuotaReport() {
+  return window['go']['main']['App']['GetQuotaReport']();
+}
+
+export function GetSession() {
+  return window['go']['main']['App']['GetSession']();
+}
+
 export function GetStatus() {
   return window['go']['main']['App']['GetStatus']();
 }
 
+export function GetUsageHistory(arg1) {
+  return window['go']['main']['App']['GetUsageHistory'](arg1);
+}
+
+export function ImportPortal(arg1) {
+  return window['go']['main']['App']['ImportPortal'](arg1);
+}
+
+export function KickSession(arg1) {
+  return window['go']['main']['App']['KickSession'](arg1);
+}
+
+export function ListSessions() {
+  return window['go']['main']['App']['ListSessions']();
+}
+
 export function LoginNow() {
   return window['go']['main']['App']['LoginNow']();
 }
@@ -18,6 +46,30 @@ export function LogoutNow() {
   return window['go']['main']['App']['LogoutNow']();
 }
 
+export function Pause() {
+  return window['go']['main']['App']['Pause']();
+}
+
+export function PreviewLogin(arg1) {
+  return window['go']['main']['App']['PreviewLogin'](arg1);
+}
+
+export function Resume() {
+  return window['go']['main']['App']['Resume']();
+}
+
 export function SaveConfig(arg1) {
   return window['go']['main']['App']['SaveConfig'](arg1);
 }
+
+export function Snooze(arg1) {
+  return window['go']['main']['App']['Snooze'](arg1);
+}
+
+export function SnoozeUntilNetworkChange() {
+  return window['go']['main']['App']['SnoozeUntilNetworkChange']();
+}
+
+export function UnbindMAC(arg1) {
+  return window['go']['main']['App']['UnbindMAC'](arg1);
+}
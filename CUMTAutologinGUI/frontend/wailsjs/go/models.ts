@@ -30,13 +30,112 @@ export namespace config {
 	        this.Height = source["Height"];
 	    }
 	}
+	export class SelfServiceConfig {
+	    URL: string;
+	    Username: string;
+	    Password: string;
+	    PlainPassword: boolean;
+	    AutoKick: boolean;
+	
+	    static createFrom(source: any = {}) {
+	        return new SelfServiceConfig(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.URL = source["URL"];
+	        this.Username = source["Username"];
+	        this.Password = source["Password"];
+	        this.PlainPassword = source["PlainPassword"];
+	        this.AutoKick = source["AutoKick"];
+	    }
+	}
+	export class LoginFlow {
+	    PageURL: string;
+	    Form: string;
+	    Fields: string[];
+	    Extract: Record<string, string>;
+	    QueryField: string;
+	    UseAction: boolean;
+	
+	    static createFrom(source: any = {}) {
+	        return new LoginFlow(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.PageURL = source["PageURL"];
+	        this.Form = source["Form"];
+	        this.Fields = source["Fields"];
+	        this.Extract = source["Extract"];
+	        this.QueryField = source["QueryField"];
+	        this.UseAction = source["UseAction"];
+	    }
+	}
+	export class PasswordEncoding {
+	    Scheme: string;
+	    Prefix: string;
+	    RSAKey: string;
+	    RSAExponent: string;
+	    RSAKeyURL: string;
+	    RSAKeyPattern: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new PasswordEncoding(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.Scheme = source["Scheme"];
+	        this.Prefix = source["Prefix"];
+	        this.RSAKey = source["RSAKey"];
+	        this.RSAExponent = source["RSAExponent"];
+	        this.RSAKeyURL = source["RSAKeyURL"];
+	        this.RSAKeyPattern = source["RSAKeyPattern"];
+	    }
+	}
+	export class SuccessRules {
+	    Status: number[];
+	    Regex: string[];
+	    Fields: Record<string, string>;
+	    FailureKeywords: string[];
+	    Location: string;
+	    Verify: boolean;
+	    VerifyTimeout: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new SuccessRules(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.Status = source["Status"];
+	        this.Regex = source["Regex"];
+	        this.Fields = source["Fields"];
+	        this.FailureKeywords = source["FailureKeywords"];
+	        this.Location = source["Location"];
+	        this.Verify = source["Verify"];
+	        this.VerifyTimeout = source["VerifyTimeout"];
+	    }
+	}
 	export class PortalConfig {
+	    Preset: string;
+	    Driver: string;
 	    LoginURL: string;
+	    StatusURL: string;
+	    LogoutURL: string;
+	    UnbindURL: string;
 	    Method: string;
 	    Form: Record<string, string>;
 	    LogoutForm: Record<string, string>;
 	    Headers: Record<string, string>;
 	    SuccessKeywords: string[];
+	    LogoutKeywords: string[];
+	    Success: SuccessRules;
+	    PasswordEncoding: PasswordEncoding;
+	    Flow: LoginFlow;
+	    DualStack: boolean;
+	    SelfService: SelfServiceConfig;
 	
 	    static createFrom(source: any = {}) {
 	        return new PortalConfig(source);
@@ -44,12 +143,235 @@ export namespace config {
 	
 	    constructor(source: any = {}) {
 	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.Preset = source["Preset"];
+	        this.Driver = source["Driver"];
 	        this.LoginURL = source["LoginURL"];
+	        this.StatusURL = source["StatusURL"];
+	        this.LogoutURL = source["LogoutURL"];
+	        this.UnbindURL = source["UnbindURL"];
 	        this.Method = source["Method"];
 	        this.Form = source["Form"];
 	        this.LogoutForm = source["LogoutForm"];
 	        this.Headers = source["Headers"];
 	        this.SuccessKeywords = source["SuccessKeywords"];
+	        this.LogoutKeywords = source["LogoutKeywords"];
+	        this.Success = this.convertValues(source["Success"], SuccessRules);
+	        this.PasswordEncoding = this.convertValues(source["PasswordEncoding"], PasswordEncoding);
+	        this.Flow = this.convertValues(source["Flow"], LoginFlow);
+	        this.DualStack = source["DualStack"];
+	        this.SelfService = this.convertValues(source["SelfService"], SelfServiceConfig);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class WebhookConfig {
+	    name: string;
+	    kind: string;
+	    url: string;
+	    headers: Record<string, string>;
+	    chat_id: string;
+	    events: string[];
+	
+	    static createFrom(source: any = {}) {
+	        return new WebhookConfig(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.name = source["name"];
+	        this.kind = source["kind"];
+	        this.url = source["url"];
+	        this.headers = source["headers"];
+	        this.chat_id = source["chat_id"];
+	        this.events = source["events"];
+	    }
+	}
+	export class NotifyConfig {
+	    enabled: boolean;
+	    on_online: boolean;
+	    on_offline: boolean;
+	    on_login_failed: boolean;
+	    on_unreachable: boolean;
+	    on_quota: boolean;
+	    unreachable_minutes: number;
+	    min_interval: number;
+	    webhooks: WebhookConfig[];
+	
+	    static createFrom(source: any = {}) {
+	        return new NotifyConfig(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.enabled = source["enabled"];
+	        this.on_online = source["on_online"];
+	        this.on_offline = source["on_offline"];
+	        this.on_login_failed = source["on_login_failed"];
+	        this.on_unreachable = source["on_unreachable"];
+	        this.on_quota = source["on_quota"];
+	        this.unreachable_minutes = source["unreachable_minutes"];
+	        this.min_interval = source["min_interval"];
+	        this.webhooks = this.convertValues(source["webhooks"], WebhookConfig);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class ScheduleWindow {
+	    name: string;
+	    days: string[];
+	    start: string;
+	    end: string;
+	    auto_login?: boolean;
+	    force_login?: boolean;
+	    interval?: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new ScheduleWindow(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.name = source["name"];
+	        this.days = source["days"];
+	        this.start = source["start"];
+	        this.end = source["end"];
+	        this.auto_login = source["auto_login"];
+	        this.force_login = source["force_login"];
+	        this.interval = source["interval"];
+	    }
+	}
+	export class ScheduleConfig {
+	    windows: ScheduleWindow[];
+	
+	    static createFrom(source: any = {}) {
+	        return new ScheduleConfig(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.windows = this.convertValues(source["windows"], ScheduleWindow);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class QuotaConfig {
+	    monthly_gb: number;
+	    reset_day: number;
+	    warn_percents: number[];
+	    min_balance: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new QuotaConfig(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.monthly_gb = source["monthly_gb"];
+	        this.reset_day = source["reset_day"];
+	        this.warn_percents = source["warn_percents"];
+	        this.min_balance = source["min_balance"];
+	    }
+	}
+	export class NetworkConfig {
+	    interface: string;
+	    source_ip: string;
+	    use_system_proxy: boolean;
+	    record: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new NetworkConfig(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.interface = source["interface"];
+	        this.source_ip = source["source_ip"];
+	        this.use_system_proxy = source["use_system_proxy"];
+	        this.record = source["record"];
+	    }
+	}
+	export class MatchConfig {
+	    gateways: string[];
+	    gateway_macs: string[];
+	    dhcp_domains: string[];
+	    interfaces: string[];
+	    subnets: string[];
+	
+	    static createFrom(source: any = {}) {
+	        return new MatchConfig(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.gateways = source["gateways"];
+	        this.gateway_macs = source["gateway_macs"];
+	        this.dhcp_domains = source["dhcp_domains"];
+	        this.interfaces = source["interfaces"];
+	        this.subnets = source["subnets"];
+	    }
+	}
+	export class KeepaliveConfig {
+	    mode: string;
+	    interval: number;
+	    url: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new KeepaliveConfig(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.mode = source["mode"];
+	        this.interval = source["interval"];
+	        this.url = source["url"];
 	    }
 	}
 	export class Config {
@@ -58,6 +380,12 @@ export namespace config {
 	    Account: AccountConfig;
 	    Portal: PortalConfig;
 	    UI: UIConfig;
+	    notify: NotifyConfig;
+	    schedule: ScheduleConfig;
+	    quota: QuotaConfig;
+	    network: NetworkConfig;
+	    match: MatchConfig;
+	    keepalive: KeepaliveConfig;
 	    auto_login_interval: number;
 	    login_mode: string;
 	    auto_start: boolean;
@@ -78,6 +406,12 @@ export namespace config {
 	        this.Account = this.convertValues(source["Account"], AccountConfig);
 	        this.Portal = this.convertValues(source["Portal"], PortalConfig);
 	        this.UI = this.convertValues(source["UI"], UIConfig);
+	        this.notify = this.convertValues(source["notify"], NotifyConfig);
+	        this.schedule = this.convertValues(source["schedule"], ScheduleConfig);
+	        this.quota = this.convertValues(source["quota"], QuotaConfig);
+	        this.network = this.convertValues(source["network"], NetworkConfig);
+	        this.match = this.convertValues(source["match"], MatchConfig);
+	        this.keepalive = this.convertValues(source["keepalive"], KeepaliveConfig);
 	        this.auto_login_interval = source["auto_login_interval"];
 	        this.login_mode = source["login_mode"];
 	        this.auto_start = source["auto_start"];
@@ -110,13 +444,98 @@ export namespace config {
 
 }
 
+export namespace history {
+	
+	export class Sample {
+	    // Go type: time
+	    time: any;
+	    online: boolean;
+	    used_bytes: number;
+	    online_seconds: number;
+	    balance: number;
+	    has_balance: boolean;
+	
+	    static createFrom(source: any = {}) {
+	        return new Sample(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.time = this.convertValues(source["time"], null);
+	        this.online = source["online"];
+	        this.used_bytes = source["used_bytes"];
+	        this.online_seconds = source["online_seconds"];
+	        this.balance = source["balance"];
+	        this.has_balance = source["has_balance"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+
+}
+
 export namespace main {
 	
+	export class PauseInfo {
+	    paused: boolean;
+	    message: string;
+	    // Go type: time
+	    until: any;
+	
+	    static createFrom(source: any = {}) {
+	        return new PauseInfo(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.paused = source["paused"];
+	        this.message = source["message"];
+	        this.until = this.convertValues(source["until"], null);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
 	export class Status {
 	    online: boolean;
+	    paused: boolean;
 	    message: string;
 	    // Go type: time
 	    last_check: any;
+	    ipv4: boolean;
+	    ipv6: boolean;
+	    has_ipv6: boolean;
 	
 	    static createFrom(source: any = {}) {
 	        return new Status(source);
@@ -125,8 +544,172 @@ export namespace main {
 	    constructor(source: any = {}) {
 	        if ('string' === typeof source) source = JSON.parse(source);
 	        this.online = source["online"];
+	        this.paused = source["paused"];
 	        this.message = source["message"];
 	        this.last_check = this.convertValues(source["last_check"], null);
+	        this.ipv4 = source["ipv4"];
+	        this.ipv6 = source["ipv6"];
+	        this.has_ipv6 = source["has_ipv6"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+
+}
+
+export namespace portal {
+	
+	export class OnlineSession {
+	    id: string;
+	    ip: string;
+	    mac: string;
+	    host: string;
+	    // Go type: time
+	    login_time: any;
+	    used_bytes: number;
+	    local: boolean;
+	
+	    static createFrom(source: any = {}) {
+	        return new OnlineSession(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.id = source["id"];
+	        this.ip = source["ip"];
+	        this.mac = source["mac"];
+	        this.host = source["host"];
+	        this.login_time = this.convertValues(source["login_time"], null);
+	        this.used_bytes = source["used_bytes"];
+	        this.local = source["local"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class SessionInfo {
+	    online: boolean;
+	    account: string;
+	    ip: string;
+	    ipv6: string;
+	    mac: string;
+	    used_bytes: number;
+	    online_seconds: number;
+	    balance: number;
+	    has_balance: boolean;
+	
+	    static createFrom(source: any = {}) {
+	        return new SessionInfo(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.online = source["online"];
+	        this.account = source["account"];
+	        this.ip = source["ip"];
+	        this.ipv6 = source["ipv6"];
+	        this.mac = source["mac"];
+	        this.used_bytes = source["used_bytes"];
+	        this.online_seconds = source["online_seconds"];
+	        this.balance = source["balance"];
+	        this.has_balance = source["has_balance"];
+	    }
+	}
+	export class HARNameVal {
+	    name: string;
+	    value: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new HARNameVal(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.name = source["name"];
+	        this.value = source["value"];
+	    }
+	}
+	export class Imported {
+	    portal: config.PortalConfig;
+	    request: string;
+	    notes: string[];
+	
+	    static createFrom(source: any = {}) {
+	        return new Imported(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.portal = this.convertValues(source["portal"], config.PortalConfig);
+	        this.request = source["request"];
+	        this.notes = source["notes"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class RequestPreview {
+	    method: string;
+	    url: string;
+	    headers: HARNameVal[];
+	    body: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new RequestPreview(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.method = source["method"];
+	        this.url = source["url"];
+	        this.headers = this.convertValues(source["headers"], HARNameVal);
+	        this.body = source["body"];
 	    }
 	
 		convertValues(a: any, classs: any, asMap: boolean = false): any {
@@ -150,3 +733,56 @@ export namespace main {
 
 }
 
+export namespace quota {
+	
+	export class Report {
+	    // Go type: time
+	    period_start: any;
+	    // Go type: time
+	    period_end: any;
+	    used_bytes: number;
+	    quota_bytes: number;
+	    used_percent: number;
+	    projected_bytes: number;
+	    // Go type: time
+	    exhaust_at: any;
+	    balance: number;
+	    has_balance: boolean;
+	
+	    static createFrom(source: any = {}) {
+	        return new Report(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.period_start = this.convertValues(source["period_start"], null);
+	        this.period_end = this.convertValues(source["period_end"], null);
+	        this.used_bytes = source["used_bytes"];
+	        this.quota_bytes = source["quota_bytes"];
+	        this.used_percent = source["used_percent"];
+	        this.projected_bytes = source["projected_bytes"];
+	        this.exhaust_at = this.convertValues(source["exhaust_at"], null);
+	        this.balance = source["balance"];
+	        this.has_balance = source["has_balance"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+
+}
@@ -1,28 +1,67 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"log"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"sync"
 	"syscall"
 	"time"
 
 	"CUMT-autologin/internal/config"
-	"CUMT-autologin/internal/netcheck"
+	"CUMT-autologin/internal/history"
+	"CUMT-autologin/internal/ifinfo"
+	"CUMT-autologin/internal/keepalive"
+	"CUMT-autologin/internal/monitor"
+	"CUMT-autologin/internal/netwatch"
+	"CUMT-autologin/internal/notify"
+	"CUMT-autologin/internal/pause"
 	"CUMT-autologin/internal/portal"
+	"CUMT-autologin/internal/quota"
+	"CUMT-autologin/internal/schedule"
+	"CUMT-autologin/internal/trayicon"
+	"CUMT-autologin/internal/wifi"
 
 	"github.com/energye/systray"
 )
 
 const (
 	defaultIntervalSec = 10
-	forceLoginInterval = 2 * time.Minute
 	logoutFlagPath     = "logout.flag" // touch this file to trigger a logout on next tick
 )
 
+var notifier = notify.New(config.NotifyConfig{})
+
+// portalOpts builds gateway request options from the network section of the config.
+var portalOpts portal.OptionsCache
+
+// appCtx is cancelled on exit to abort in-flight gateway requests.
+var appCtx, cancelApp = context.WithCancel(context.Background())
+
+// clock drives schedule evaluation.
+var clock schedule.Clock = schedule.SystemClock{}
+
+// mon checks the session and raises quota alerts for the login loop.
+var mon = &monitor.Monitor{
+	Tracker:  quota.NewTracker(history.DefaultPath),
+	Notifier: notifier,
+	Clock:    clock,
+	Log: func(level, format string, args ...any) {
+		log.Printf("[core] "+format, args...)
+	},
+}
+
+var (
+	statusMu  sync.Mutex
+	trayInfo  trayicon.Info
+	trayReady bool
+	trayState = trayicon.StateUnknown
+)
+
 func main() {
 	log.SetFlags(log.LstdFlags | log.Lmicroseconds)
 	initLogging()
@@ -35,16 +74,25 @@ func main() {
 
 // 托盘初始化
 func onReady() {
-	systray.SetIcon(iconData)
-	systray.SetTooltip("CUMT 校园网自动登录")
+	systray.SetIcon(trayicon.Icon(iconData, trayicon.StateUnknown))
+	systray.SetTooltip(trayicon.Tooltip(trayicon.Info{}, time.Now()))
+	statusMu.Lock()
+	trayReady = true
+	statusMu.Unlock()
 
 	// 左键点击托盘图标：唤起 GUI
 	systray.SetOnClick(func(menu systray.IMenu) {
 		go summonWildsapp()
 	})
 
-	// 右键菜单：手动登录 / 退出
+	// 右键菜单：手动登录 / 暂停 / 退出
 	mLoginNow := systray.AddMenuItem("立即尝试登录", "立刻跑一次登录逻辑")
+	mPause := systray.AddMenuItem("暂停自动登录", "临时停止自动登录")
+	mPauseManual := mPause.AddSubMenuItem("暂停（直到手动恢复）", "")
+	mSnooze30 := mPause.AddSubMenuItem("暂停 30 分钟", "")
+	mSnooze1h := mPause.AddSubMenuItem("暂停 1 小时", "")
+	mSnoozeNet := mPause.AddSubMenuItem("暂停至网络变化", "切换网络后自动恢复")
+	mResume := systray.AddMenuItem("恢复自动登录", "立即恢复自动登录")
 	systray.AddSeparator()
 	mQuit := systray.AddMenuItem("退出", "退出 CUMT-autologin")
 
@@ -55,13 +103,28 @@ func onReady() {
 	mLoginNow.Click(func() {
 		go runOnce()
 	})
+	mPauseManual.Click(func() {
+		applyPause(pause.Pause(pause.DefaultPath))
+	})
+	mSnooze30.Click(func() {
+		applyPause(pause.Snooze(pause.DefaultPath, 30*time.Minute))
+	})
+	mSnooze1h.Click(func() {
+		applyPause(pause.Snooze(pause.DefaultPath, time.Hour))
+	})
+	mSnoozeNet.Click(func() {
+		applyPause(pause.SnoozeUntilNetworkChange(pause.DefaultPath))
+	})
+	mResume.Click(func() {
+		applyPause(pause.Resume(pause.DefaultPath))
+	})
 	mQuit.Click(func() {
 		systray.Quit()
 	})
 }
 
 func onExit() {
-	// need cleanup if required
+	cancelApp()
 }
 
 func initLogging() {
@@ -81,6 +144,45 @@ func initLogging() {
 	log.Printf("[core] logging to %s", logPath)
 }
 
+// applyPause reflects a pause / resume action in the tray right away
+// instead of waiting for the next loop tick.
+func applyPause(err error) {
+	if err != nil {
+		log.Printf("[core] update pause state error: %v", err)
+		return
+	}
+	if st, paused := pause.Check(pause.DefaultPath, time.Now()); paused {
+		log.Printf("[core] auto-login paused: %s", st.Describe(time.Now()))
+		setStatus(trayicon.StatePaused, st.Describe(time.Now()))
+		return
+	}
+	log.Printf("[core] auto-login resumed")
+	setStatus(trayicon.StateUnknown, "已恢复自动登录")
+}
+
+// setStatus records the current state and refreshes the tray icon and tooltip.
+func setStatus(state trayicon.State, text string) {
+	statusMu.Lock()
+	defer statusMu.Unlock()
+	trayInfo.Update(state, text, time.Now())
+	if !trayReady {
+		return
+	}
+	if state != trayState {
+		trayState = state
+		systray.SetIcon(trayicon.Icon(iconData, state))
+	}
+	systray.SetTooltip(trayicon.Tooltip(trayInfo, time.Now()))
+}
+
+// setTrayDetails records the SSID / account shown in the tooltip.
+func setTrayDetails(ssid, account string) {
+	statusMu.Lock()
+	trayInfo.SSID = ssid
+	trayInfo.Account = account
+	statusMu.Unlock()
+}
+
 func summonWildsapp() {
 	exe, _ := os.Executable()
 	exeDir := filepath.Dir(exe)
@@ -130,16 +232,28 @@ func summonWildsapp() {
 }
 
 func runCoreLoop() {
-	var lastLogin time.Time
+	var keeper keepalive.Timer
+	var lastScheduleErr string
+
+	events, err := netwatch.Watch(appCtx, netwatch.Options{
+		SSID: func() (string, error) { return wifi.CurrentSSID("") },
+	})
+	if err != nil {
+		log.Printf("[core] network change events unavailable, polling only: %v", err)
+	}
 
 	for {
 		cfg, err := config.Load(config.DefaultConfigPath)
 		if err != nil {
 			log.Printf("[core] read config error: %v", err)
+			setStatus(trayicon.StateUnknown, "配置读取失败")
 			time.Sleep(5 * time.Second)
 			continue
 		}
 
+		notifier.SetConfig(cfg.Notify)
+		ssid := ""
+
 		if err := config.SetAutoStart(cfg.AutoStart); err != nil {
 			log.Printf("[core] SetAutoStart failed: %v", err)
 		}
@@ -149,53 +263,116 @@ func runCoreLoop() {
 			interval = defaultIntervalSec
 		}
 
-		now := time.Now()
+		now := clock.Now()
+
+		plan := schedule.Evaluate(cfg.Schedule, now)
+		if err := schedule.Validate(cfg.Schedule); err != nil && err.Error() != lastScheduleErr {
+			log.Printf("[core] schedule config error: %v", err)
+			lastScheduleErr = err.Error()
+		}
+		if plan.Interval > 0 {
+			interval = int(plan.Interval / time.Second)
+		}
 
 		if handleLogoutFlag(cfg) {
-			lastLogin = now
-			time.Sleep(time.Duration(interval) * time.Second)
+			keeper.Reset(now)
+			wait(events, time.Duration(interval)*time.Second)
+			continue
+		}
+
+		if st, paused := pause.Check(pause.DefaultPath, now); paused {
+			setStatus(trayicon.StatePaused, st.Describe(now))
+			wait(events, time.Duration(interval)*time.Second)
+			continue
+		}
+
+		if !plan.Active {
+			setStatus(trayicon.StatePaused, plan.Describe())
+			wait(events, time.Duration(interval)*time.Second)
 			continue
 		}
 
 		if cfg.WifiSSID != "" {
-			ssid, err := currentSSID()
+			ssid, err = wifi.CurrentSSID(cfg.WifiSSID)
 			if err != nil {
 				log.Printf("[core] read wifi ssid failed: %v", err)
-				time.Sleep(time.Duration(interval) * time.Second)
+				setStatus(trayicon.StateUnknown, "读取 WiFi 状态出错")
+				wait(events, time.Duration(interval)*time.Second)
 				continue
 			}
 			if ssid == "" {
 				log.Printf("[core] wifi not connected, skip")
-				time.Sleep(time.Duration(interval) * time.Second)
+				setStatus(trayicon.StateUnknown, "未连接 WiFi")
+				wait(events, time.Duration(interval)*time.Second)
 				continue
 			}
 			if ssid != cfg.WifiSSID {
 				log.Printf("[core] wifi=%q (target %q), skip", ssid, cfg.WifiSSID)
-				time.Sleep(time.Duration(interval) * time.Second)
+				setTrayDetails(ssid, cfg.LoginAccount())
+				setStatus(trayicon.StateUnknown, "已连接 "+ssid+" (非目标)")
+				wait(events, time.Duration(interval)*time.Second)
 				continue
 			}
 		}
+		if ok, why := ifinfo.MatchCurrent(cfg.Match); !ok {
+			log.Printf("[core] network does not match, skip: %s", why)
+			setTrayDetails(ssid, cfg.LoginAccount())
+			setStatus(trayicon.StateUnknown, "非校园网络（"+why+"）")
+			wait(events, time.Duration(interval)*time.Second)
+			continue
+		}
+		setTrayDetails(ssid, cfg.LoginAccount())
+
+		online, _ := mon.CheckOnline(appCtx, cfg, portalOpts.Get(cfg))
+		if online {
+			notifier.Online()
+			setStatus(trayicon.StateOnline, "在线")
+		} else {
+			notifier.Offline()
+		}
+		if online && (!plan.ForceLogin || !keeper.Due(cfg.Keepalive, now)) {
+			log.Printf("[core] online, no need to login (last=%s)", keeper.Last().Format(time.RFC3339))
+			wait(events, time.Duration(interval)*time.Second)
+			continue
+		}
 
-		online := netcheck.IsOnline(cfg.CheckURL)
-		if online && now.Sub(lastLogin) < forceLoginInterval {
-			log.Printf("[core] online, no need to login (last=%s)", lastLogin.Format(time.RFC3339))
-			time.Sleep(time.Duration(interval) * time.Second)
+		if online && keepalive.Mode(cfg.Keepalive) != keepalive.ModeRelogin {
+			if err := keepalive.Ping(appCtx, cfg, portalOpts.Get(cfg)); err != nil {
+				log.Printf("[core] keepalive (%s) failed: %v", keepalive.Mode(cfg.Keepalive), err)
+			} else {
+				log.Printf("[core] keepalive (%s) sent", keepalive.Mode(cfg.Keepalive))
+			}
+			keeper.Reset(now)
+			wait(events, time.Duration(interval)*time.Second)
 			continue
 		}
 
 		if online {
-			log.Printf("[core] online but forcing re-login (interval elapsed)")
+			log.Printf("[core] online but forcing re-login (keepalive interval elapsed)")
 		} else {
 			log.Printf("[core] offline, try login")
+			setStatus(trayicon.StateCaptive, "未认证 / 尝试登录中...")
 		}
 
 		if err := doLogin(cfg); err != nil {
 			log.Printf("[core] login failed: %v", err)
 		} else {
-			lastLogin = time.Now()
+			keeper.Reset(time.Now())
 		}
 
-		time.Sleep(time.Duration(interval) * time.Second)
+		wait(events, time.Duration(interval)*time.Second)
+	}
+}
+
+// wait sleeps for d, or less when the network changes so that a reconnect
+// is handled right away.
+func wait(events <-chan netwatch.Event, d time.Duration) {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+	case ev := <-events:
+		log.Printf("[core] network changed (%s), check now", ev)
 	}
 }
 
@@ -207,7 +384,7 @@ func runOnce() {
 	}
 
 	if cfg.WifiSSID != "" {
-		ssid, err := currentSSID()
+		ssid, err := wifi.CurrentSSID(cfg.WifiSSID)
 		if err != nil {
 			log.Printf("[core] read wifi ssid failed: %v", err)
 			return
@@ -218,10 +395,15 @@ func runOnce() {
 		}
 	}
 
-	if netcheck.IsOnline(cfg.CheckURL) {
+	notifier.SetConfig(cfg.Notify)
+
+	if online, _ := mon.CheckOnline(appCtx, cfg, portalOpts.Get(cfg)); online {
+		notifier.Online()
+		setStatus(trayicon.StateOnline, "在线")
 		log.Printf("[core] runOnce: already online")
 		return
 	}
+	notifier.Offline()
 
 	if err := doLogin(cfg); err != nil {
 		log.Printf("[core] runOnce: login failed: %v", err)
@@ -236,13 +418,14 @@ func handleLogoutFlag(cfg *config.Config) bool {
 	}
 	log.Printf("[core] logout flag detected, try logout")
 	pCfg := preparePortalConfig(cfg)
-	body, err := portal.Logout(pCfg)
-	if err != nil {
+	res, err := portal.Logout(appCtx, pCfg, portalOpts.Get(cfg))
+	switch {
+	case err != nil:
 		log.Printf("[core] logout error: %v", err)
-	} else if body != "" && !portal.IsLoginSuccess(body, pCfg) {
-		log.Printf("[core] logout response may not indicate success")
-	} else {
-		log.Printf("[core] logout finished")
+	case !res.OK:
+		log.Printf("[core] logout rejected: %s", res.Message)
+	default:
+		log.Printf("[core] logout finished: %s", res.Message)
 	}
 	_ = os.Remove(logoutFlagPath)
 	return true
@@ -250,16 +433,41 @@ func handleLogoutFlag(cfg *config.Config) bool {
 
 func doLogin(cfg *config.Config) error {
 	pCfg := preparePortalConfig(cfg)
-	body, err := portal.Login(pCfg)
+	setStatus(trayicon.StateLoggingIn, "登录中...")
+	opts := portalOpts.Get(cfg)
+	reply, err := portal.Submit(appCtx, pCfg, opts)
 	if err != nil {
+		notifier.Unreachable(err)
+		setStatus(trayicon.StateFailed, "登录失败（请求错误）")
 		return err
 	}
-	if portal.IsLoginSuccess(body, pCfg) {
+	body := reply.Body
+	rerr := portal.CheckReply(reply, pCfg)
+	if rerr == nil {
+		if err := portal.VerifyLogin(appCtx, pCfg, opts); err != nil {
+			log.Printf("[core] login accepted but verification failed: %v", err)
+			setStatus(trayicon.StateFailed, "网关已接受登录，但仍无法联网")
+			return err
+		}
 		log.Printf("[core] login success")
+		notifier.Online()
+		setStatus(trayicon.StateOnline, "在线")
 		return nil
 	}
+	log.Printf("[core] %v", rerr)
 	_ = os.WriteFile("last_login_response.html", []byte(body), 0644)
-	return fmt.Errorf("login response did not match success keywords")
+	reason := portal.FailureReason(body)
+	if mon.AutoKick(appCtx, cfg, portalOpts.Get(cfg), body) {
+		setStatus(trayicon.StateCaptive, "已下线最早的其他设备，稍后重试登录")
+		return fmt.Errorf("login rejected: %s, kicked oldest session", reason)
+	}
+	notifier.LoginFailed(reason)
+	if reason != "" {
+		setStatus(trayicon.StateFailed, "登录失败："+reason)
+		return fmt.Errorf("login rejected: %s", reason)
+	}
+	setStatus(trayicon.StateFailed, "登录失败（网关响应异常）")
+	return rerr
 }
 
 func preparePortalConfig(cfg *config.Config) *config.PortalConfig {
@@ -267,11 +475,7 @@ func preparePortalConfig(cfg *config.Config) *config.PortalConfig {
 	if pCfg.Form == nil {
 		pCfg.Form = make(map[string]string)
 	}
-	account := cfg.Account.StudentID
-	if cfg.LoginMode != "campus_only" {
-		account += config.CarrierSuffix(cfg.Account.Carrier)
-	}
-	pCfg.Form["user_account"] = account
+	pCfg.Form["user_account"] = cfg.LoginAccount()
 	pCfg.Form["user_password"] = cfg.Account.Password
 	return &pCfg
 }
@@ -1,7 +0,0 @@
-//go:build !windows
-
-package main
-
-func currentSSID() (string, error) {
-	return "", nil
-}
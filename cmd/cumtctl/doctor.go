@@ -0,0 +1,49 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"CUMT-autologin/internal/config"
+	"CUMT-autologin/internal/doctor"
+)
+
+// runDoctor gets a nil cfg when the config cannot be loaded; diagnosing
+// that is part of its job.
+func runDoctor(ctx context.Context, _ *config.Config, args []string) error {
+	fs := flag.NewFlagSet("doctor", flag.ExitOnError)
+	asJSON := fs.Bool("json", false, "以 JSON 输出")
+	noLogin := fs.Bool("no-login", false, "跳过试登录（试登录会真实提交一次登录表单）")
+	bundle := fs.String("bundle", "", "把诊断报告、配置、日志与近期历史（已脱敏）打包到该 zip 文件")
+	_ = fs.Parse(args)
+
+	rep := doctor.Run(ctx, doctor.Options{ConfigPath: configPath, SkipLogin: *noLogin})
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(rep); err != nil {
+			return err
+		}
+	} else {
+		rep.WriteText(os.Stdout)
+	}
+
+	if *bundle != "" {
+		if *bundle == "auto" {
+			*bundle = "cumt-support-" + time.Now().Format("20060102-150405") + ".zip"
+		}
+		if err := rep.WriteBundle(*bundle); err != nil {
+			return fmt.Errorf("写入支持包失败: %w", err)
+		}
+		fmt.Fprintf(os.Stderr, "支持包已保存到 %s\n", *bundle)
+	}
+	if rep.Failed() {
+		return errors.New("存在失败项")
+	}
+	return nil
+}
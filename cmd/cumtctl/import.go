@@ -0,0 +1,61 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"CUMT-autologin/internal/config"
+	"CUMT-autologin/internal/portal"
+)
+
+// runImport turns a browser HAR capture or a "Copy as cURL" command into a
+// preset file.
+func runImport(_ context.Context, _ *config.Config, args []string) error {
+	fs := flag.NewFlagSet("import", flag.ExitOnError)
+	name := fs.String("name", "imported", "预设名称")
+	desc := fs.String("desc", "从浏览器抓包导入", "预设说明")
+	output := fs.String("o", "", "输出文件，为空输出到标准输出")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "用法: cumtctl import [-name 名称] [-o 输出文件] <HAR 文件 | curl 命令文件 | ->")
+		fs.PrintDefaults()
+	}
+	_ = fs.Parse(args)
+
+	var data []byte
+	var err error
+	switch src := fs.Arg(0); src {
+	case "", "-":
+		data, err = io.ReadAll(os.Stdin)
+	default:
+		data, err = os.ReadFile(src)
+	}
+	if err != nil {
+		return err
+	}
+	imp, err := portal.Import(string(data))
+	if err != nil {
+		return err
+	}
+	out, err := config.ExportPreset(imp.Portal, *name, *desc)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(os.Stderr, "登录请求: %s\n", imp.Request)
+	for _, n := range imp.Notes {
+		fmt.Fprintf(os.Stderr, "注意: %s\n", n)
+	}
+	if *output == "" {
+		fmt.Print(string(out))
+		return nil
+	}
+	if err := os.WriteFile(*output, out, 0644); err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "已导入到 %s，使用方式: portal.preset: %s\n", *output, filepath.Base(*output))
+	return nil
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+
+	"CUMT-autologin/internal/config"
+	"CUMT-autologin/internal/portal"
+)
+
+func runLogout(ctx context.Context, cfg *config.Config, args []string) error {
+	fs := flag.NewFlagSet("logout", flag.ExitOnError)
+	_ = fs.Parse(args)
+
+	res, err := portal.Logout(ctx, &cfg.Portal, portal.NewOptions(cfg))
+	if err != nil {
+		return err
+	}
+	if !res.OK {
+		return fmt.Errorf("网关拒绝注销: %s", res.Message)
+	}
+	fmt.Println(res.Message)
+	return nil
+}
+
+func runUnbind(ctx context.Context, cfg *config.Config, args []string) error {
+	fs := flag.NewFlagSet("unbind", flag.ExitOnError)
+	mac := fs.String("mac", "", "要解绑的设备 MAC，留空解绑账号下全部设备")
+	_ = fs.Parse(args)
+
+	res, err := portal.UnbindMAC(ctx, &cfg.Portal, *mac, portal.NewOptions(cfg))
+	if err != nil {
+		return err
+	}
+	if !res.OK {
+		return fmt.Errorf("网关拒绝解绑: %s", res.Message)
+	}
+	fmt.Println(res.Message)
+	return nil
+}
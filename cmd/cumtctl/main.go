@@ -0,0 +1,83 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"sort"
+
+	"CUMT-autologin/internal/config"
+)
+
+// command is a cumtctl subcommand.
+type command struct {
+	help string
+	run  func(ctx context.Context, cfg *config.Config, args []string) error
+	// noConfig commands run without loading the config and get a nil cfg.
+	noConfig bool
+}
+
+// configPath is the -config flag, for commands that read the file themselves.
+var configPath string
+
+var commands = map[string]command{
+	"status":   {help: "显示网络、网关会话与自动登录状态", run: runStatus},
+	"logout":   {help: "注销本机在网关上的会话", run: runLogout},
+	"unbind":   {help: "解绑账号的 MAC 绑定，下线占用会话的其他设备", run: runUnbind},
+	"sessions": {help: "列出账号下的在线设备（自助服务系统）", run: runSessions},
+	"kick":     {help: "强制下线指定会话", run: runKick},
+	"preset":   {help: "列出、查看或导出网关预设 (list / show / export)", run: runPreset},
+	"import":   {help: "从浏览器 HAR 或 cURL 命令生成网关预设", run: runImport, noConfig: true},
+	"preview":  {help: "显示登录时将发送的请求（不会连接网关）", run: runPreview},
+	"doctor":   {help: "诊断无法自动登录的原因，可生成支持包", run: runDoctor, noConfig: true},
+	"pause":    {help: "暂停自动登录，直到 resume", run: runPause, noConfig: true},
+	"snooze":   {help: "暂停自动登录一段时间 (如 30m) 或至网络变化 (net)", run: runSnooze, noConfig: true},
+	"resume":   {help: "恢复自动登录", run: runResume, noConfig: true},
+}
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "用法: cumtctl [-config config.yaml] <命令> [参数]\n\n命令:\n")
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(os.Stderr, "  %-10s %s\n", name, commands[name].help)
+	}
+}
+
+func main() {
+	flag.StringVar(&configPath, "config", config.DefaultConfigPath, "配置文件路径")
+	flag.Usage = usage
+	flag.Parse()
+
+	if flag.NArg() == 0 {
+		usage()
+		os.Exit(2)
+	}
+	cmd, ok := commands[flag.Arg(0)]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "未知命令: %s\n\n", flag.Arg(0))
+		usage()
+		os.Exit(2)
+	}
+
+	var cfg *config.Config
+	if !cmd.noConfig {
+		var err error
+		if cfg, err = config.Load(configPath); err != nil {
+			fmt.Fprintf(os.Stderr, "读取配置失败: %v\n", err)
+			os.Exit(1)
+		}
+	}
+	// Ctrl+C cancels in-flight gateway requests.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	if err := cmd.run(ctx, cfg, flag.Args()[1:]); err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %v\n", flag.Arg(0), err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"time"
+
+	"CUMT-autologin/internal/config"
+	"CUMT-autologin/internal/pause"
+)
+
+// pausePath is the pause file next to the config given with -config, so
+// that a non-default installation is paused, not the default one.
+func pausePath() string {
+	return pause.PathFor(configPath)
+}
+
+func runPause(ctx context.Context, cfg *config.Config, args []string) error {
+	fs := flag.NewFlagSet("pause", flag.ExitOnError)
+	_ = fs.Parse(args)
+
+	if err := pause.Pause(pausePath()); err != nil {
+		return err
+	}
+	fmt.Println("已暂停自动登录，运行 cumtctl resume 恢复")
+	return nil
+}
+
+func runSnooze(ctx context.Context, cfg *config.Config, args []string) error {
+	fs := flag.NewFlagSet("snooze", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "用法: cumtctl snooze <时长，如 30m、2h | net>")
+	}
+	_ = fs.Parse(args)
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return errors.New("需要一个时长或 net")
+	}
+
+	path := pausePath()
+	if fs.Arg(0) == "net" {
+		if err := pause.SnoozeUntilNetworkChange(path); err != nil {
+			return err
+		}
+	} else {
+		d, err := time.ParseDuration(fs.Arg(0))
+		if err != nil || d <= 0 {
+			return fmt.Errorf("无效的时长 %q", fs.Arg(0))
+		}
+		if err := pause.Snooze(path, d); err != nil {
+			return err
+		}
+	}
+	st, _ := pause.Load(path)
+	fmt.Println(st.Describe(time.Now()))
+	return nil
+}
+
+func runResume(ctx context.Context, cfg *config.Config, args []string) error {
+	fs := flag.NewFlagSet("resume", flag.ExitOnError)
+	_ = fs.Parse(args)
+
+	if err := pause.Resume(pausePath()); err != nil {
+		return err
+	}
+	fmt.Println("已恢复自动登录")
+	return nil
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/yaml.v3"
+
+	"CUMT-autologin/internal/config"
+)
+
+// runPreset lists, shows and exports portal presets.
+func runPreset(_ context.Context, cfg *config.Config, args []string) error {
+	if len(args) == 0 {
+		args = []string{"list"}
+	}
+	switch args[0] {
+	case "list":
+		for _, p := range config.Presets() {
+			fmt.Printf("%-22s %s\n", p.Name, p.Description)
+		}
+		if cfg.Portal.Preset != "" {
+			fmt.Printf("\n当前使用: %s\n", cfg.Portal.Preset)
+		}
+		return nil
+
+	case "show":
+		if len(args) < 2 {
+			return errors.New("用法: cumtctl preset show <名称>")
+		}
+		p, err := config.LookupPreset(args[1], filepath.Dir(configPath))
+		if err != nil {
+			return err
+		}
+		out, err := yaml.Marshal(p)
+		if err != nil {
+			return err
+		}
+		fmt.Print(string(out))
+		return nil
+
+	case "export":
+		fs := flag.NewFlagSet("preset export", flag.ExitOnError)
+		name := fs.String("name", "my-campus", "预设名称")
+		desc := fs.String("desc", "", "预设说明")
+		output := fs.String("o", "", "输出文件，为空输出到标准输出")
+		_ = fs.Parse(args[1:])
+		out, err := config.ExportPreset(cfg.Portal, *name, *desc)
+		if err != nil {
+			return err
+		}
+		if *output == "" {
+			fmt.Print(string(out))
+			return nil
+		}
+		if err := os.WriteFile(*output, out, 0644); err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "已导出到 %s，使用方式: portal.preset: %s\n", *output, filepath.Base(*output))
+		return nil
+	}
+	return fmt.Errorf("未知子命令 %q（list / show / export）", args[0])
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+
+	"CUMT-autologin/internal/config"
+	"CUMT-autologin/internal/portal"
+)
+
+// runPreview prints the login request without sending it.
+func runPreview(_ context.Context, cfg *config.Config, args []string) error {
+	fs := flag.NewFlagSet("preview", flag.ExitOnError)
+	asJSON := fs.Bool("json", false, "以 JSON 输出")
+	_ = fs.Parse(args)
+
+	pCfg := cfg.Portal
+	if cfg.Account.StudentID != "" {
+		pCfg.Form["user_account"] = cfg.LoginAccount()
+	}
+	p, err := portal.PreviewLogin(&pCfg, portal.NewOptions(cfg), cfg.Account.Password)
+	if err != nil {
+		return err
+	}
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetEscapeHTML(false)
+		enc.SetIndent("", "  ")
+		return enc.Encode(p)
+	}
+	fmt.Print(p)
+	return nil
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+
+	"CUMT-autologin/internal/config"
+	"CUMT-autologin/internal/portal"
+)
+
+func selfService(cfg *config.Config) (*portal.SelfService, error) {
+	username, password := cfg.SelfServiceCredentials()
+	return portal.NewSelfService(cfg.Portal.SelfService, username, password, portal.NewOptions(cfg))
+}
+
+func runSessions(ctx context.Context, cfg *config.Config, args []string) error {
+	fs := flag.NewFlagSet("sessions", flag.ExitOnError)
+	asJSON := fs.Bool("json", false, "以 JSON 输出")
+	_ = fs.Parse(args)
+
+	ss, err := selfService(cfg)
+	if err != nil {
+		return err
+	}
+	sessions, err := ss.Sessions(ctx)
+	if err != nil {
+		return err
+	}
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(sessions)
+	}
+	if len(sessions) == 0 {
+		fmt.Println("当前没有在线设备")
+		return nil
+	}
+	fmt.Printf("%-20s %-15s %-12s %-19s %s\n", "会话 ID", "IP", "MAC", "登录时间", "流量")
+	for _, s := range sessions {
+		mark := ""
+		if s.Local {
+			mark = " (本机)"
+		}
+		fmt.Printf("%-20s %-15s %-12s %-19s %s%s\n",
+			s.ID, s.IP, s.MAC, s.LoginTime.Format("2006-01-02 15:04:05"), portal.FormatBytes(s.UsedBytes), mark)
+	}
+	return nil
+}
+
+func runKick(ctx context.Context, cfg *config.Config, args []string) error {
+	fs := flag.NewFlagSet("kick", flag.ExitOnError)
+	oldest := fs.Bool("oldest", false, "下线最早登录的其他设备")
+	_ = fs.Parse(args)
+
+	ss, err := selfService(cfg)
+	if err != nil {
+		return err
+	}
+	if *oldest {
+		s, err := ss.KickOldest(ctx)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("已下线 %s (%s, %s)\n", s.ID, s.IP, s.MAC)
+		return nil
+	}
+	if fs.NArg() != 1 {
+		return fmt.Errorf("用法: cumtctl kick <会话 ID> 或 cumtctl kick -oldest")
+	}
+	if err := ss.Kick(ctx, fs.Arg(0)); err != nil {
+		return err
+	}
+	fmt.Printf("已下线 %s\n", fs.Arg(0))
+	return nil
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"CUMT-autologin/internal/config"
+	"CUMT-autologin/internal/ifinfo"
+	"CUMT-autologin/internal/netbind"
+	"CUMT-autologin/internal/netcheck"
+	"CUMT-autologin/internal/pause"
+	"CUMT-autologin/internal/portal"
+	"CUMT-autologin/internal/quota"
+	"CUMT-autologin/internal/schedule"
+	"CUMT-autologin/internal/wifi"
+)
+
+type statusReport struct {
+	Online       bool                `json:"online"`
+	Probe        netcheck.Result     `json:"probe"`
+	Network      *ifinfo.Snapshot    `json:"network,omitempty"`
+	NetworkMatch string              `json:"network_match,omitempty"` // why match rules failed
+	WiFi         []wifi.Interface    `json:"wifi,omitempty"`
+	Driver       string              `json:"driver"`
+	Session      *portal.SessionInfo `json:"session,omitempty"`
+	Quota        *quota.Report       `json:"quota,omitempty"`
+	SessionError string              `json:"session_error,omitempty"`
+	Paused       bool                `json:"paused"`
+	PauseMessage string              `json:"pause_message,omitempty"`
+	Schedule     string              `json:"schedule"`
+}
+
+func runStatus(ctx context.Context, cfg *config.Config, args []string) error {
+	fs := flag.NewFlagSet("status", flag.ExitOnError)
+	asJSON := fs.Bool("json", false, "以 JSON 输出")
+	_ = fs.Parse(args)
+
+	now := time.Now()
+	rep := statusReport{
+		Probe:    netcheck.Check(netbind.FromConfig(cfg.Network)),
+		Driver:   portal.Driver(&cfg.Portal),
+		Schedule: schedule.Evaluate(cfg.Schedule, now).Describe(),
+	}
+	rep.Online = rep.Probe.Online(cfg.Portal.DualStack)
+	if snap, err := ifinfo.Inspect(); err == nil {
+		rep.Network = snap
+		if ok, why := ifinfo.Match(cfg.Match, snap); !ok {
+			rep.NetworkMatch = why
+		}
+	} else if ifinfo.Enabled(cfg.Match) {
+		rep.NetworkMatch = "读取网络信息失败: " + err.Error()
+	}
+	rep.WiFi, _ = wifi.Interfaces()
+	info, err := portal.Status(ctx, &cfg.Portal, portal.NewOptions(cfg))
+	switch {
+	case err == nil:
+		rep.Session = info
+		r := quota.Project(cfg.Quota, info, now)
+		rep.Quota = &r
+	case errors.Is(err, portal.ErrStatusUnsupported):
+		rep.SessionError = "当前驱动不支持查询"
+	default:
+		rep.SessionError = err.Error()
+	}
+	if st, paused := pause.Check(pausePath(), now); paused {
+		rep.Paused = true
+		rep.PauseMessage = st.Describe(now)
+	}
+
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(rep)
+	}
+
+	fmt.Printf("网络探测:   %s（IPv4 %s", onlineText(rep.Online), onlineText(rep.Probe.IPv4))
+	if rep.Probe.HasIPv6 {
+		fmt.Printf("，IPv6 %s）\n", onlineText(rep.Probe.IPv6))
+	} else {
+		fmt.Printf("，无 IPv6 地址）\n")
+	}
+	if n := rep.Network; n != nil && n.Interface != "" {
+		fmt.Printf("默认网卡:   %s（网关 %s", n.Interface, n.Gateway)
+		if n.GatewayMAC != "" {
+			fmt.Printf(" %s", n.GatewayMAC)
+		}
+		if n.DNSSuffix != "" {
+			fmt.Printf("，域 %s", n.DNSSuffix)
+		}
+		fmt.Printf("）\n")
+	}
+	for _, w := range rep.WiFi {
+		if w.Connected() {
+			fmt.Printf("Wi-Fi:      %s 已连接 %s（%s，信号 %d%%）\n", w.Name, w.SSID, w.BSSID, w.Signal)
+		} else {
+			fmt.Printf("Wi-Fi:      %s 未连接\n", w.Name)
+		}
+	}
+	if rep.NetworkMatch != "" {
+		fmt.Printf("网络匹配:   不匹配（%s）\n", rep.NetworkMatch)
+	} else if ifinfo.Enabled(cfg.Match) {
+		fmt.Printf("网络匹配:   匹配\n")
+	}
+	fmt.Printf("网关驱动:   %s\n", rep.Driver)
+	if rep.Session == nil {
+		fmt.Printf("网关会话:   未知（%s）\n", rep.SessionError)
+	} else {
+		s := rep.Session
+		fmt.Printf("网关会话:   %s\n", onlineText(s.Online))
+		if s.Online {
+			fmt.Printf("  账号:     %s\n", s.Account)
+			fmt.Printf("  IP:       %s\n", s.IP)
+			if s.IPv6 != "" {
+				fmt.Printf("  IPv6:     %s\n", s.IPv6)
+			}
+			if s.MAC != "" {
+				fmt.Printf("  MAC:      %s\n", s.MAC)
+			}
+			fmt.Printf("  已用流量: %s\n", portal.FormatBytes(s.UsedBytes))
+			fmt.Printf("  在线时长: %s\n", time.Duration(s.OnlineSeconds)*time.Second)
+			if s.HasBalance {
+				fmt.Printf("  余额:     %.2f 元\n", s.Balance)
+			}
+			if q := rep.Quota; q.QuotaBytes > 0 {
+				fmt.Printf("  本期额度: %s (%.0f%%)\n", portal.FormatBytes(q.QuotaBytes), q.UsedPercent)
+				if !q.ExhaustAt.IsZero() {
+					fmt.Printf("  预计用尽: %s\n", q.ExhaustAt.Format("2006-01-02 15:04"))
+				}
+			}
+		}
+	}
+	if rep.Paused {
+		fmt.Printf("自动登录:   %s\n", rep.PauseMessage)
+	} else {
+		fmt.Printf("自动登录:   %s\n", rep.Schedule)
+	}
+	return nil
+}
+
+func onlineText(online bool) string {
+	if online {
+		return "在线"
+	}
+	return "离线"
+}
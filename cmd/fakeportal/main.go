@@ -0,0 +1,70 @@
+// Command fakeportal serves a fake Dr.COM / Srun campus portal for
+// development away from campus. Point login_url / status_url at it (the
+// portal section to use is printed on start) and drive failures through
+// the /_fake/ endpoints, e.g.
+//
+//	curl 'http://127.0.0.1:8080/_fake/fault?login=arrears&times=1'
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"time"
+
+	"CUMT-autologin/internal/fakeportal"
+
+	"gopkg.in/yaml.v3"
+)
+
+func main() {
+	addr := flag.String("addr", "127.0.0.1:8080", "监听地址")
+	driver := flag.String("driver", fakeportal.DriverDrcom, "模拟的网关类型: drcom / srun")
+	account := flag.String("account", "", "接受的账号（含运营商后缀），为空接受任意账号")
+	password := flag.String("password", "", "接受的密码，为空接受任意密码")
+	maxDevices := flag.Int("max-devices", 0, "每个账号的在线设备上限，0 不限")
+	idle := flag.Duration("idle", 0, "会话空闲多久后过期，0 不过期")
+	usedMB := flag.Int64("used-mb", 1024, "状态接口报告的已用流量 (MB)")
+	balance := flag.Float64("balance", 30, "状态接口报告的余额（元）")
+	fail := flag.String("fail", "", "登录一律失败: password / arrears / device_limit / no_account")
+	status := flag.Int("status", 0, "所有网关请求返回该 HTTP 状态码，如 502")
+	delay := flag.Duration("delay", 0, "每个响应前的延迟")
+	flag.Parse()
+
+	srv := fakeportal.New(fakeportal.Options{
+		Driver:      *driver,
+		Account:     *account,
+		Password:    *password,
+		MaxDevices:  *maxDevices,
+		IdleTimeout: *idle,
+		UsedBytes:   *usedMB << 20,
+		Balance:     *balance,
+	})
+	srv.SetFault(fakeportal.Fault{Login: *fail, HTTPStatus: *status, Delay: *delay})
+
+	pc := srv.PortalConfig("http://" + *addr)
+	out, err := yaml.Marshal(map[string]any{"portal": map[string]any{
+		"driver":           pc.Driver,
+		"login_url":        pc.LoginURL,
+		"status_url":       pc.StatusURL,
+		"method":           pc.Method,
+		"form":             pc.Form,
+		"success_keywords": pc.SuccessKeywords,
+	}})
+	if err == nil {
+		fmt.Printf("# config.yaml 中的 portal 配置:\n%s\n", out)
+	}
+
+	log.Printf("[fakeportal] %s portal listening on http://%s", *driver, *addr)
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		srv.ServeHTTP(w, r)
+		log.Printf("[fakeportal] %s %s %s (%s)", r.RemoteAddr, r.Method, r.URL.RequestURI(), time.Since(start).Round(time.Millisecond))
+	})
+	if err := http.ListenAndServe(*addr, handler); err != nil {
+		log.Printf("[fakeportal] %v", err)
+		os.Exit(1)
+	}
+}
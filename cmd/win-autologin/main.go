@@ -4,6 +4,8 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"os"
 	"os/exec"
@@ -12,8 +14,18 @@ import (
 	"time"
 
 	"CUMT-autologin/internal/config"
-	"CUMT-autologin/internal/netcheck"
+	"CUMT-autologin/internal/history"
+	"CUMT-autologin/internal/ifinfo"
+	"CUMT-autologin/internal/keepalive"
+	"CUMT-autologin/internal/monitor"
+	"CUMT-autologin/internal/netwatch"
+	"CUMT-autologin/internal/notify"
+	"CUMT-autologin/internal/pause"
 	"CUMT-autologin/internal/portal"
+	"CUMT-autologin/internal/quota"
+	"CUMT-autologin/internal/schedule"
+	"CUMT-autologin/internal/trayicon"
+	"CUMT-autologin/internal/wifi"
 
 	"github.com/getlantern/systray"
 
@@ -26,21 +38,62 @@ var (
 	loginUseCarrierSuffix = true
 	statusMenu            *systray.MenuItem
 	currentStatusText     = "启动中..."
+	trayInfo              trayicon.Info
 	statusMu              sync.RWMutex
+	trayMu                sync.Mutex
+	trayState             = trayicon.StateUnknown
 	cfgMu                 sync.RWMutex
 	settingsReqCh         chan struct{}
+	notifier                             = notify.New(config.NotifyConfig{})
+	clock                 schedule.Clock = schedule.SystemClock{}
+	appCtx, cancelApp                    = context.WithCancel(context.Background())
+	portalOpts            portal.OptionsCache
 )
 
-const enableBackgroundLoop = false
+// mon checks the session and raises quota alerts for the login loop.
+var mon = &monitor.Monitor{
+	Tracker:  quota.NewTracker(history.DefaultPath),
+	Notifier: notifier,
+	Clock:    clock,
+	Log: func(level, format string, args ...any) {
+		fmt.Println("["+level+"]", fmt.Sprintf(format, args...))
+	},
+}
 
-func setStatus(text string) {
+func setStatus(state trayicon.State, text string) {
 	statusMu.Lock()
 	currentStatusText = text
+	trayInfo.Update(state, text, time.Now())
+	info := trayInfo
 	statusMu.Unlock()
 
 	if statusMenu != nil {
 		statusMenu.SetTitle("状态: " + text)
+		refreshTray(info)
+	}
+}
+
+// setTrayDetails records the SSID / account shown in the tooltip.
+func setTrayDetails(ssid, account string) {
+	statusMu.Lock()
+	if ssid != "" {
+		trayInfo.SSID = ssid
+	}
+	if account != "" {
+		trayInfo.Account = account
 	}
+	statusMu.Unlock()
+}
+
+// refreshTray swaps the tray icon when the state changes and rewrites the tooltip.
+func refreshTray(info trayicon.Info) {
+	trayMu.Lock()
+	defer trayMu.Unlock()
+	if info.State != trayState {
+		trayState = info.State
+		systray.SetIcon(trayicon.Icon(iconData, info.State))
+	}
+	systray.SetTooltip(trayicon.Tooltip(info, time.Now()))
 }
 
 func getStatus() string {
@@ -84,88 +137,147 @@ func snapshotConfig() *config.Config {
 }
 
 func runDaemon(_ *config.Config, stopCh <-chan struct{}) {
-	forceLoginInterval := 2 * time.Minute
-	var lastLoginTime time.Time
+	var keeper keepalive.Timer
+
+	events, err := netwatch.Watch(appCtx, netwatch.Options{
+		SSID: func() (string, error) { return wifi.CurrentSSID("") },
+	})
+	if err != nil {
+		fmt.Println("[WARN] network change events unavailable, polling only:", err)
+	}
 
 	for {
 		cfg := snapshotConfig()
 		if cfg == nil {
-			setStatus("配置未加载")
+			setStatus(trayicon.StateUnknown, "配置未加载")
 			time.Sleep(2 * time.Second)
 			continue
 		}
 
+		notifier.SetConfig(cfg.Notify)
+
 		interval := cfg.AutoLoginInterval
 		if interval <= 0 {
 			interval = 10
 		}
 		checkInterval := time.Duration(interval) * time.Second
+		if plan := schedule.Evaluate(cfg.Schedule, clock.Now()); plan.Interval > 0 {
+			checkInterval = plan.Interval
+		}
 
 		select {
 		case <-stopCh:
-			setStatus("已停止")
+			setStatus(trayicon.StateUnknown, "已停止")
 			return
 		case <-time.After(checkInterval):
-			now := time.Now()
-
-			if cfg.WifiSSID != "" {
-				ssid, err := currentSSID()
-				if err != nil {
-					fmt.Println("[WARN] get current ssid error:", err)
-					setStatus("读取 WiFi 状态出错")
-					continue
-				}
-				if ssid == "" {
-					fmt.Println("[INFO] no wifi connected, skip")
-					setStatus("未连接 WiFi")
-					continue
-				}
-				if ssid != cfg.WifiSSID {
-					fmt.Printf("[INFO] current ssid=%q, target=%q, skip\n", ssid, cfg.WifiSSID)
-					setStatus("已连接 " + ssid + " (非目标)")
-					continue
-				}
-			} else {
-				setStatus("未限制 WiFi")
-			}
+		case ev := <-events:
+			fmt.Printf("[INFO] network changed (%s), check now\n", ev)
+		}
 
-			needForceLogin := false
-			if lastLoginTime.IsZero() || now.Sub(lastLoginTime) >= forceLoginInterval {
-				needForceLogin = true
-			}
+		now := clock.Now()
 
-			online := netcheck.IsOnline(cfg.CheckURL)
-			fmt.Println("[DEBUG] IsOnline =", online, "needForceLogin =", needForceLogin)
+		if st, paused := pause.Check(pause.DefaultPath, now); paused {
+			setStatus(trayicon.StatePaused, st.Describe(now))
+			continue
+		}
+
+		plan := schedule.Evaluate(cfg.Schedule, now)
+		if !plan.Active {
+			setStatus(trayicon.StatePaused, plan.Describe())
+			continue
+		}
 
-			if online && !needForceLogin {
-				setStatus("在线")
-				fmt.Println("[INFO] already online and no need to force login, skip")
+		if cfg.WifiSSID != "" {
+			ssid, err := wifi.CurrentSSID(cfg.WifiSSID)
+			if err != nil {
+				fmt.Println("[WARN] get current ssid error:", err)
+				setStatus(trayicon.StateUnknown, "读取 WiFi 状态出错")
 				continue
 			}
+			if ssid == "" {
+				fmt.Println("[INFO] no wifi connected, skip")
+				setStatus(trayicon.StateUnknown, "未连接 WiFi")
+				continue
+			}
+			setTrayDetails(ssid, "")
+			if ssid != cfg.WifiSSID {
+				fmt.Printf("[INFO] current ssid=%q, target=%q, skip\n", ssid, cfg.WifiSSID)
+				setStatus(trayicon.StateUnknown, "已连接 "+ssid+" (非目标)")
+				continue
+			}
+		}
+
+		if ok, why := ifinfo.MatchCurrent(cfg.Match); !ok {
+			fmt.Println("[INFO] network does not match, skip:", why)
+			setStatus(trayicon.StateUnknown, "非校园网络（"+why+"）")
+			continue
+		}
+
+		needKeepalive := plan.ForceLogin && keeper.Due(cfg.Keepalive, now)
+		mode := keepalive.Mode(cfg.Keepalive)
+
+		online, _ := mon.CheckOnline(appCtx, cfg, portalOpts.Get(cfg))
+		fmt.Println("[DEBUG] IsOnline =", online, "needKeepalive =", needKeepalive, "mode =", mode)
+
+		if online {
+			notifier.Online()
+		} else {
+			notifier.Offline()
+		}
 
-			if !online {
-				setStatus("未认证 / 尝试登录中...")
+		if online && !needKeepalive {
+			setStatus(trayicon.StateOnline, "在线")
+			fmt.Println("[INFO] already online and no keepalive due, skip")
+			continue
+		}
+
+		if online && mode != keepalive.ModeRelogin {
+			setStatus(trayicon.StateOnline, "在线")
+			if err := keepalive.Ping(appCtx, cfg, portalOpts.Get(cfg)); err != nil {
+				fmt.Printf("[WARN] keepalive (%s) failed: %v\n", mode, err)
 			} else {
-				setStatus("定期重登录中...")
+				fmt.Printf("[INFO] keepalive (%s) sent\n", mode)
 			}
+			keeper.Reset(now)
+			continue
+		}
 
-			fmt.Println("[INFO] try login...")
-			body, err := portal.Login(&cfg.Portal)
-			if err != nil {
-				fmt.Println("[ERROR] login error:", err)
-				setStatus("登录失败（请求错误）")
+		if !online {
+			setStatus(trayicon.StateCaptive, "未认证 / 尝试登录中...")
+		} else {
+			setStatus(trayicon.StateLoggingIn, "定期重登录中...")
+		}
+
+		fmt.Println("[INFO] try login...")
+		opts := portalOpts.Get(cfg)
+		reply, err := portal.Submit(appCtx, &cfg.Portal, opts)
+		if err != nil {
+			fmt.Println("[ERROR] login error:", err)
+			notifier.Unreachable(err)
+			setStatus(trayicon.StateFailed, "登录失败（请求错误）")
+			continue
+		}
+
+		keeper.Reset(now)
+		body := reply.Body
+		if rerr := portal.CheckReply(reply, &cfg.Portal); rerr == nil {
+			if err := portal.VerifyLogin(appCtx, &cfg.Portal, opts); err != nil {
+				fmt.Println("[WARN] login accepted but still offline:", err)
+				setStatus(trayicon.StateFailed, "网关已接受登录，但仍无法联网")
 				continue
 			}
-
-			lastLoginTime = now
-			if portal.IsLoginSuccess(body, &cfg.Portal) {
-				fmt.Println("[INFO] login response looks success")
-				setStatus("在线")
-			} else {
-				fmt.Println("[WARN] login response not matched success keywords, save for debug")
-				_ = os.WriteFile("last_login_response.html", []byte(body), 0644)
-				setStatus("登录失败（网关响应异常）")
+			fmt.Println("[INFO] login response looks success")
+			notifier.Online()
+			setStatus(trayicon.StateOnline, "在线")
+		} else {
+			fmt.Println("[WARN] login response rejected, save for debug:", rerr)
+			_ = os.WriteFile("last_login_response.html", []byte(body), 0644)
+			if mon.AutoKick(appCtx, cfg, portalOpts.Get(cfg), body) {
+				setStatus(trayicon.StateCaptive, "已下线最早的其他设备，稍后重试登录")
+				continue
 			}
+			notifier.LoginFailed(portal.FailureReason(body))
+			setStatus(trayicon.StateFailed, "登录失败（网关响应异常）")
 		}
 	}
 }
@@ -194,6 +306,7 @@ func updateLoginAccountFieldsLocked() {
 	}
 	globalCfg.Portal.Form["user_account"] = userAccount
 	globalCfg.Portal.Form["user_password"] = acc.Password
+	setTrayDetails("", userAccount)
 
 	fmt.Println("[INFO] login mode changed, user_account =", userAccount)
 }
@@ -225,9 +338,11 @@ func main() {
 }
 
 func onReady() {
-	systray.SetIcon(iconData)
+	systray.SetIcon(trayicon.Icon(iconData, trayicon.StateUnknown))
 	systray.SetTitle("CUMT Autologin")
-	systray.SetTooltip("CUMT 校园网自动登录")
+	statusMu.RLock()
+	systray.SetTooltip(trayicon.Tooltip(trayInfo, time.Now()))
+	statusMu.RUnlock()
 
 	statusMenu = systray.AddMenuItem("状态: 检测中...", "当前连接状态")
 	statusMenu.Disable()
@@ -240,17 +355,22 @@ func onReady() {
 	mModeCampus := mMode.AddSubMenuItemCheckbox("校园网账号 (纯学号)", "通过校园网账号登录", !loginUseCarrierSuffix)
 
 	mLogout := systray.AddMenuItem("注销当前会话", "调用网关注销接口")
+	mUnbind := systray.AddMenuItem("解绑 MAC（下线其他设备）", "清除账号绑定的设备，释放被其他设备占用的会话")
+
+	mPause := systray.AddMenuItem("暂停自动登录", "临时停止自动登录")
+	mPauseManual := mPause.AddSubMenuItem("暂停（直到手动恢复）", "")
+	mSnooze30 := mPause.AddSubMenuItem("暂停 30 分钟", "")
+	mSnooze1h := mPause.AddSubMenuItem("暂停 1 小时", "")
+	mSnoozeNet := mPause.AddSubMenuItem("暂停至网络变化", "切换网络后自动恢复")
+	mResume := systray.AddMenuItem("恢复自动登录", "立即恢复自动登录")
 
 	mOpenSettings := systray.AddMenuItem("设置...", "打开设置窗口")
 	mOpenConfig := systray.AddMenuItem("打开配置文件 (YAML)", "用记事本打开 config.yaml")
 
 	mQuit := systray.AddMenuItem("退出", "退出自动登录")
 
-	var stopCh chan struct{}
-	if enableBackgroundLoop {
-		stopCh = make(chan struct{})
-		go runDaemon(globalCfg, stopCh)
-	}
+	stopCh := make(chan struct{})
+	go runDaemon(globalCfg, stopCh)
 
 	cfgMu.RLock()
 	initialOpenSettings := false
@@ -272,6 +392,24 @@ func onReady() {
 			case <-mLogout.ClickedCh:
 				go logoutOnce()
 
+			case <-mUnbind.ClickedCh:
+				go unbindOnce()
+
+			case <-mPauseManual.ClickedCh:
+				applyPause(pause.Pause(pause.DefaultPath))
+
+			case <-mSnooze30.ClickedCh:
+				applyPause(pause.Snooze(pause.DefaultPath, 30*time.Minute))
+
+			case <-mSnooze1h.ClickedCh:
+				applyPause(pause.Snooze(pause.DefaultPath, time.Hour))
+
+			case <-mSnoozeNet.ClickedCh:
+				applyPause(pause.SnoozeUntilNetworkChange(pause.DefaultPath))
+
+			case <-mResume.ClickedCh:
+				applyPause(pause.Resume(pause.DefaultPath))
+
 			case <-mOpenSettings.ClickedCh:
 				requestOpenSettings()
 
@@ -301,9 +439,7 @@ func onReady() {
 				mModeCampus.Check()
 
 			case <-mQuit.ClickedCh:
-				if stopCh != nil {
-					close(stopCh)
-				}
+				close(stopCh)
 				systray.Quit()
 				return
 			}
@@ -313,6 +449,23 @@ func onReady() {
 
 func onExit() {
 	fmt.Println("[INFO] systray exiting")
+	cancelApp()
+}
+
+// applyPause reflects a pause / resume action in the tray right away.
+func applyPause(err error) {
+	if err != nil {
+		fmt.Println("[ERROR] update pause state error:", err)
+		return
+	}
+	now := time.Now()
+	if st, paused := pause.Check(pause.DefaultPath, now); paused {
+		fmt.Println("[INFO] auto-login paused:", st.Describe(now))
+		setStatus(trayicon.StatePaused, st.Describe(now))
+		return
+	}
+	fmt.Println("[INFO] auto-login resumed")
+	setStatus(trayicon.StateUnknown, "已恢复自动登录")
 }
 
 func requestOpenSettings() {
@@ -329,7 +482,7 @@ func requestOpenSettings() {
 func loginOnce() {
 	cfg := snapshotConfig()
 	if cfg == nil {
-		setStatus("配置未加载")
+		setStatus(trayicon.StateUnknown, "配置未加载")
 		return
 	}
 	cfgMu.RLock()
@@ -348,19 +501,34 @@ func loginOnce() {
 	cfg.Portal.Form["user_account"] = userAccount
 	cfg.Portal.Form["user_password"] = acc.Password
 
-	setStatus("手动登录中...")
-	body, err := portal.Login(&cfg.Portal)
+	notifier.SetConfig(cfg.Notify)
+	setStatus(trayicon.StateLoggingIn, "手动登录中...")
+	opts := portalOpts.Get(cfg)
+	reply, err := portal.Submit(appCtx, &cfg.Portal, opts)
 	if err != nil {
 		fmt.Println("[ERROR] manual login error:", err)
-		setStatus("手动登录失败")
+		notifier.Unreachable(err)
+		setStatus(trayicon.StateFailed, "手动登录失败")
 		return
 	}
-	if portal.IsLoginSuccess(body, &cfg.Portal) {
+	body := reply.Body
+	if rerr := portal.CheckReply(reply, &cfg.Portal); rerr == nil {
+		if err := portal.VerifyLogin(appCtx, &cfg.Portal, opts); err != nil {
+			fmt.Println("[WARN] manual login accepted but still offline:", err)
+			setStatus(trayicon.StateFailed, "网关已接受登录，但仍无法联网")
+			return
+		}
 		fmt.Println("[INFO] manual login success")
-		setStatus("在线（手动登录成功）")
+		notifier.Online()
+		setStatus(trayicon.StateOnline, "在线（手动登录成功）")
 	} else {
-		fmt.Println("[WARN] manual login response not matched success keywords")
-		setStatus("手动登录失败（网关响应异常）")
+		fmt.Println("[WARN] manual login response rejected:", rerr)
+		if mon.AutoKick(appCtx, cfg, portalOpts.Get(cfg), body) {
+			setStatus(trayicon.StateCaptive, "已下线最早的其他设备，稍后重试登录")
+			return
+		}
+		notifier.LoginFailed(portal.FailureReason(body))
+		setStatus(trayicon.StateFailed, "手动登录失败（网关响应异常）")
 	}
 }
 
@@ -374,30 +542,51 @@ func settingsThread() {
 
 func logoutOnce() {
 	fmt.Println("[INFO] manual logout triggered from tray")
-	setStatus("手动注销中...")
+	setStatus(trayicon.StateUnknown, "手动注销中...")
 	cfg := snapshotConfig()
 	if cfg == nil {
-		setStatus("配置未加载")
+		setStatus(trayicon.StateUnknown, "配置未加载")
+		return
+	}
+	res, err := portal.Logout(appCtx, &cfg.Portal, portalOpts.Get(cfg))
+	if errors.Is(err, portal.ErrLogoutNotConfigured) {
+		fmt.Println("[INFO] logout_form not configured, nothing to do")
+		setStatus(trayicon.StateUnknown, "未配置注销参数")
 		return
 	}
-	body, err := portal.Logout(&cfg.Portal)
 	if err != nil {
 		fmt.Println("[ERROR] logout error:", err)
-		setStatus("注销失败（请求错误）")
+		setStatus(trayicon.StateUnknown, "注销失败（请求错误）")
 		return
 	}
-	if body == "" {
-		fmt.Println("[INFO] logout_form not configured, nothing to do")
-		setStatus("未配置注销参数")
+	if res.OK {
+		fmt.Println("[INFO] logout ok:", res.Message)
+		setStatus(trayicon.StateCaptive, "已注销")
+	} else {
+		fmt.Println("[WARN] logout rejected:", res.Message)
+		setStatus(trayicon.StateUnknown, "注销失败："+res.Message)
+	}
+}
+
+func unbindOnce() {
+	fmt.Println("[INFO] unbind mac triggered from tray")
+	cfg := snapshotConfig()
+	if cfg == nil {
+		setStatus(trayicon.StateUnknown, "配置未加载")
 		return
 	}
-	if portal.IsLoginSuccess(body, &cfg.Portal) {
-		fmt.Println("[INFO] logout response looks success")
-		setStatus("已注销")
-	} else {
-		fmt.Println("[WARN] logout response may not be success")
-		setStatus("注销可能失败（检查浏览器）")
+	res, err := portal.UnbindMAC(appCtx, &cfg.Portal, "", portalOpts.Get(cfg))
+	if err != nil {
+		fmt.Println("[ERROR] unbind mac error:", err)
+		setStatus(trayicon.StateUnknown, "解绑失败："+err.Error())
+		return
+	}
+	fmt.Println("[INFO] unbind mac:", res.OK, res.Message)
+	if !res.OK {
+		setStatus(trayicon.StateUnknown, "解绑失败："+res.Message)
+		return
 	}
+	setStatus(trayicon.StateUnknown, "已解绑，其他设备已下线")
 }
 
 func openConfig() {
@@ -16,12 +16,94 @@ const (
 var DefaultConfigPath = detectDefaultConfigPath()
 
 type PortalConfig struct {
+	// Preset names a built-in portal preset or a preset file; the other
+	// fields override it.
+	Preset string `yaml:"preset,omitempty"`
+	// Driver selects gateway specific behaviour: generic (default) / drcom / srun.
+	Driver          string            `yaml:"driver"`
 	LoginURL        string            `yaml:"login_url"`
+	StatusURL       string            `yaml:"status_url"`
+	LogoutURL       string            `yaml:"logout_url"` // derived from login_url when empty
+	UnbindURL       string            `yaml:"unbind_url"` // derived from login_url when empty
 	Method          string            `yaml:"method"`
 	Form            map[string]string `yaml:"form"`
 	LogoutForm      map[string]string `yaml:"logout_form"`
 	Headers         map[string]string `yaml:"headers"`
 	SuccessKeywords []string          `yaml:"success_keywords"`
+	LogoutKeywords  []string          `yaml:"logout_keywords"` // generic driver only
+	// Success refines how a login reply is judged, on top of SuccessKeywords.
+	Success SuccessRules `yaml:"success"`
+	// PasswordEncoding transforms the password before it is sent.
+	PasswordEncoding PasswordEncoding `yaml:"password_encoding"`
+	// Flow fetches the login page first, for form portals that need a
+	// session cookie and hidden fields.
+	Flow LoginFlow `yaml:"flow"`
+	// DualStack sends both the IPv4 and IPv6 address when logging in, for
+	// gateways that authenticate each family separately.
+	DualStack bool `yaml:"dual_stack"`
+
+	SelfService SelfServiceConfig `yaml:"self_service"`
+}
+
+// SuccessRules decide whether the gateway accepted a login. Every rule that
+// is set must hold; a failure keyword rejects the reply whatever else matches.
+type SuccessRules struct {
+	Status          []int             `yaml:"status"`           // accepted HTTP status codes
+	Regex           []string          `yaml:"regex"`            // each must match the body
+	Fields          map[string]string `yaml:"fields"`           // JSON / JSONP path -> value, e.g. result: "1"
+	FailureKeywords []string          `yaml:"failure_keywords"` // any of them means failure
+	Location        string            `yaml:"location"`         // regex the URL after redirects must match
+	// Verify checks that the network works after the gateway accepted the
+	// login; it is implied when no rule at all is configured.
+	Verify        bool `yaml:"verify"`
+	VerifyTimeout int  `yaml:"verify_timeout"` // seconds, default 10
+}
+
+// PasswordEncoding describes how a portal wants the password: hashed,
+// base64'd or RSA-encrypted with a key published by the portal.
+type PasswordEncoding struct {
+	// Scheme is plain (default) / md5 / sha1 / sha256 / base64 /
+	// drcom_md5 ({MD5} + md5) / drcom_base64 ({B} + base64) /
+	// rsa (PKCS#1 v1.5, base64, as JSEncrypt) / rsa_no_padding (reversed
+	// password, no padding, hex, as RSAUtils.js).
+	Scheme string `yaml:"scheme"`
+	Prefix string `yaml:"prefix"` // prepended to the encoded password
+	// RSAKey is a PEM public key or a hex modulus; when empty the key is
+	// read from the page at RSAKeyURL.
+	RSAKey      string `yaml:"rsa_key"`
+	RSAExponent string `yaml:"rsa_exponent"` // hex, default 10001
+	RSAKeyURL   string `yaml:"rsa_key_url"`
+	// RSAKeyPattern picks the key out of that page with its first group;
+	// PEM blocks and "modulus" / "exponent" assignments are found without it.
+	RSAKeyPattern string `yaml:"rsa_key_pattern"`
+}
+
+// LoginFlow describes the page step of a multi-step login: the page is
+// fetched with a fresh cookie jar, its hidden inputs (__VIEWSTATE, CSRF
+// tokens...) are merged under the configured form, and the form is then
+// submitted with the same cookies.
+type LoginFlow struct {
+	PageURL string   `yaml:"page_url"` // the login page; the flow is off when empty
+	Form    string   `yaml:"form"`     // id or name of the form, default the one with a password input
+	Fields  []string `yaml:"fields"`   // hidden inputs to copy, default all of them
+	// Extract maps form fields to regexps over the page URL and body; the
+	// first group, or the whole match, becomes the value.
+	Extract map[string]string `yaml:"extract"`
+	// QueryField receives the query string of the page URL after
+	// redirects, e.g. queryString for Ruijie portals.
+	QueryField string `yaml:"query_field"`
+	UseAction  bool   `yaml:"use_action"` // submit to the form's action instead of login_url
+}
+
+// SelfServiceConfig points at the account self-service system, which lists
+// and kicks the online sessions of the account.
+type SelfServiceConfig struct {
+	URL           string `yaml:"url"`            // e.g. http://202.119.196.6:8080/Self
+	Username      string `yaml:"username"`       // defaults to account.student_id
+	Password      string `yaml:"password"`       // defaults to account.password
+	PlainPassword bool   `yaml:"plain_password"` // send the password as is instead of md5
+	// AutoKick kicks the oldest other session when login hits the device limit.
+	AutoKick bool `yaml:"auto_kick"`
 }
 
 type AccountConfig struct {
@@ -29,17 +111,127 @@ type AccountConfig struct {
 	Carrier   string `yaml:"carrier"` // telecom / unicom / cmcc
 	Password  string `yaml:"password"`
 }
+
+// NotifyConfig controls desktop notifications raised on connection state changes.
+type NotifyConfig struct {
+	Enabled bool `yaml:"enabled" json:"enabled"`
+
+	OnOnline      bool `yaml:"on_online" json:"on_online"`
+	OnOffline     bool `yaml:"on_offline" json:"on_offline"`
+	OnLoginFailed bool `yaml:"on_login_failed" json:"on_login_failed"`
+	OnUnreachable bool `yaml:"on_unreachable" json:"on_unreachable"`
+	OnQuota       bool `yaml:"on_quota" json:"on_quota"`
+
+	// UnreachableMinutes is how long the portal must stay unreachable before notifying.
+	UnreachableMinutes int `yaml:"unreachable_minutes" json:"unreachable_minutes"`
+	// MinInterval is the minimum number of seconds between two notifications of the same kind.
+	MinInterval int `yaml:"min_interval" json:"min_interval"`
+
+	Webhooks []WebhookConfig `yaml:"webhooks" json:"webhooks"`
+}
+
+// WebhookConfig describes an outbound chat-bot / HTTP sink for notifications.
+type WebhookConfig struct {
+	Name string `yaml:"name" json:"name"`
+	// Kind selects the payload format: generic / wecom / dingtalk / feishu / serverchan / telegram.
+	Kind    string            `yaml:"kind" json:"kind"`
+	URL     string            `yaml:"url" json:"url"`
+	Headers map[string]string `yaml:"headers" json:"headers"`
+	// ChatID is only used by telegram.
+	ChatID string `yaml:"chat_id" json:"chat_id"`
+	// Events limits which events are sent; empty means all. Webhooks do not
+	// depend on notify.enabled or the on_* switches, which are for toasts.
+	Events []string `yaml:"events" json:"events"`
+}
+
+// QuotaConfig describes the monthly traffic allowance and warning thresholds.
+type QuotaConfig struct {
+	// MonthlyGB is the traffic allowance per period; 0 disables quota warnings.
+	MonthlyGB float64 `yaml:"monthly_gb" json:"monthly_gb"`
+	// ResetDay is the day of month the allowance resets (1-28).
+	ResetDay int `yaml:"reset_day" json:"reset_day"`
+	// WarnPercents lists usage percentages that trigger a warning.
+	WarnPercents []int `yaml:"warn_percents" json:"warn_percents"`
+	// MinBalance warns when the balance (yuan) drops below it; 0 disables.
+	MinBalance float64 `yaml:"min_balance" json:"min_balance"`
+}
+
+// ScheduleConfig lists time windows that change how the login loop behaves.
+type ScheduleConfig struct {
+	Windows []ScheduleWindow `yaml:"windows" json:"windows"`
+}
+
+// ScheduleWindow applies between Start and End ("HH:MM", may cross midnight)
+// on the listed Days (mon..sun, ranges like mon-fri, weekdays / weekends).
+type ScheduleWindow struct {
+	Name  string   `yaml:"name" json:"name"`
+	Days  []string `yaml:"days" json:"days"`
+	Start string   `yaml:"start" json:"start"`
+	End   string   `yaml:"end" json:"end"`
+
+	// AutoLogin=false turns the window into quiet hours.
+	AutoLogin *bool `yaml:"auto_login,omitempty" json:"auto_login,omitempty"`
+	// ForceLogin toggles the periodic keepalive action (see keepalive.mode)
+	// inside the window.
+	ForceLogin *bool `yaml:"force_login,omitempty" json:"force_login,omitempty"`
+	// Interval overrides auto_login_interval (seconds) inside the window.
+	Interval int `yaml:"interval,omitempty" json:"interval,omitempty"`
+}
+
+// NetworkConfig pins gateway and probe traffic to one interface or source
+// address, e.g. when both Ethernet and Wi-Fi are up or a VPN is running.
+type NetworkConfig struct {
+	Interface string `yaml:"interface" json:"interface"` // e.g. eth0, wlan0, "WLAN"
+	SourceIP  string `yaml:"source_ip" json:"source_ip"` // wins over interface
+	// UseSystemProxy sends gateway requests through HTTP_PROXY; by default
+	// they always go direct, like the connectivity probes.
+	UseSystemProxy bool `yaml:"use_system_proxy" json:"use_system_proxy"`
+	// Record saves every gateway exchange, passwords redacted, to this
+	// HAR file for bug reports.
+	Record string `yaml:"record" json:"record"`
+}
+
+// MatchConfig limits auto-login to the campus LAN whatever the medium. Every
+// non-empty list must have a matching entry; wifi_ssid still applies on top.
+type MatchConfig struct {
+	Gateways    []string `yaml:"gateways" json:"gateways"`         // default gateway IPs
+	GatewayMACs []string `yaml:"gateway_macs" json:"gateway_macs"` // MAC of the default gateway
+	DHCPDomains []string `yaml:"dhcp_domains" json:"dhcp_domains"` // DNS suffix handed out by DHCP, e.g. cumt.edu.cn
+	Interfaces  []string `yaml:"interfaces" json:"interfaces"`     // names or patterns such as "eth*"
+	Subnets     []string `yaml:"subnets" json:"subnets"`           // CIDRs the interface address must fall in
+}
+
+// KeepaliveConfig decides how an online session is kept from expiring when
+// the machine is idle.
+type KeepaliveConfig struct {
+	// Mode is keepalive (default, a lightweight request to the gateway),
+	// status (query the session state), relogin (log in again) or off.
+	Mode string `yaml:"mode" json:"mode"`
+	// Interval is the number of seconds between two keepalive actions.
+	Interval int `yaml:"interval" json:"interval"`
+	// URL is the gateway's heartbeat endpoint; defaults to the status
+	// endpoint, then the portal root.
+	URL string `yaml:"url" json:"url"`
+}
+
 type UIConfig struct {
 	Width  int `yaml:"width"`
 	Height int `yaml:"height"`
 }
 
 type Config struct {
-	WifiSSID string        `yaml:"wifi_ssid"`
-	CheckURL string        `yaml:"check_url"`
-	Account  AccountConfig `yaml:"account"`
-	Portal   PortalConfig  `yaml:"portal"`
-	UI       UIConfig      `yaml:"ui"`
+	WifiSSID string         `yaml:"wifi_ssid"`
+	CheckURL string         `yaml:"check_url"`
+	Account  AccountConfig  `yaml:"account"`
+	Portal   PortalConfig   `yaml:"portal"`
+	UI       UIConfig       `yaml:"ui"`
+	Notify   NotifyConfig   `yaml:"notify" json:"notify"`
+	Schedule ScheduleConfig `yaml:"schedule" json:"schedule"`
+	Quota    QuotaConfig    `yaml:"quota" json:"quota"`
+	Network  NetworkConfig  `yaml:"network" json:"network"`
+	Match    MatchConfig    `yaml:"match" json:"match"`
+
+	Keepalive KeepaliveConfig `yaml:"keepalive" json:"keepalive"`
 
 	AutoLoginInterval int    `yaml:"auto_login_interval" json:"auto_login_interval"`
 	LoginMode         string `yaml:"login_mode" json:"login_mode"`
@@ -107,6 +299,11 @@ func Load(path string) (*Config, error) {
 		return nil, err
 	}
 	c.path = path
+	if c.Portal.Preset != "" {
+		if err := applyPreset(&c, data, filepath.Dir(path)); err != nil {
+			return nil, err
+		}
+	}
 
 	if c.CheckURL == "" {
 		c.CheckURL = "http://www.msftconnecttest.com/connecttest.txt"
@@ -148,6 +345,23 @@ func Load(path string) (*Config, error) {
 		c.OpenSettingsOnRun = true
 	}
 
+	applyNotifyDefaults(&c.Notify, raw)
+	if c.Quota.ResetDay < 1 || c.Quota.ResetDay > 28 {
+		c.Quota.ResetDay = 1
+	}
+	if len(c.Quota.WarnPercents) == 0 {
+		c.Quota.WarnPercents = []int{80, 95}
+	}
+	if !hasKey(raw, "quota", "min_balance") {
+		c.Quota.MinBalance = 5
+	}
+	if c.Keepalive.Mode == "" {
+		c.Keepalive.Mode = "keepalive"
+	}
+	if c.Keepalive.Interval <= 0 {
+		c.Keepalive.Interval = 120
+	}
+
 	if c.Account.StudentID != "" {
 		suffix := CarrierSuffix(c.Account.Carrier)
 		userAccount := c.Account.StudentID + suffix
@@ -158,11 +372,100 @@ func Load(path string) (*Config, error) {
 	return &c, nil
 }
 
+// hasKey reports whether the nested YAML path exists in raw.
+func hasKey(raw map[string]any, path ...string) bool {
+	cur := raw
+	for i, key := range path {
+		v, ok := cur[key]
+		if !ok {
+			return false
+		}
+		if i == len(path)-1 {
+			return true
+		}
+		next, ok := v.(map[string]any)
+		if !ok {
+			return false
+		}
+		cur = next
+	}
+	return false
+}
+
+func applyNotifyDefaults(n *NotifyConfig, raw map[string]any) {
+	if !hasKey(raw, "notify", "enabled") {
+		n.Enabled = true
+	}
+	if !hasKey(raw, "notify", "on_online") {
+		n.OnOnline = true
+	}
+	if !hasKey(raw, "notify", "on_offline") {
+		n.OnOffline = true
+	}
+	if !hasKey(raw, "notify", "on_login_failed") {
+		n.OnLoginFailed = true
+	}
+	if !hasKey(raw, "notify", "on_unreachable") {
+		n.OnUnreachable = true
+	}
+	if !hasKey(raw, "notify", "on_quota") {
+		n.OnQuota = true
+	}
+	if n.UnreachableMinutes <= 0 {
+		n.UnreachableMinutes = 5
+	}
+	if n.MinInterval <= 0 {
+		n.MinInterval = 300
+	}
+	for i := range n.Webhooks {
+		if n.Webhooks[i].Kind == "" {
+			n.Webhooks[i].Kind = "generic"
+		}
+	}
+}
+
+// LoginAccount is the account sent to the gateway: the student ID with the
+// carrier suffix, or the bare ID in campus_only mode.
+func (c *Config) LoginAccount() string {
+	if strings.ToLower(c.LoginMode) == "campus_only" {
+		return c.Account.StudentID
+	}
+	return c.Account.StudentID + CarrierSuffix(c.Account.Carrier)
+}
+
+// SelfServiceCredentials returns the self-service login, falling back to the
+// portal account.
+func (c *Config) SelfServiceCredentials() (username, password string) {
+	username, password = c.Portal.SelfService.Username, c.Portal.SelfService.Password
+	if username == "" {
+		username = c.Account.StudentID
+	}
+	if password == "" {
+		password = c.Account.Password
+	}
+	return username, password
+}
+
 func (c *Config) Save() error {
 	if c.path == "" {
 		c.path = DefaultConfigPath
 	}
-	out, err := yaml.Marshal(c)
+	var doc yaml.Node
+	if err := doc.Encode(c); err != nil {
+		return err
+	}
+	if c.Portal.Preset != "" {
+		portal, err := presetPortalNode(c.Portal, filepath.Dir(c.path))
+		if err != nil {
+			return err
+		}
+		for i := 0; i+1 < len(doc.Content); i += 2 {
+			if doc.Content[i].Value == "portal" {
+				doc.Content[i+1] = portal
+			}
+		}
+	}
+	out, err := yaml.Marshal(&doc)
 	if err != nil {
 		return err
 	}
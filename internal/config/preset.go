@@ -0,0 +1,204 @@
+package config
+
+import (
+	"embed"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+//go:embed presets/*.yaml
+var presetFS embed.FS
+
+// Preset is a named portal block that portal.preset refers to. The fields
+// of the user's portal block override the preset's one by one; form maps
+// are merged key by key.
+type Preset struct {
+	Name        string       `yaml:"name"`
+	Description string       `yaml:"description"`
+	Portal      PortalConfig `yaml:"portal"`
+}
+
+// Presets returns the built-in presets sorted by name.
+func Presets() []Preset {
+	entries, _ := presetFS.ReadDir("presets")
+	var list []Preset
+	for _, e := range entries {
+		data, err := presetFS.ReadFile("presets/" + e.Name())
+		if err != nil {
+			continue
+		}
+		var p Preset
+		if yaml.Unmarshal(data, &p) == nil {
+			list = append(list, p)
+		}
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list
+}
+
+// LookupPreset returns the built-in preset called name, or reads a preset
+// file when name ends in .yaml / .yml; relative paths are resolved against
+// dir, the directory of the config file.
+func LookupPreset(name, dir string) (*Preset, error) {
+	if ext := strings.ToLower(filepath.Ext(name)); ext == ".yaml" || ext == ".yml" {
+		path := name
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(dir, path)
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		var p Preset
+		if err := yaml.Unmarshal(data, &p); err != nil {
+			return nil, fmt.Errorf("preset %s: %w", name, err)
+		}
+		return &p, nil
+	}
+	for _, p := range Presets() {
+		if strings.EqualFold(p.Name, name) {
+			return &p, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown portal preset %q", name)
+}
+
+// ExportPreset renders p as a shareable preset file. Credentials are left
+// out: the account fields, self-service and anything password-like.
+func ExportPreset(p PortalConfig, name, description string) ([]byte, error) {
+	p.Preset = ""
+	p.SelfService = SelfServiceConfig{}
+	p.Form = withoutSecrets(p.Form)
+	p.LogoutForm = withoutSecrets(p.LogoutForm)
+	p.Headers = withoutSecrets(p.Headers)
+	var doc yaml.Node
+	if err := doc.Encode(Preset{Name: name, Description: description, Portal: p}); err != nil {
+		return nil, err
+	}
+	pruneEmpty(&doc)
+	return yaml.Marshal(&doc)
+}
+
+// pruneEmpty drops empty strings, false, and empty maps and lists from the
+// mappings under n, so an exported preset only lists what it sets.
+func pruneEmpty(n *yaml.Node) {
+	for _, c := range n.Content {
+		pruneEmpty(c)
+	}
+	if n.Kind != yaml.MappingNode {
+		return
+	}
+	kept := n.Content[:0]
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		v := n.Content[i+1]
+		empty := len(v.Content) == 0 && (v.Kind == yaml.MappingNode || v.Kind == yaml.SequenceNode) ||
+			v.Kind == yaml.ScalarNode && (v.Value == "" || v.Tag == "!!bool" && v.Value == "false")
+		if !empty {
+			kept = append(kept, n.Content[i], v)
+		}
+	}
+	n.Content = kept
+}
+
+// withoutSecrets drops credential entries, keeping the ones that only hold
+// a placeholder such as "{password}".
+func withoutSecrets(m map[string]string) map[string]string {
+	out := make(map[string]string, len(m))
+	for k, v := range m {
+		lk := strings.ToLower(k)
+		placeholder := strings.HasPrefix(v, "{") && strings.HasSuffix(v, "}")
+		if k == "user_account" || (strings.Contains(lk, "pass") || strings.Contains(lk, "pwd")) && !placeholder ||
+			lk == "cookie" || lk == "authorization" {
+			continue
+		}
+		out[k] = v
+	}
+	return out
+}
+
+// applyPreset decodes the portal block of data over the preset named in it.
+func applyPreset(c *Config, data []byte, dir string) error {
+	p, err := LookupPreset(c.Portal.Preset, dir)
+	if err != nil {
+		return err
+	}
+	var doc struct {
+		Portal yaml.Node `yaml:"portal"`
+	}
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return err
+	}
+	portal := p.Portal
+	portal.Form = copyMap(p.Portal.Form)
+	portal.LogoutForm = copyMap(p.Portal.LogoutForm)
+	portal.Headers = copyMap(p.Portal.Headers)
+	if err := doc.Portal.Decode(&portal); err != nil {
+		return err
+	}
+	c.Portal = portal
+	return nil
+}
+
+func copyMap(m map[string]string) map[string]string {
+	if m == nil {
+		return nil
+	}
+	out := make(map[string]string, len(m))
+	for k, v := range m {
+		out[k] = v
+	}
+	return out
+}
+
+// presetPortalNode encodes p for saving without the fields and form entries
+// that equal the preset's, so that the saved config keeps following it.
+func presetPortalNode(p PortalConfig, dir string) (*yaml.Node, error) {
+	var node yaml.Node
+	preset, err := LookupPreset(p.Preset, dir)
+	if err != nil {
+		return &node, node.Encode(p)
+	}
+	dropped := make(map[string]bool)
+	out := reflect.ValueOf(&p).Elem()
+	base := reflect.ValueOf(preset.Portal)
+	for i := 0; i < out.NumField(); i++ {
+		f, b := out.Field(i), base.Field(i)
+		key, _, _ := strings.Cut(out.Type().Field(i).Tag.Get("yaml"), ",")
+		if key == "preset" {
+			continue
+		}
+		if m, ok := f.Interface().(map[string]string); ok {
+			m = copyMap(m)
+			for k, v := range b.Interface().(map[string]string) {
+				if bv, ok := m[k]; ok && bv == v {
+					delete(m, k)
+				}
+			}
+			f.Set(reflect.ValueOf(m))
+			dropped[key] = len(m) == 0
+			continue
+		}
+		if f.Kind() == reflect.Slice && f.Len() == 0 && b.Len() == 0 {
+			dropped[key] = true
+			continue
+		}
+		dropped[key] = reflect.DeepEqual(f.Interface(), b.Interface())
+	}
+	if err := node.Encode(p); err != nil {
+		return nil, err
+	}
+	kept := node.Content[:0]
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if !dropped[node.Content[i].Value] {
+			kept = append(kept, node.Content[i], node.Content[i+1])
+		}
+	}
+	node.Content = kept
+	return &node, nil
+}
@@ -0,0 +1,171 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestPresetsEmbedded(t *testing.T) {
+	names := make(map[string]bool)
+	for _, p := range Presets() {
+		if p.Name == "" || names[p.Name] {
+			t.Errorf("preset name %q missing or duplicated", p.Name)
+		}
+		names[p.Name] = true
+		if p.Portal.Driver == "" || len(p.Portal.SuccessKeywords) == 0 {
+			t.Errorf("preset %s lacks a driver or success keywords", p.Name)
+		}
+	}
+	for _, want := range []string{"cumt", "drcom-eportal", "drcom-eportal-legacy", "srun"} {
+		if !names[want] {
+			t.Errorf("preset %s not embedded", want)
+		}
+	}
+	if _, err := LookupPreset("no-such-preset", ""); err == nil {
+		t.Error("unknown preset found")
+	}
+}
+
+const presetConfig = `account:
+  student_id: "08123456"
+  password: hunter22
+portal:
+  preset: cumt
+  login_url: http://gw.example/eportal/?c=Portal&a=login
+  method: POST
+  form:
+    callback: drcom_custom
+    ac_id: "3"
+`
+
+func TestPresetRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(presetConfig), 0644); err != nil {
+		t.Fatal(err)
+	}
+	c, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	preset, _ := LookupPreset("cumt", "")
+	check := func(stage string, p PortalConfig) {
+		t.Helper()
+		if p.Driver != "drcom" || !reflect.DeepEqual(p.SuccessKeywords, preset.Portal.SuccessKeywords) {
+			t.Errorf("%s: preset fields lost: %+v", stage, p)
+		}
+		if p.LoginURL != "http://gw.example/eportal/?c=Portal&a=login" || p.Method != "POST" {
+			t.Errorf("%s: overrides lost: login_url=%s method=%s", stage, p.LoginURL, p.Method)
+		}
+		for k, want := range map[string]string{"callback": "drcom_custom", "ac_id": "3", "login_method": "1", "user_account": "08123456"} {
+			if p.Form[k] != want {
+				t.Errorf("%s: form %s = %q, want %q", stage, k, p.Form[k], want)
+			}
+		}
+	}
+	check("load", c.Portal)
+
+	if err := c.Save(); err != nil {
+		t.Fatal(err)
+	}
+	data, _ := os.ReadFile(path)
+	var saved struct {
+		Portal map[string]any `yaml:"portal"`
+	}
+	if err := yaml.Unmarshal(data, &saved); err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []string{"driver", "success_keywords"} {
+		if _, ok := saved.Portal[key]; ok {
+			t.Errorf("saved portal repeats the preset's %s:\n%s", key, data)
+		}
+	}
+	form, _ := saved.Portal["form"].(map[string]any)
+	if _, ok := form["login_method"]; ok {
+		t.Errorf("saved form repeats the preset's login_method:\n%s", data)
+	}
+	if form["callback"] != "drcom_custom" || saved.Portal["preset"] != "cumt" {
+		t.Errorf("saved portal lost overrides:\n%s", data)
+	}
+
+	again, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	check("reload", again.Portal)
+}
+
+func TestPresetPortalNode(t *testing.T) {
+	preset, _ := LookupPreset("srun", "")
+	p := preset.Portal
+	p.Preset = "srun"
+	p.Form = copyMap(preset.Portal.Form)
+	p.Form["ac_id"] = "9"
+	p.LoginURL = "http://10.0.0.1/cgi-bin/srun_portal"
+
+	node, err := presetPortalNode(p, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got map[string]any
+	if err := node.Decode(&got); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]any{
+		"preset":    "srun",
+		"login_url": "http://10.0.0.1/cgi-bin/srun_portal",
+		"form":      map[string]any{"ac_id": "9"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("node = %v\nwant %v", got, want)
+	}
+
+	// A preset that cannot be found keeps every field.
+	p.Preset = "gone.yaml"
+	node, _ = presetPortalNode(p, t.TempDir())
+	if err := node.Decode(&got); err != nil || got["driver"] == nil {
+		t.Errorf("missing preset dropped fields: %v, %v", got, err)
+	}
+}
+
+func TestExportPreset(t *testing.T) {
+	p := PortalConfig{
+		Preset:   "cumt",
+		Driver:   "drcom",
+		LoginURL: "http://gw.example/eportal/portal/login",
+		Method:   "GET",
+		Form: map[string]string{
+			"callback": "dr1003", "user_account": "08123456", "user_password": "hunter22",
+			"upass": "{password}",
+		},
+		Headers:     map[string]string{"Cookie": "sid=1", "Referer": "http://gw.example/"},
+		SelfService: SelfServiceConfig{URL: "http://ss.example", Password: "hunter22"},
+	}
+	data, err := ExportPreset(p, "mine", "我的网关")
+	if err != nil {
+		t.Fatal(err)
+	}
+	text := string(data)
+	for _, leak := range []string{"hunter22", "08123456", "sid=1", "self_service", "preset:", "status_url", "dual_stack"} {
+		if strings.Contains(text, leak) {
+			t.Errorf("export contains %q:\n%s", leak, text)
+		}
+	}
+
+	path := filepath.Join(t.TempDir(), "mine.yaml")
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	got, err := LookupPreset("mine.yaml", filepath.Dir(path))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Name != "mine" || got.Portal.LoginURL != p.LoginURL || got.Portal.Form["callback"] != "dr1003" ||
+		got.Portal.Form["upass"] != "{password}" || got.Portal.Headers["Referer"] != "http://gw.example/" {
+		t.Errorf("exported preset = %+v", got)
+	}
+}
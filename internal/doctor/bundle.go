@@ -0,0 +1,272 @@
+package doctor
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"CUMT-autologin/internal/history"
+	"CUMT-autologin/internal/ifinfo"
+	"CUMT-autologin/internal/portal"
+	"CUMT-autologin/internal/wifi"
+)
+
+const (
+	// maxLogTail is how much of the end of each log goes into the bundle.
+	maxLogTail  = 512 << 10
+	historyDays = 7
+	// maxRecordEntries is how many of the latest recorded exchanges go in.
+	maxRecordEntries = 50
+	redacted         = "******"
+)
+
+// logFiles are collected from the config directory and the directory of
+// the running executable.
+var logFiles = []string{"core.log", "gui.log", "gui_app.log", "window_debug.log", "last_login_response.html"}
+
+// WriteBundle writes a support bundle for r to path: the report, the
+// config, log tails, recent history and any recording, all with passwords,
+// tokens and the student ID masked.
+func (r *Report) WriteBundle(path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	zw := zip.NewWriter(f)
+	werr := r.writeBundle(zw)
+	if err := zw.Close(); werr == nil {
+		werr = err
+	}
+	if err := f.Close(); werr == nil {
+		werr = err
+	}
+	return werr
+}
+
+func (r *Report) writeBundle(zw *zip.Writer) error {
+	add := func(name string, data []byte) error {
+		w, err := zw.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate, Modified: r.Time})
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(data)
+		return err
+	}
+
+	var text bytes.Buffer
+	r.WriteText(&text)
+	if err := add("report.txt", r.redact(text.Bytes())); err != nil {
+		return err
+	}
+	report, _ := json.MarshalIndent(r, "", "  ")
+	if err := add("report.json", r.redact(report)); err != nil {
+		return err
+	}
+	if err := add("system.json", r.systemInfo()); err != nil {
+		return err
+	}
+	if data, err := os.ReadFile(r.ConfigPath); err == nil {
+		if err := add("config.yaml", r.redact(redactYAML(data))); err != nil {
+			return err
+		}
+	}
+
+	seen := make(map[string]bool)
+	for _, dir := range r.dirs() {
+		for _, name := range logFiles {
+			if seen[name] {
+				continue
+			}
+			data, err := readTail(filepath.Join(dir, name), maxLogTail)
+			if err != nil {
+				continue
+			}
+			seen[name] = true
+			if err := add("logs/"+name, r.redact(data)); err != nil {
+				return err
+			}
+		}
+	}
+
+	histPath := filepath.Join(filepath.Dir(r.ConfigPath), filepath.Base(history.DefaultPath))
+	if samples, err := history.Load(histPath, r.Time.AddDate(0, 0, -historyDays)); err == nil && len(samples) > 0 {
+		var buf bytes.Buffer
+		enc := json.NewEncoder(&buf)
+		for _, s := range samples {
+			_ = enc.Encode(s)
+		}
+		if err := add("history.jsonl", buf.Bytes()); err != nil {
+			return err
+		}
+	}
+
+	if r.cfg != nil && r.cfg.Network.Record != "" {
+		if data, err := recordTail(r.cfg.Network.Record, maxRecordEntries); err == nil {
+			if err := add("record.har", r.redact(data)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// recordTail re-encodes the last n entries of the recording at path, so
+// the bundle gets a HAR file that still opens in browser dev tools.
+func recordTail(path string, n int) ([]byte, error) {
+	h, err := portal.LoadHAR(path)
+	if err != nil {
+		return nil, err
+	}
+	if over := len(h.Log.Entries) - n; over > 0 {
+		h.Log.Entries = h.Log.Entries[over:]
+	}
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	err = enc.Encode(h)
+	return buf.Bytes(), err
+}
+
+// dirs returns where the logs may be: next to the config and next to the
+// executable.
+func (r *Report) dirs() []string {
+	dirs := []string{filepath.Dir(r.ConfigPath)}
+	if exe, err := os.Executable(); err == nil {
+		if d := filepath.Dir(exe); d != dirs[0] {
+			dirs = append(dirs, d)
+		}
+	}
+	return dirs
+}
+
+func (r *Report) systemInfo() []byte {
+	info := map[string]any{
+		"os":   runtime.GOOS,
+		"arch": runtime.GOARCH,
+		"go":   runtime.Version(),
+		"time": time.Now().Format(time.RFC3339),
+	}
+	if snap, err := ifinfo.Inspect(); err == nil {
+		info["network"] = snap
+	}
+	if list, err := wifi.Interfaces(); err == nil {
+		info["wifi"] = list
+	}
+	data, _ := json.MarshalIndent(info, "", "  ")
+	return r.redact(data)
+}
+
+// secretPairRe matches a password-like key=value, key: value or JSON
+// "key":"value" pair; the value is group 2.
+var secretPairRe = regexp.MustCompile(`(?i)((?:password|passwd|pwd|upass|token|secret|chksum)["']?[ \t]*[=:][ \t]*["']?)([^"'&\s,;]+)`)
+
+// redact masks the secrets of the loaded config wherever they appear, raw,
+// URL encoded or in the form the portal is sent. Secrets shorter than four
+// characters are only masked where they stand alone as a value, so that
+// unrelated text is not mangled. Without a config the secrets are unknown,
+// so password-like fields are masked by name instead.
+func (r *Report) redact(data []byte) []byte {
+	if r.cfg == nil {
+		return secretPairRe.ReplaceAll(data, []byte("${1}"+redacted))
+	}
+	s := string(data)
+	for _, secret := range portal.Secrets(r.cfg) {
+		for _, form := range []string{secret, url.QueryEscape(secret)} {
+			if len(form) >= 4 {
+				s = strings.ReplaceAll(s, form, redacted)
+				continue
+			}
+			re := regexp.MustCompile(`(^|[=:"'\s])` + regexp.QuoteMeta(form) + `($|[&"'\s,;])`)
+			s = re.ReplaceAllString(s, "${1}"+redacted+"${2}")
+		}
+	}
+	if id := r.cfg.Account.StudentID; len(id) >= 4 {
+		s = strings.ReplaceAll(s, id, maskID(id))
+	}
+	return []byte(s)
+}
+
+// maskID keeps the first and last two characters of a student ID, enough
+// to tell accounts apart in a report.
+func maskID(id string) string {
+	if len(id) <= 4 {
+		return strings.Repeat("*", len(id))
+	}
+	return id[:2] + strings.Repeat("*", len(id)-4) + id[len(id)-2:]
+}
+
+// redactYAML masks the values of secret keys and webhook targets; on a
+// parse error the file is dropped rather than leaked.
+func redactYAML(data []byte) []byte {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return []byte("# config.yaml 解析失败，未附带内容\n")
+	}
+	redactNode(&doc, "")
+	out, err := yaml.Marshal(&doc)
+	if err != nil {
+		return nil
+	}
+	return out
+}
+
+func redactNode(n *yaml.Node, parent string) {
+	if n.Kind == yaml.MappingNode {
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			key, val := n.Content[i].Value, n.Content[i+1]
+			if val.Kind == yaml.ScalarNode && val.Value != "" && val.Tag != "!!bool" && secretKey(parent, key) {
+				val.Value, val.Tag, val.Style = redacted, "!!str", 0
+				continue
+			}
+			redactNode(val, key)
+		}
+		return
+	}
+	for _, c := range n.Content {
+		redactNode(c, parent)
+	}
+}
+
+// secretNames are config keys, or key suffixes after "_", holding secrets.
+var secretNames = []string{"password", "passwd", "pwd", "upass", "token", "secret", "chksum"}
+
+func secretKey(parent, key string) bool {
+	k := strings.ToLower(key)
+	for _, name := range secretNames {
+		if k == name || strings.HasSuffix(k, "_"+name) {
+			return true
+		}
+	}
+	// Webhook URLs, headers and chat IDs carry the bot credentials.
+	return parent == "webhooks" && (k == "url" || k == "chat_id") || parent == "headers"
+}
+
+// readTail returns at most max bytes from the end of the file at path.
+func readTail(path string, max int64) ([]byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	st, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	if st.Size() > max {
+		if _, err := f.Seek(-max, io.SeekEnd); err != nil {
+			return nil, err
+		}
+	}
+	return io.ReadAll(f)
+}
@@ -0,0 +1,167 @@
+package doctor
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"CUMT-autologin/internal/config"
+	"CUMT-autologin/internal/portal"
+)
+
+func TestRedactEncodedPasswords(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.Account.StudentID = "08123456"
+	cfg.Account.Password = "p@ss word&1"
+	cfg.Portal.PasswordEncoding = config.PasswordEncoding{Scheme: "md5", Prefix: "{MD5}"}
+	r := &Report{cfg: cfg}
+
+	sum := md5.Sum([]byte("p@ss word&1"))
+	md5hex := hex.EncodeToString(sum[:])
+	encoded, err := portal.EncodePassword("p@ss word&1", cfg.Portal.PasswordEncoding, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	in := strings.Join([]string{
+		"raw p@ss word&1",
+		"query user_password=" + url.QueryEscape("p@ss word&1") + "&x=1",
+		"encoded " + encoded,
+		"self-service md5 " + md5hex,
+		"account 08123456",
+	}, "\n")
+	out := string(r.redact([]byte(in)))
+	for _, leak := range []string{"p@ss", url.QueryEscape("p@ss word&1"), md5hex, "08123456"} {
+		if strings.Contains(out, leak) {
+			t.Errorf("redacted text contains %q:\n%s", leak, out)
+		}
+	}
+	if !strings.Contains(out, "08****56") {
+		t.Errorf("student ID not masked:\n%s", out)
+	}
+}
+
+func TestRedactShortPassword(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.Account.Password = "a1"
+	r := &Report{cfg: cfg}
+	in := `user_password=a1&ac_id=1 {"password":"a1"} a1b2 data1`
+	out := string(r.redact([]byte(in)))
+	want := `user_password=******&ac_id=1 {"password":"******"} a1b2 data1`
+	if out != want {
+		t.Errorf("redact = %s\n    want %s", out, want)
+	}
+}
+
+func TestRedactWithoutConfig(t *testing.T) {
+	r := &Report{}
+	in := strings.Join([]string{
+		`GET /eportal/?c=Portal&a=login&user_account=08123456&user_password=hunter22&ac_id=1`,
+		`body {"username":"08123456","password":"hunter22","token": "t0k3n"}`,
+		`login with upass=s3cret, pwd: 'x9y8'`,
+		`portal.password_encoding.scheme = md5`,
+	}, "\n")
+	out := string(r.redact([]byte(in)))
+	for _, leak := range []string{"hunter22", "t0k3n", "s3cret", "x9y8"} {
+		if strings.Contains(out, leak) {
+			t.Errorf("redacted text contains %q:\n%s", leak, out)
+		}
+	}
+	for _, keep := range []string{"user_password=******&ac_id=1", `"password":"******"`, "password_encoding.scheme = md5"} {
+		if !strings.Contains(out, keep) {
+			t.Errorf("redacted text lacks %q:\n%s", keep, out)
+		}
+	}
+}
+
+func TestRedactYAML(t *testing.T) {
+	in := `account:
+  student_id: "08123456"
+  password: hunter22
+portal:
+  form:
+    user_password: hunter22
+    upass: hunter22
+  success_keywords: ['"result":1']
+  logout_keywords: [注销成功]
+  headers:
+    Cookie: sid=abc
+  password_encoding:
+    scheme: rsa
+    rsa_key_url: http://10.2.5.251/key
+    rsa_key_pattern: modulus="([0-9a-f]+)"
+self_service:
+  password: hunter22
+  plain_password: true
+notify:
+  webhooks:
+    - kind: serverchan
+      url: https://sctapi.ftqq.com/SCTKEY.send
+      token: t0k3n
+`
+	out := string(redactYAML([]byte(in)))
+	for _, leak := range []string{"hunter22", "sid=abc", "SCTKEY", "t0k3n"} {
+		if strings.Contains(out, leak) {
+			t.Errorf("redacted config contains %q:\n%s", leak, out)
+		}
+	}
+	for _, keep := range []string{"rsa_key_url: http://10.2.5.251/key", `rsa_key_pattern: modulus="([0-9a-f]+)"`,
+		"scheme: rsa", "plain_password: true", "注销成功", `'"result":1'`} {
+		if !strings.Contains(out, keep) {
+			t.Errorf("redacted config lacks %q:\n%s", keep, out)
+		}
+	}
+}
+
+func TestRecordTail(t *testing.T) {
+	var h portal.HAR
+	h.Log.Version = "1.2"
+	for i := 0; i < maxRecordEntries+20; i++ {
+		h.Log.Entries = append(h.Log.Entries, portal.HAREntry{
+			Request: portal.HARRequest{Method: "GET", URL: fmt.Sprintf("http://10.2.5.251/%d", i)},
+		})
+	}
+	data, _ := json.Marshal(h)
+	path := filepath.Join(t.TempDir(), "record.har")
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	out, err := recordTail(path, maxRecordEntries)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got portal.HAR
+	if err := json.Unmarshal(out, &got); err != nil {
+		t.Fatalf("bundle HAR does not parse: %v", err)
+	}
+	entries := got.Log.Entries
+	if len(entries) != maxRecordEntries {
+		t.Fatalf("%d entries, want %d", len(entries), maxRecordEntries)
+	}
+	if last := entries[len(entries)-1].Request.URL; last != fmt.Sprintf("http://10.2.5.251/%d", maxRecordEntries+19) {
+		t.Errorf("last entry = %s", last)
+	}
+}
+
+func TestCheckClockUsesSkewAtCapture(t *testing.T) {
+	e := &env{}
+	if c := e.checkClock(); c.Status != StatusSkip {
+		t.Errorf("without a Date header: %+v", c)
+	}
+	e.clockSkew, e.haveSkew = -3*time.Minute, true
+	if c := e.checkClock(); c.Status != StatusWarn || !strings.Contains(c.Detail, "3m0s") {
+		t.Errorf("skewed clock: %+v", c)
+	}
+	// The verdict does not drift with the time the checks take.
+	e.clockSkew = 30 * time.Second
+	if c := e.checkClock(); c.Status != StatusOK {
+		t.Errorf("small skew: %+v", c)
+	}
+}
@@ -0,0 +1,462 @@
+package doctor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"CUMT-autologin/internal/config"
+	"CUMT-autologin/internal/ifinfo"
+	"CUMT-autologin/internal/netbind"
+	"CUMT-autologin/internal/netcheck"
+	"CUMT-autologin/internal/portal"
+	"CUMT-autologin/internal/wifi"
+)
+
+// Trial login classes, see Report.Login.
+const (
+	LoginSuccess       = "success"
+	LoginAlreadyOnline = "already_online"
+	LoginRejected      = "rejected"
+	LoginUnreachable   = "unreachable"
+	LoginUnknown       = "unknown"
+	LoginSkipped       = "skipped"
+)
+
+// maxClockSkew is how far the local clock may drift from the gateway's
+// before it is reported.
+const maxClockSkew = 2 * time.Minute
+
+// env carries what the checks share.
+type env struct {
+	ctx  context.Context
+	cfg  *config.Config
+	opts Options
+
+	snap    *ifinfo.Snapshot
+	snapErr error
+	// clockSkew is how far the local clock ran ahead of the gateway's Date
+	// header when the reply arrived; haveSkew is false without one.
+	clockSkew time.Duration
+	haveSkew  bool
+}
+
+func checkConfig(path string) (*config.Config, Check) {
+	c := Check{Name: "配置文件"}
+	if _, err := os.Stat(path); err != nil {
+		c.Status, c.Detail = StatusFail, "无法读取: "+err.Error()
+		c.Hint = "确认配置文件位于程序目录，或用 -config 指定路径"
+		return nil, c
+	}
+	cfg, err := config.Load(path)
+	if err != nil {
+		c.Status, c.Detail = StatusFail, "解析失败: "+err.Error()
+		c.Hint = "检查 YAML 缩进与引号，注意不要混用 Tab"
+		return nil, c
+	}
+
+	var fails, warns []string
+	if cfg.Account.StudentID == "" {
+		fails = append(fails, "未填写 account.student_id")
+	}
+	if cfg.Account.Password == "" {
+		fails = append(fails, "未填写 account.password")
+	}
+	if cfg.Portal.LoginURL == "" {
+		fails = append(fails, "未填写 portal.login_url")
+	} else if u, err := url.Parse(cfg.Portal.LoginURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		fails = append(fails, "portal.login_url 不是有效的 http(s) 地址")
+	}
+	if d := strings.ToLower(cfg.Portal.Driver); d != "" && portal.Driver(&cfg.Portal) == portal.DriverGeneric && d != portal.DriverGeneric {
+		warns = append(warns, fmt.Sprintf("未知的 portal.driver %q，按 generic 处理", cfg.Portal.Driver))
+	}
+	if err := portal.ValidateRules(&cfg.Portal); err != nil {
+		fails = append(fails, "portal.success 或 portal.flow 中的正则表达式无效: "+err.Error())
+	}
+	if enc := cfg.Portal.PasswordEncoding; portal.IsRSAScheme(enc.Scheme) {
+		if enc.RSAKey == "" && enc.RSAKeyURL == "" {
+			fails = append(fails, "password_encoding 使用 RSA，但没有配置 rsa_key 或 rsa_key_url")
+		} else if enc.RSAKey != "" {
+			if _, err := portal.ParseRSAKey(enc.RSAKey, enc.RSAExponent); err != nil {
+				fails = append(fails, "password_encoding.rsa_key 无效: "+err.Error())
+			}
+		}
+	} else if _, err := portal.EncodePassword("", enc, nil); err != nil {
+		fails = append(fails, fmt.Sprintf("未知的 password_encoding.scheme %q", enc.Scheme))
+	}
+	if portal.NeedsVerify(&cfg.Portal) && !cfg.Portal.Success.Verify {
+		warns = append(warns, "未配置 portal.success_keywords 或 portal.success，只能以登录后能否联网判断结果")
+	}
+	for _, cidr := range cfg.Match.Subnets {
+		if _, err := netip.ParsePrefix(strings.TrimSpace(cidr)); err != nil {
+			fails = append(fails, fmt.Sprintf("match.subnets 中的 %q 不是有效的 CIDR", cidr))
+		}
+	}
+	if n := cfg.Network.Interface; n != "" {
+		if _, err := net.InterfaceByName(n); err != nil {
+			fails = append(fails, fmt.Sprintf("network.interface %q 不存在", n))
+		}
+	}
+	if ip := cfg.Network.SourceIP; ip != "" && net.ParseIP(ip) == nil {
+		fails = append(fails, fmt.Sprintf("network.source_ip %q 不是有效的 IP", ip))
+	}
+
+	switch {
+	case len(fails) > 0:
+		c.Status, c.Detail = StatusFail, join(append(fails, warns...))
+		c.Hint = "按提示修改配置文件后重启程序"
+	case len(warns) > 0:
+		c.Status, c.Detail = StatusWarn, join(warns)
+	default:
+		c.Status, c.Detail = StatusOK, fmt.Sprintf("账号 %s，驱动 %s", maskID(cfg.Account.StudentID), portal.Driver(&cfg.Portal))
+	}
+	return cfg, c
+}
+
+func (e *env) snapshot() (*ifinfo.Snapshot, error) {
+	if e.snap == nil && e.snapErr == nil {
+		e.snap, e.snapErr = ifinfo.Inspect()
+	}
+	return e.snap, e.snapErr
+}
+
+func (e *env) checkMatch() Check {
+	c := Check{Name: "网络匹配"}
+	snap, err := e.snapshot()
+	if !ifinfo.Enabled(e.cfg.Match) {
+		c.Status, c.Detail = StatusSkip, "未配置 match 规则"
+		if err == nil && snap.Interface != "" {
+			c.Detail += fmt.Sprintf("（当前网卡 %s，网关 %s）", snap.Interface, snap.Gateway)
+		}
+		return c
+	}
+	if err != nil {
+		c.Status, c.Detail = StatusFail, "读取网络信息失败: "+err.Error()
+		return c
+	}
+	if ok, why := ifinfo.Match(e.cfg.Match, snap); !ok {
+		c.Status, c.Detail = StatusFail, why
+		c.Hint = "不在校园网时这是正常的；在校园网内请对照 cumtctl status 的输出修改 match 规则"
+		return c
+	}
+	c.Status, c.Detail = StatusOK, fmt.Sprintf("网卡 %s，网关 %s", snap.Interface, snap.Gateway)
+	return c
+}
+
+func (e *env) checkWiFi() Check {
+	c := Check{Name: "Wi-Fi"}
+	want := e.cfg.WifiSSID
+	ssid, err := wifi.CurrentSSID(want)
+	switch {
+	case err != nil:
+		c.Status, c.Detail = StatusWarn, "读取 Wi-Fi 状态失败: "+err.Error()
+		if want == "" {
+			c.Status = StatusSkip
+		}
+	case want == "":
+		c.Status, c.Detail = StatusSkip, "未配置 wifi_ssid"
+		if ssid != "" {
+			c.Detail += "（当前 " + ssid + "）"
+		}
+	case ssid == want:
+		c.Status, c.Detail = StatusOK, "已连接 "+ssid
+	case ssid == "":
+		c.Status, c.Detail = StatusWarn, "未连接 Wi-Fi，配置要求 "+want
+		c.Hint = "使用有线网络时请清空 wifi_ssid，改用 match 规则"
+	default:
+		c.Status, c.Detail = StatusFail, fmt.Sprintf("当前连接 %s，配置要求 %s", ssid, want)
+		c.Hint = "连接到校园网 Wi-Fi，或修改 wifi_ssid"
+	}
+	return c
+}
+
+func (e *env) checkAddress() Check {
+	c := Check{Name: "IP 地址"}
+	snap, err := e.snapshot()
+	if err != nil {
+		c.Status, c.Detail = StatusFail, "读取网络信息失败: "+err.Error()
+		return c
+	}
+	if snap.Interface == "" {
+		c.Status, c.Detail = StatusFail, "没有默认路由"
+		c.Hint = "检查网线或 Wi-Fi 是否已连接"
+		return c
+	}
+	var v4 []netip.Prefix
+	for _, p := range snap.Addrs {
+		if p.Addr().Is4() {
+			v4 = append(v4, p)
+		}
+	}
+	if len(v4) == 0 {
+		c.Status, c.Detail = StatusFail, snap.Interface+" 没有 IPv4 地址"
+		c.Hint = "DHCP 未分配地址，尝试重新连接网络"
+		return c
+	}
+	addr := v4[0].Addr()
+	if addr.IsLinkLocalUnicast() {
+		c.Status, c.Detail = StatusFail, fmt.Sprintf("%s 只有自动配置地址 %s，DHCP 未分配地址", snap.Interface, addr)
+		c.Hint = "重新连接网络；仍然如此时可能是网口或 AP 故障"
+		return c
+	}
+	c.Detail = fmt.Sprintf("%s %s", snap.Interface, v4[0])
+	if len(e.cfg.Match.Subnets) == 0 {
+		c.Status = StatusOK
+		return c
+	}
+	for _, cidr := range e.cfg.Match.Subnets {
+		if pfx, err := netip.ParsePrefix(strings.TrimSpace(cidr)); err == nil && pfx.Contains(addr) {
+			c.Status = StatusOK
+			return c
+		}
+	}
+	c.Status = StatusFail
+	c.Detail += " 不在 match.subnets 中"
+	c.Hint = "不在校园网，或校园网地址段有变化"
+	return c
+}
+
+func (e *env) binding() netbind.Binding {
+	return netbind.FromConfig(e.cfg.Network)
+}
+
+func (e *env) checkDNS() Check {
+	c := Check{Name: "DNS 解析"}
+	resolver := e.binding().Resolver()
+	if resolver == nil {
+		resolver = net.DefaultResolver
+	}
+	lookup := func(host string) error {
+		ctx, cancel := context.WithTimeout(e.ctx, e.opts.timeout())
+		defer cancel()
+		_, err := resolver.LookupHost(ctx, host)
+		return err
+	}
+
+	var fails, oks []string
+	if u, err := url.Parse(e.cfg.Portal.LoginURL); err == nil && u.Hostname() != "" && net.ParseIP(u.Hostname()) == nil {
+		if err := lookup(u.Hostname()); err != nil {
+			fails = append(fails, fmt.Sprintf("网关域名 %s 解析失败: %v", u.Hostname(), err))
+		} else {
+			oks = append(oks, u.Hostname())
+		}
+	}
+	if err := lookup("www.msftconnecttest.com"); err != nil {
+		c.Status = StatusWarn
+		c.Detail = join(append(fails, "外网域名解析失败: "+err.Error()))
+		c.Hint = "检查 DNS 设置，或是否开启了会接管 DNS 的代理 / VPN"
+	} else {
+		oks = append(oks, "www.msftconnecttest.com")
+	}
+	if len(fails) > 0 {
+		c.Status, c.Detail = StatusFail, join(fails)
+		c.Hint = "网关地址无法解析时可以改用 IP 填写 portal.login_url"
+		return c
+	}
+	if c.Status == "" {
+		c.Status, c.Detail = StatusOK, strings.Join(oks, "、")+" 解析正常"
+	}
+	return c
+}
+
+// httpClient returns a client that leaves like the gateway requests do.
+func (e *env) httpClient() *http.Client {
+	proxy := http.ProxyFromEnvironment
+	if !e.cfg.Network.UseSystemProxy {
+		proxy = nil
+	}
+	return &http.Client{
+		Timeout: e.opts.timeout(),
+		Transport: &http.Transport{
+			Proxy:       proxy,
+			DialContext: e.binding().DialContext,
+		},
+		// The gateway root usually redirects; its own reply is what counts.
+		CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+	}
+}
+
+func (e *env) checkGateway() Check {
+	c := Check{Name: "网关连通"}
+	u, err := url.Parse(e.cfg.Portal.LoginURL)
+	if err != nil || u.Host == "" {
+		c.Status, c.Detail = StatusSkip, "portal.login_url 无效"
+		return c
+	}
+	target := u.Scheme + "://" + u.Host + "/"
+	req, err := http.NewRequestWithContext(e.ctx, http.MethodGet, target, nil)
+	if err != nil {
+		c.Status, c.Detail = StatusFail, err.Error()
+		return c
+	}
+	start := time.Now()
+	resp, err := e.httpClient().Do(req)
+	if err != nil {
+		c.Status, c.Detail = StatusFail, fmt.Sprintf("无法访问 %s: %v", target, err)
+		c.Hint = "确认已连接校园网；设置了 network.interface / source_ip 时确认其正确"
+		return c
+	}
+	resp.Body.Close()
+	if d, err := http.ParseTime(resp.Header.Get("Date")); err == nil {
+		// The gateway stamped the reply about half a round trip ago.
+		e.clockSkew = time.Now().Sub(d.Add(time.Since(start) / 2))
+		e.haveSkew = true
+	}
+	c.Detail = fmt.Sprintf("%s 返回 HTTP %d，耗时 %s", target, resp.StatusCode, time.Since(start).Round(time.Millisecond))
+	c.Status = StatusOK
+	if resp.StatusCode >= 500 {
+		c.Status = StatusWarn
+		c.Hint = "网关服务异常，稍后再试"
+	}
+	return c
+}
+
+func (e *env) checkProbe() Check {
+	c := Check{Name: "联网探测"}
+	r := netcheck.Check(e.binding())
+	family := fmt.Sprintf("IPv4 %s", passText(r.IPv4))
+	if r.HasIPv6 {
+		family += fmt.Sprintf("，IPv6 %s", passText(r.IPv6))
+	}
+	if r.Online(e.cfg.Portal.DualStack) {
+		c.Status, c.Detail = StatusOK, "已联网（"+family+"）"
+		return c
+	}
+	c.Status, c.Detail = StatusWarn, "未联网（"+family+"）"
+	c.Hint = "尚未登录时这是正常的，参见试登录结果"
+	return c
+}
+
+func passText(ok bool) string {
+	if ok {
+		return "通过"
+	}
+	return "未通过"
+}
+
+// checkLogin submits the login form once, like the login loop does, and
+// classifies the reply without acting on it.
+func (e *env) checkLogin() (Check, string) {
+	c := Check{Name: "试登录"}
+	pCfg := e.cfg.Portal
+	pCfg.Form = make(map[string]string, len(e.cfg.Portal.Form))
+	for k, v := range e.cfg.Portal.Form {
+		pCfg.Form[k] = v
+	}
+	if e.cfg.Account.StudentID != "" {
+		pCfg.Form["user_account"] = e.cfg.LoginAccount()
+	}
+
+	opts := portal.NewOptions(e.cfg)
+	opts.Timeout = e.opts.timeout()
+	reply, err := portal.Submit(e.ctx, &pCfg, opts)
+	if err != nil {
+		// A GET login URL carries the password; report the cause only.
+		var uerr *url.Error
+		if errors.As(err, &uerr) {
+			err = uerr.Err
+		}
+		c.Status, c.Detail = StatusFail, "请求失败: "+err.Error()
+		c.Hint = "网关不可达，先解决上面的网络问题"
+		if errors.Is(err, portal.ErrEmptyURL) {
+			c.Hint = "填写 portal.login_url"
+		}
+		return c, LoginUnreachable
+	}
+	body := reply.Body
+	if alreadyOnline(body) {
+		c.Status, c.Detail = StatusOK, "网关表示本机已在线"
+		return c, LoginAlreadyOnline
+	}
+	rerr := portal.CheckReply(reply, &pCfg)
+	if rerr == nil {
+		c.Status, c.Detail = StatusOK, "登录成功"
+		if err := portal.VerifyLogin(e.ctx, &pCfg, opts); err != nil {
+			c.Status, c.Detail = StatusWarn, "网关已接受登录，但之后仍无法联网"
+			c.Hint = "检查 portal.success 规则是否过宽，或网关是否需要额外步骤"
+		}
+		return c, LoginSuccess
+	}
+	reason := portal.FailureReason(body)
+	if reason == "" {
+		c.Status, c.Detail = StatusFail, "无法识别登录结果: "+rerr.Error()
+		c.Hint = "对照支持包中的 last_login_response.html 调整 success_keywords / portal.success，或设置 network.record 录制请求"
+		return c, LoginUnknown
+	}
+	c.Status, c.Detail = StatusFail, "网关拒绝: "+reason
+	c.Hint = loginHint(reason)
+	return c, LoginRejected
+}
+
+// alreadyOnline recognises the "already logged in" replies, which do not
+// contain the success keywords on most gateways.
+func alreadyOnline(body string) bool {
+	return strings.Contains(body, `"ret_code":2`) || strings.Contains(body, `"ret_code":"2"`) ||
+		strings.Contains(body, "ip_already_online_error")
+}
+
+func loginHint(reason string) string {
+	switch {
+	case reason == portal.ReasonDeviceLimit || strings.Contains(reason, "其他设备"):
+		return "下线其他设备（cumtctl sessions / kick），或开启 portal.self_service.auto_kick"
+	case strings.Contains(reason, "密码"):
+		return "检查 account.password；在自助服务系统改过密码后需要同步修改"
+	case strings.Contains(reason, "账号不存在"), strings.Contains(reason, "运营商"):
+		return "检查 account.student_id 与 account.carrier（运营商后缀）"
+	case strings.Contains(reason, "欠费"), strings.Contains(reason, "状态异常"):
+		return "账号可能欠费或停机，请到自助服务系统确认"
+	case strings.Contains(reason, "IP"):
+		return "重新连接网络以获取地址"
+	}
+	return "参考网关返回的原因；无法判断时请附上支持包反馈"
+}
+
+func (e *env) checkProxy() Check {
+	c := Check{Name: "系统代理"}
+	var found []string
+	for _, k := range []string{"HTTP_PROXY", "HTTPS_PROXY", "ALL_PROXY", "http_proxy", "https_proxy", "all_proxy"} {
+		if v := os.Getenv(k); v != "" {
+			found = append(found, k+"="+v)
+		}
+	}
+	if p := systemProxy(); p != "" {
+		found = append(found, p)
+	}
+	switch {
+	case len(found) == 0:
+		c.Status, c.Detail = StatusOK, "未设置代理"
+	case e.cfg.Network.UseSystemProxy:
+		c.Status, c.Detail = StatusWarn, join(found)+"，网关请求会经过代理"
+		c.Hint = "代理通常无法访问校园网网关，建议关闭 network.use_system_proxy"
+	default:
+		c.Status, c.Detail = StatusOK, join(found)+"，网关请求直连"
+		c.Hint = "浏览器仍会走代理，登录页打不开时先关闭代理"
+	}
+	return c
+}
+
+func (e *env) checkClock() Check {
+	c := Check{Name: "系统时间"}
+	if !e.haveSkew {
+		c.Status, c.Detail = StatusSkip, "网关未返回时间"
+		return c
+	}
+	skew := e.clockSkew.Round(time.Second)
+	if skew < 0 {
+		skew = -skew
+	}
+	if skew > maxClockSkew {
+		c.Status, c.Detail = StatusWarn, fmt.Sprintf("与网关相差 %s", skew)
+		c.Hint = "校准系统时间，时间偏差会导致 HTTPS 与部分网关的令牌校验失败"
+		return c
+	}
+	c.Status, c.Detail = StatusOK, fmt.Sprintf("与网关相差 %s", skew)
+	return c
+}
@@ -0,0 +1,137 @@
+// Package doctor diagnoses why auto-login does not work and packs what a
+// maintainer needs into a support bundle.
+package doctor
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"CUMT-autologin/internal/config"
+)
+
+// Status is the outcome of one check.
+type Status string
+
+const (
+	StatusOK   Status = "ok"
+	StatusWarn Status = "warn"
+	StatusFail Status = "fail"
+	StatusSkip Status = "skip"
+)
+
+// Check is one line of the report.
+type Check struct {
+	Name   string `json:"name"`
+	Status Status `json:"status"`
+	Detail string `json:"detail"`
+	// Hint suggests a fix when the check did not pass.
+	Hint string `json:"hint,omitempty"`
+}
+
+// Report is the result of Run.
+type Report struct {
+	Time       time.Time `json:"time"`
+	ConfigPath string    `json:"config_path"`
+	Checks     []Check   `json:"checks"`
+	// Login classifies the trial login: success, already_online, rejected,
+	// unreachable, unknown or skipped.
+	Login string `json:"login"`
+
+	cfg *config.Config
+}
+
+// Options controls Run.
+type Options struct {
+	ConfigPath string
+	// SkipLogin leaves out the trial login, which really submits the form.
+	SkipLogin bool
+	// Timeout bounds each network check; defaults to 5s.
+	Timeout time.Duration
+}
+
+func (o Options) timeout() time.Duration {
+	if o.Timeout > 0 {
+		return o.Timeout
+	}
+	return 5 * time.Second
+}
+
+// Run performs every check in turn. Checks that depend on a readable
+// config are skipped when it cannot be loaded.
+func Run(ctx context.Context, opts Options) *Report {
+	if opts.ConfigPath == "" {
+		opts.ConfigPath = config.DefaultConfigPath
+	}
+	r := &Report{Time: time.Now(), ConfigPath: opts.ConfigPath, Login: LoginSkipped}
+	cfg, c := checkConfig(opts.ConfigPath)
+	r.add(c)
+	if cfg == nil {
+		return r
+	}
+	r.cfg = cfg
+
+	e := &env{ctx: ctx, cfg: cfg, opts: opts}
+	r.add(e.checkMatch())
+	r.add(e.checkWiFi())
+	r.add(e.checkAddress())
+	r.add(e.checkDNS())
+	r.add(e.checkGateway())
+	r.add(e.checkProbe())
+	if opts.SkipLogin {
+		r.add(Check{Name: "试登录", Status: StatusSkip, Detail: "已跳过"})
+	} else {
+		c, class := e.checkLogin()
+		r.add(c)
+		r.Login = class
+	}
+	r.add(e.checkProxy())
+	r.add(e.checkClock())
+	return r
+}
+
+func (r *Report) add(c Check) {
+	r.Checks = append(r.Checks, c)
+}
+
+// Failed reports whether any check failed.
+func (r *Report) Failed() bool {
+	for _, c := range r.Checks {
+		if c.Status == StatusFail {
+			return true
+		}
+	}
+	return false
+}
+
+var statusText = map[Status]string{
+	StatusOK:   "[ 正常 ]",
+	StatusWarn: "[ 注意 ]",
+	StatusFail: "[ 失败 ]",
+	StatusSkip: "[ 跳过 ]",
+}
+
+// WriteText writes the human readable report.
+func (r *Report) WriteText(w io.Writer) {
+	fmt.Fprintf(w, "诊断时间: %s\n", r.Time.Format("2006-01-02 15:04:05"))
+	fmt.Fprintf(w, "配置文件: %s\n\n", r.ConfigPath)
+	for _, c := range r.Checks {
+		fmt.Fprintf(w, "%s %s: %s\n", statusText[c.Status], c.Name, c.Detail)
+		if c.Hint != "" && (c.Status == StatusWarn || c.Status == StatusFail) {
+			fmt.Fprintf(w, "         建议: %s\n", c.Hint)
+		}
+	}
+	fmt.Fprintln(w)
+	if r.Failed() {
+		fmt.Fprintln(w, "存在失败项，请按建议处理；仍无法解决时请附上支持包 (cumtctl doctor -bundle) 反馈。")
+	} else {
+		fmt.Fprintln(w, "未发现问题。")
+	}
+}
+
+// join lists problems in one detail line.
+func join(items []string) string {
+	return strings.Join(items, "；")
+}
@@ -0,0 +1,9 @@
+//go:build !windows
+
+package doctor
+
+// systemProxy only reports the Windows proxy settings; elsewhere the
+// environment variables are all there is.
+func systemProxy() string {
+	return ""
+}
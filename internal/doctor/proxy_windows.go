@@ -0,0 +1,26 @@
+//go:build windows
+
+package doctor
+
+import (
+	"golang.org/x/sys/windows/registry"
+)
+
+// systemProxy describes the WinINet proxy set in Internet Options, which
+// browsers use but the gateway requests ignore unless use_system_proxy is set.
+func systemProxy() string {
+	k, err := registry.OpenKey(registry.CURRENT_USER,
+		`Software\Microsoft\Windows\CurrentVersion\Internet Settings`, registry.QUERY_VALUE)
+	if err != nil {
+		return ""
+	}
+	defer k.Close()
+	if pac, _, err := k.GetStringValue("AutoConfigURL"); err == nil && pac != "" {
+		return "PAC " + pac
+	}
+	if on, _, err := k.GetIntegerValue("ProxyEnable"); err != nil || on == 0 {
+		return ""
+	}
+	server, _, _ := k.GetStringValue("ProxyServer")
+	return "Internet 选项代理 " + server
+}
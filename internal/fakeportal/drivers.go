@@ -0,0 +1,149 @@
+package fakeportal
+
+import (
+	"encoding/base64"
+	"net/http"
+	"strings"
+)
+
+// drcomMessages are the ePortal replies for each failure. The "userid"
+// family comes base64 encoded, the RADIUS ones in clear text.
+var drcomMessages = map[string]string{
+	FailPassword:    base64.StdEncoding.EncodeToString([]byte("userid error2")),
+	FailNoAccount:   base64.StdEncoding.EncodeToString([]byte("userid error1")),
+	FailArrears:     "Rad:Status_Err",
+	FailDeviceLimit: "Rad:Limit Users Err",
+}
+
+// srunMessages are the srun_portal error_msg values for each failure.
+var srunMessages = map[string]string{
+	FailPassword:    "E2553: Password is error.",
+	FailNoAccount:   "E2531: User not found.",
+	FailArrears:     "E2616: Arrearage users.",
+	FailDeviceLimit: "E2620: You are already online.",
+}
+
+func callback(r *http.Request, def string) string {
+	if cb := r.URL.Query().Get("callback"); cb != "" {
+		return cb
+	}
+	return def
+}
+
+func (s *Server) drcomLogin(w http.ResponseWriter, r *http.Request, ip, forced string) {
+	q := r.URL.Query()
+	// Some ePortal versions prefix the account with ",0,".
+	account := strings.TrimPrefix(q.Get("user_account"), ",0,")
+	cb := callback(r, "dr1003")
+	if s.online(ip) && forced == "" {
+		writeJSONP(w, cb, map[string]any{"result": 0, "msg": "", "ret_code": 2})
+		return
+	}
+	if fail := s.login(ip, account, q.Get("user_password"), forced); fail != "" {
+		writeJSONP(w, cb, map[string]any{"result": 0, "msg": drcomMessages[fail], "ret_code": 1})
+		return
+	}
+	s.setMAC(ip, q.Get("wlan_user_mac"))
+	writeJSONP(w, cb, map[string]any{"result": 1, "msg": "Portal协议认证成功！"})
+}
+
+func (s *Server) drcomLogout(w http.ResponseWriter, r *http.Request, ip string) {
+	cb := callback(r, "dr1004")
+	if !s.logout(ip) {
+		writeJSONP(w, cb, map[string]any{"result": 0, "msg": "用户不在线"})
+		return
+	}
+	writeJSONP(w, cb, map[string]any{"result": 1, "msg": "注销成功"})
+}
+
+// drcomUnbind ends the sessions of the account bound to the MAC; the
+// all-zero MAC means every device of the account.
+func (s *Server) drcomUnbind(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	account, mac := q.Get("user_account"), strings.ToLower(q.Get("wlan_user_mac"))
+	s.mu.Lock()
+	for ip, sess := range s.sessions {
+		if sess.Account == account && (mac == "" || mac == "000000000000" || sess.MAC == mac) {
+			delete(s.sessions, ip)
+		}
+	}
+	s.mu.Unlock()
+	writeJSONP(w, callback(r, "dr1002"), map[string]any{"result": 1, "msg": "解绑成功"})
+}
+
+// drcomStatus answers chkstatus: time in minutes, flow in KB and fee in
+// 1/10000 yuan.
+func (s *Server) drcomStatus(w http.ResponseWriter, r *http.Request, ip string) {
+	cb := callback(r, "dr1002")
+	sess, ok := s.session(ip)
+	if !ok {
+		writeJSONP(w, cb, map[string]any{"result": 0, "msg": "", "v46ip": ip})
+		return
+	}
+	writeJSONP(w, cb, map[string]any{
+		"result": 1,
+		"uid":    sess.Account,
+		"v46ip":  ip,
+		"olmac":  sess.MAC,
+		"time":   int(s.opts.Now().Sub(sess.LoginAt).Minutes()),
+		"flow":   s.opts.UsedBytes / 1024,
+		"fee":    int64(s.opts.Balance * 10000),
+	})
+}
+
+func (s *Server) srunLogin(w http.ResponseWriter, r *http.Request, ip, forced string) {
+	q := r.URL.Query()
+	cb := callback(r, "jsonp")
+	if fail := s.login(ip, q.Get("username"), q.Get("password"), forced); fail != "" {
+		msg := srunMessages[fail]
+		writeJSONP(w, cb, map[string]any{
+			"error":     "login_error",
+			"res":       "login_error",
+			"error_msg": msg,
+			"ecode":     strings.SplitN(msg, ":", 2)[0],
+			"client_ip": ip,
+		})
+		return
+	}
+	writeJSONP(w, cb, map[string]any{"error": "ok", "res": "ok", "suc_msg": "login_ok", "client_ip": ip, "online_ip": ip})
+}
+
+func (s *Server) srunLogout(w http.ResponseWriter, r *http.Request, ip string) {
+	cb := callback(r, "jsonp")
+	if !s.logout(ip) {
+		writeJSONP(w, cb, map[string]any{"error": "not_online_error", "res": "not_online_error", "client_ip": ip})
+		return
+	}
+	writeJSONP(w, cb, map[string]any{"error": "ok", "res": "ok", "client_ip": ip})
+}
+
+func (s *Server) srunStatus(w http.ResponseWriter, r *http.Request, ip string) {
+	cb := callback(r, "jsonp")
+	sess, ok := s.session(ip)
+	if !ok {
+		writeJSONP(w, cb, map[string]any{"error": "not_online_error", "client_ip": ip, "online_ip": ip})
+		return
+	}
+	writeJSONP(w, cb, map[string]any{
+		"error":        "ok",
+		"user_name":    sess.Account,
+		"online_ip":    ip,
+		"user_mac":     sess.MAC,
+		"sum_bytes":    s.opts.UsedBytes,
+		"sum_seconds":  int64(s.opts.Now().Sub(sess.LoginAt).Seconds()),
+		"user_balance": s.opts.Balance,
+	})
+}
+
+// setMAC records the MAC a client reported at login, ignoring placeholders.
+func (s *Server) setMAC(ip, mac string) {
+	mac = strings.ToLower(strings.NewReplacer(":", "", "-", "").Replace(mac))
+	if mac == "" || mac == "000000000000" {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if sess, ok := s.sessions[ip]; ok {
+		sess.MAC = mac
+	}
+}
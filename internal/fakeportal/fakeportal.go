@@ -0,0 +1,381 @@
+package fakeportal
+
+import (
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"CUMT-autologin/internal/config"
+)
+
+// Drivers the fake portal emulates. All endpoints are always served; the
+// driver only decides where captive redirects point.
+const (
+	DriverDrcom = "drcom"
+	DriverSrun  = "srun"
+)
+
+// Login failures a Fault can force.
+const (
+	FailPassword    = "password"
+	FailArrears     = "arrears"
+	FailDeviceLimit = "device_limit"
+	FailNoAccount   = "no_account"
+)
+
+// Options configures a fake portal.
+type Options struct {
+	Driver string
+	// Account and Password are the accepted credentials; empty accepts any.
+	// A carrier suffix such as "@telecom" is part of the account.
+	Account  string
+	Password string
+	// MaxDevices limits the sessions of one account; 0 is unlimited.
+	MaxDevices int
+	// IdleTimeout ends a session that sends no request for that long; 0 never.
+	IdleTimeout time.Duration
+	// UsedBytes and Balance are reported by the status endpoints.
+	UsedBytes int64
+	Balance   float64
+	// Now replaces time.Now, e.g. to expire sessions without waiting.
+	Now func() time.Time
+}
+
+// Fault changes how the next requests are answered.
+type Fault struct {
+	// Login makes logins fail with one of the Fail* reasons.
+	Login string `json:"login,omitempty"`
+	// HTTPStatus answers every portal request with this status, e.g. 502.
+	HTTPStatus int `json:"http_status,omitempty"`
+	// Delay is added before every portal response.
+	Delay time.Duration `json:"delay,omitempty"`
+	// Times is how many requests the fault applies to; 0 until cleared.
+	Times int `json:"times,omitempty"`
+}
+
+// Session is one logged-in client, keyed by its IP like a real gateway.
+type Session struct {
+	IP       string    `json:"ip"`
+	Account  string    `json:"account"`
+	MAC      string    `json:"mac,omitempty"`
+	LoginAt  time.Time `json:"login_at"`
+	LastSeen time.Time `json:"last_seen"`
+}
+
+// Server is a fake campus portal. It is an http.Handler.
+type Server struct {
+	opts Options
+
+	mu       sync.Mutex
+	sessions map[string]*Session
+	fault    Fault
+	logins   int
+}
+
+// New returns a fake portal; serve it with http.ListenAndServe.
+func New(opts Options) *Server {
+	if opts.Driver == "" {
+		opts.Driver = DriverDrcom
+	}
+	if opts.Now == nil {
+		opts.Now = time.Now
+	}
+	return &Server{opts: opts, sessions: make(map[string]*Session)}
+}
+
+// NewTestServer starts a fake portal on a loopback port. Close the returned
+// server when done.
+func NewTestServer(opts Options) (*Server, *httptest.Server) {
+	s := New(opts)
+	return s, httptest.NewServer(s)
+}
+
+// PortalConfig returns a portal section that points every endpoint of the
+// driver at base, the URL the fake portal is served on.
+func (s *Server) PortalConfig(base string) config.PortalConfig {
+	base = strings.TrimSuffix(base, "/")
+	cfg := config.PortalConfig{
+		Driver:     s.opts.Driver,
+		Method:     http.MethodGet,
+		Form:       map[string]string{},
+		LogoutForm: map[string]string{},
+	}
+	switch s.opts.Driver {
+	case DriverSrun:
+		cfg.LoginURL = base + "/cgi-bin/srun_portal"
+		cfg.StatusURL = base + "/cgi-bin/rad_user_info?callback=jsonp"
+		cfg.Form["action"] = "login"
+		cfg.Form["username"] = s.opts.Account
+		cfg.Form["password"] = s.opts.Password
+		cfg.SuccessKeywords = []string{`"error":"ok"`}
+	default:
+		cfg.LoginURL = base + "/eportal/portal/login"
+		cfg.StatusURL = base + "/drcom/chkstatus?callback=dr1002"
+		cfg.Form["callback"] = "dr1003"
+		cfg.Form["user_account"] = s.opts.Account
+		cfg.Form["user_password"] = s.opts.Password
+		cfg.SuccessKeywords = []string{`"result":1`}
+	}
+	return cfg
+}
+
+// SetFault replaces the active fault; the zero Fault clears it.
+func (s *Server) SetFault(f Fault) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.fault = f
+}
+
+// Expire ends the session of ip, or every session when ip is empty.
+func (s *Server) Expire(ip string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if ip == "" {
+		s.sessions = make(map[string]*Session)
+		return
+	}
+	delete(s.sessions, ip)
+}
+
+// Sessions returns a copy of the live sessions.
+func (s *Server) Sessions() []Session {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.expireIdleLocked()
+	out := make([]Session, 0, len(s.sessions))
+	for _, sess := range s.sessions {
+		out = append(out, *sess)
+	}
+	return out
+}
+
+// Logins returns how many login requests were received, failed or not.
+func (s *Server) Logins() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.logins
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if strings.HasPrefix(r.URL.Path, "/_fake/") {
+		s.serveAdmin(w, r)
+		return
+	}
+
+	fault := s.takeFault()
+	if fault.Delay > 0 {
+		select {
+		case <-time.After(fault.Delay):
+		case <-r.Context().Done():
+			return
+		}
+	}
+	if fault.HTTPStatus != 0 {
+		http.Error(w, http.StatusText(fault.HTTPStatus), fault.HTTPStatus)
+		return
+	}
+
+	ip := clientIP(r)
+	s.touch(ip)
+	q := r.URL.Query()
+	switch {
+	case r.URL.Path == "/eportal/portal/login" || (r.URL.Path == "/eportal/" && q.Get("a") == "login"):
+		s.drcomLogin(w, r, ip, fault.Login)
+	case r.URL.Path == "/eportal/portal/logout" || (r.URL.Path == "/eportal/" && q.Get("a") == "logout"):
+		s.drcomLogout(w, r, ip)
+	case r.URL.Path == "/eportal/portal/mac/unbind" || (r.URL.Path == "/eportal/" && q.Get("a") == "unbind_mac"):
+		s.drcomUnbind(w, r)
+	case r.URL.Path == "/drcom/chkstatus":
+		s.drcomStatus(w, r, ip)
+	case r.URL.Path == "/cgi-bin/srun_portal":
+		if q.Get("action") == "logout" {
+			s.srunLogout(w, r, ip)
+		} else {
+			s.srunLogin(w, r, ip, fault.Login)
+		}
+	case r.URL.Path == "/cgi-bin/rad_user_info":
+		s.srunStatus(w, r, ip)
+	case r.URL.Path == "/eportal/index.jsp" || r.URL.Path == "/srun_portal_pc":
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		_, _ = w.Write([]byte(loginPage))
+	default:
+		s.serveProbe(w, r, ip)
+	}
+}
+
+// serveProbe answers connectivity probes: the expected reply when ip is
+// logged in, a captive redirect to the login page otherwise.
+func (s *Server) serveProbe(w http.ResponseWriter, r *http.Request, ip string) {
+	if s.online(ip) {
+		switch r.URL.Path {
+		case "/generate_204", "/gen_204":
+			w.WriteHeader(http.StatusNoContent)
+		case "/ncsi.txt":
+			_, _ = w.Write([]byte("Microsoft NCSI"))
+		default:
+			_, _ = w.Write([]byte("Microsoft Connect Test"))
+		}
+		return
+	}
+	target := "/eportal/index.jsp?wlanuserip=" + ip + "&wlanacname=fake-ac"
+	if s.opts.Driver == DriverSrun {
+		target = "/srun_portal_pc?ac_id=1&theme=pro"
+	}
+	http.Redirect(w, r, target, http.StatusFound)
+}
+
+// serveAdmin exposes the controls over HTTP so that scripts can drive the
+// standalone server:
+//
+//	/_fake/fault?login=arrears&status=502&delay=2s&times=1
+//	/_fake/expire[?ip=...]
+//	/_fake/state
+func (s *Server) serveAdmin(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	switch strings.TrimPrefix(r.URL.Path, "/_fake/") {
+	case "fault":
+		var f Fault
+		f.Login = q.Get("login")
+		f.HTTPStatus, _ = strconv.Atoi(q.Get("status"))
+		f.Delay, _ = time.ParseDuration(q.Get("delay"))
+		f.Times, _ = strconv.Atoi(q.Get("times"))
+		s.SetFault(f)
+	case "expire":
+		s.Expire(q.Get("ip"))
+	case "state":
+	default:
+		http.NotFound(w, r)
+		return
+	}
+	s.mu.Lock()
+	fault := s.fault
+	logins := s.logins
+	s.mu.Unlock()
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]any{
+		"fault":    fault,
+		"sessions": s.Sessions(),
+		"logins":   logins,
+	})
+}
+
+// takeFault returns the active fault and uses up one of its Times.
+func (s *Server) takeFault() Fault {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	f := s.fault
+	if f.Times > 0 {
+		s.fault.Times--
+		if s.fault.Times == 0 {
+			s.fault = Fault{}
+		}
+	}
+	return f
+}
+
+// login checks the credentials and opens a session; it returns one of the
+// Fail* reasons, or "" on success.
+func (s *Server) login(ip, account, password, forced string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.logins++
+	if forced != "" {
+		return forced
+	}
+	if s.opts.Account != "" && account != s.opts.Account {
+		return FailNoAccount
+	}
+	if s.opts.Password != "" && password != s.opts.Password {
+		return FailPassword
+	}
+	s.expireIdleLocked()
+	if s.opts.MaxDevices > 0 {
+		n := 0
+		for other, sess := range s.sessions {
+			if other != ip && sess.Account == account {
+				n++
+			}
+		}
+		if n >= s.opts.MaxDevices {
+			return FailDeviceLimit
+		}
+	}
+	now := s.opts.Now()
+	s.sessions[ip] = &Session{IP: ip, Account: account, LoginAt: now, LastSeen: now}
+	return ""
+}
+
+func (s *Server) logout(ip string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.expireIdleLocked()
+	_, ok := s.sessions[ip]
+	delete(s.sessions, ip)
+	return ok
+}
+
+// session returns a copy of the live session of ip.
+func (s *Server) session(ip string) (Session, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.expireIdleLocked()
+	sess, ok := s.sessions[ip]
+	if !ok {
+		return Session{}, false
+	}
+	return *sess, true
+}
+
+func (s *Server) online(ip string) bool {
+	_, ok := s.session(ip)
+	return ok
+}
+
+// touch counts a request as activity of the session of ip.
+func (s *Server) touch(ip string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.expireIdleLocked()
+	if sess, ok := s.sessions[ip]; ok {
+		sess.LastSeen = s.opts.Now()
+	}
+}
+
+func (s *Server) expireIdleLocked() {
+	if s.opts.IdleTimeout <= 0 {
+		return
+	}
+	now := s.opts.Now()
+	for ip, sess := range s.sessions {
+		if now.Sub(sess.LastSeen) > s.opts.IdleTimeout {
+			delete(s.sessions, ip)
+		}
+	}
+}
+
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// writeJSONP answers callback(payload) like the real gateways do.
+func writeJSONP(w http.ResponseWriter, callback string, payload map[string]any) {
+	data, _ := json.Marshal(payload)
+	w.Header().Set("Content-Type", "text/javascript; charset=utf-8")
+	_, _ = w.Write([]byte(callback + "(" + string(data) + ")"))
+}
+
+const loginPage = `<!DOCTYPE html>
+<html><head><meta charset="utf-8"><title>上网登录页</title></head>
+<body><form method="get" action="/eportal/portal/login">
+<input name="user_account"><input name="user_password" type="password">
+<button type="submit">登录</button></form></body></html>
+`
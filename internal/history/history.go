@@ -0,0 +1,169 @@
+package history
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"CUMT-autologin/internal/config"
+)
+
+// DefaultPath is history.jsonl alongside the default config file.
+var DefaultPath = filepath.Join(filepath.Dir(config.DefaultConfigPath), "history.jsonl")
+
+// Sample is one observation of the portal session, stored as a JSON line.
+type Sample struct {
+	Time          time.Time `json:"time"`
+	Online        bool      `json:"online"`
+	UsedBytes     int64     `json:"used_bytes"`
+	OnlineSeconds int64     `json:"online_seconds"`
+	Balance       float64   `json:"balance"`
+	HasBalance    bool      `json:"has_balance"`
+}
+
+// fileMu serialises access within the process; lockFile extends it to the
+// other processes (tray, GUI, cumtctl) sharing the same history.
+var fileMu sync.Mutex
+
+// withLock runs fn holding fileMu and an advisory lock on path+".lock".
+func withLock(path string, fn func() error) error {
+	fileMu.Lock()
+	defer fileMu.Unlock()
+	f, err := os.OpenFile(path+".lock", os.O_CREATE|os.O_RDWR, 0644)
+	if errors.Is(err, os.ErrNotExist) {
+		// No directory yet, so no history to race on.
+		return fn()
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if err := lockFile(f); err != nil {
+		return err
+	}
+	defer unlockFile(f)
+	return fn()
+}
+
+// Append adds s to the history file at path.
+func Append(path string, s Sample) error {
+	data, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+	return withLock(path, func() error {
+		f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		_, err = f.Write(append(data, '\n'))
+		return err
+	})
+}
+
+// Load returns all samples taken at or after since, oldest first.
+// A missing file yields no samples; malformed lines are skipped.
+func Load(path string, since time.Time) ([]Sample, error) {
+	var out []Sample
+	err := withLock(path, func() error {
+		var err error
+		out, err = load(path, since)
+		return err
+	})
+	return out, err
+}
+
+func load(path string, since time.Time) ([]Sample, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var out []Sample
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	for sc.Scan() {
+		var s Sample
+		if json.Unmarshal(sc.Bytes(), &s) != nil {
+			continue
+		}
+		if !s.Time.Before(since) {
+			out = append(out, s)
+		}
+	}
+	return out, sc.Err()
+}
+
+// Prune drops samples older than keep. The file is rewritten under the lock
+// and replaced by a rename, so a concurrent Append is neither lost nor torn.
+func Prune(path string, keep time.Duration) error {
+	return withLock(path, func() error {
+		samples, err := load(path, time.Now().Add(-keep))
+		if err != nil {
+			return err
+		}
+		var buf bytes.Buffer
+		for _, s := range samples {
+			data, err := json.Marshal(s)
+			if err != nil {
+				return err
+			}
+			buf.Write(data)
+			buf.WriteByte('\n')
+		}
+		tmp := path + ".tmp"
+		if err := os.WriteFile(tmp, buf.Bytes(), 0644); err != nil {
+			return err
+		}
+		if err := os.Rename(tmp, path); err != nil {
+			os.Remove(tmp)
+			return err
+		}
+		return nil
+	})
+}
+
+// Recorder appends samples to a history file at most once per MinGap.
+// The gap is checked against the file as well, so several processes sharing
+// the same history (tray and GUI) do not double the sampling rate.
+type Recorder struct {
+	Path   string
+	MinGap time.Duration
+	Keep   time.Duration
+
+	mu   sync.Mutex
+	last time.Time
+}
+
+// Record stores s unless a sample was taken less than MinGap ago.
+func (r *Recorder) Record(s Sample) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if !r.last.IsZero() && s.Time.Sub(r.last) < r.MinGap {
+		return false, nil
+	}
+	recent, err := Load(r.Path, s.Time.Add(-r.MinGap))
+	if err != nil {
+		return false, err
+	}
+	if len(recent) > 0 {
+		r.last = recent[len(recent)-1].Time
+		return false, nil
+	}
+	if err := Append(r.Path, s); err != nil {
+		return false, err
+	}
+	// Prune roughly once a day, piggybacking on the first sample after midnight.
+	if r.Keep > 0 && (r.last.IsZero() || r.last.YearDay() != s.Time.YearDay()) {
+		_ = Prune(r.Path, r.Keep)
+	}
+	r.last = s.Time
+	return true, nil
+}
@@ -0,0 +1,69 @@
+package history
+
+import (
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestPruneKeepsConcurrentAppends(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.jsonl")
+	now := time.Now()
+	for i := 0; i < 50; i++ {
+		if err := Append(path, Sample{Time: now.AddDate(0, 0, -100)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	const n = 200
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < n; i++ {
+			if err := Append(path, Sample{Time: now, UsedBytes: int64(i)}); err != nil {
+				t.Error(err)
+			}
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 50; i++ {
+			if err := Prune(path, 90*24*time.Hour); err != nil {
+				t.Error(err)
+			}
+		}
+	}()
+	wg.Wait()
+
+	samples, err := Load(path, time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(samples) != n {
+		t.Fatalf("got %d samples, want %d", len(samples), n)
+	}
+	for i, s := range samples {
+		if s.UsedBytes != int64(i) {
+			t.Fatalf("sample %d has UsedBytes %d", i, s.UsedBytes)
+		}
+	}
+}
+
+func TestRecorderMinGap(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.jsonl")
+	r := &Recorder{Path: path, MinGap: 10 * time.Minute}
+	now := time.Date(2024, 3, 1, 8, 0, 0, 0, time.Local)
+	for i, want := range []bool{true, false, true} {
+		ok, err := r.Record(Sample{Time: now.Add(time.Duration(i) * 6 * time.Minute)})
+		if err != nil || ok != want {
+			t.Errorf("Record #%d = %v, %v; want %v", i, ok, err, want)
+		}
+	}
+	// A second process sharing the file respects the same gap.
+	other := &Recorder{Path: path, MinGap: 10 * time.Minute}
+	if ok, _ := other.Record(Sample{Time: now.Add(13 * time.Minute)}); ok {
+		t.Error("second recorder sampled within MinGap")
+	}
+}
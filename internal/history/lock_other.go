@@ -0,0 +1,9 @@
+//go:build !linux && !darwin && !freebsd && !netbsd && !openbsd && !windows
+
+package history
+
+import "os"
+
+// Without advisory locks only fileMu protects the history.
+func lockFile(f *os.File) error   { return nil }
+func unlockFile(f *os.File) error { return nil }
@@ -0,0 +1,183 @@
+package ifinfo
+
+import (
+	"fmt"
+	"net"
+	"net/netip"
+	"path"
+	"strings"
+
+	"CUMT-autologin/internal/config"
+)
+
+// Snapshot describes the interface that carries the default IPv4 route.
+type Snapshot struct {
+	Interface  string         `json:"interface"`
+	Addrs      []netip.Prefix `json:"addrs"`
+	Gateway    netip.Addr     `json:"gateway"`
+	GatewayMAC string         `json:"gateway_mac"` // empty when not in the neighbour cache
+	DNSSuffix  string         `json:"dns_suffix"`  // DHCP domain / connection-specific suffix
+}
+
+// Inspect returns the snapshot of the current default route interface.
+func Inspect() (*Snapshot, error) {
+	s, err := inspect()
+	if err != nil {
+		return nil, err
+	}
+	if s.Interface != "" && len(s.Addrs) == 0 {
+		s.Addrs = interfaceAddrs(s.Interface)
+	}
+	s.GatewayMAC = normalizeMAC(s.GatewayMAC)
+	s.DNSSuffix = strings.TrimSuffix(strings.ToLower(s.DNSSuffix), ".")
+	return s, nil
+}
+
+// Enabled reports whether m has any rule, i.e. whether Inspect is needed.
+func Enabled(m config.MatchConfig) bool {
+	return len(m.Gateways) > 0 || len(m.GatewayMACs) > 0 || len(m.DHCPDomains) > 0 ||
+		len(m.Interfaces) > 0 || len(m.Subnets) > 0
+}
+
+// Match reports whether s satisfies every rule of m. When it does not,
+// reason tells the first rule that failed.
+func Match(m config.MatchConfig, s *Snapshot) (ok bool, reason string) {
+	if !Enabled(m) {
+		return true, ""
+	}
+	if s == nil || s.Interface == "" {
+		return false, "没有默认路由"
+	}
+	if len(m.Interfaces) > 0 && !anyOf(m.Interfaces, func(p string) bool { return matchName(p, s.Interface) }) {
+		return false, fmt.Sprintf("网卡 %s 不在 interfaces 中", s.Interface)
+	}
+	if len(m.Gateways) > 0 && !anyOf(m.Gateways, func(g string) bool {
+		ip, err := netip.ParseAddr(strings.TrimSpace(g))
+		return err == nil && ip == s.Gateway
+	}) {
+		return false, fmt.Sprintf("网关 %s 不在 gateways 中", describe(s.Gateway))
+	}
+	if len(m.GatewayMACs) > 0 && !anyOf(m.GatewayMACs, func(mac string) bool {
+		return s.GatewayMAC != "" && normalizeMAC(mac) == s.GatewayMAC
+	}) {
+		return false, fmt.Sprintf("网关 MAC %s 不在 gateway_macs 中", orDash(s.GatewayMAC))
+	}
+	if len(m.DHCPDomains) > 0 && !anyOf(m.DHCPDomains, func(d string) bool { return matchDomain(d, s.DNSSuffix) }) {
+		return false, fmt.Sprintf("DHCP 域 %s 不在 dhcp_domains 中", orDash(s.DNSSuffix))
+	}
+	if len(m.Subnets) > 0 && !anyOf(m.Subnets, func(c string) bool { return inSubnet(c, s.Addrs) }) {
+		return false, "网卡地址不在 subnets 中"
+	}
+	return true, ""
+}
+
+// inspectCurrent is Inspect, replaced in tests.
+var inspectCurrent = Inspect
+
+// MatchCurrent inspects the current network and matches it against m. It
+// does nothing when m has no rule.
+func MatchCurrent(m config.MatchConfig) (ok bool, reason string) {
+	if !Enabled(m) {
+		return true, ""
+	}
+	s, err := inspectCurrent()
+	if err != nil {
+		return false, "读取网络信息失败: " + err.Error()
+	}
+	return Match(m, s)
+}
+
+func anyOf(list []string, match func(string) bool) bool {
+	for _, item := range list {
+		if match(item) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchName compares interface names case-insensitively; patterns use
+// path.Match syntax ("eth*", "以太网*").
+func matchName(pattern, name string) bool {
+	pattern, name = strings.ToLower(strings.TrimSpace(pattern)), strings.ToLower(name)
+	if ok, err := path.Match(pattern, name); err == nil && ok {
+		return true
+	}
+	return pattern == name
+}
+
+// matchDomain accepts the domain itself and any subdomain of it.
+func matchDomain(rule, suffix string) bool {
+	rule = strings.TrimSuffix(strings.ToLower(strings.TrimSpace(rule)), ".")
+	if rule == "" || suffix == "" {
+		return false
+	}
+	return suffix == rule || strings.HasSuffix(suffix, "."+rule)
+}
+
+func inSubnet(cidr string, addrs []netip.Prefix) bool {
+	p, err := netip.ParsePrefix(strings.TrimSpace(cidr))
+	if err != nil {
+		return false
+	}
+	for _, a := range addrs {
+		if p.Contains(a.Addr()) {
+			return true
+		}
+	}
+	return false
+}
+
+func interfaceAddrs(name string) []netip.Prefix {
+	ifc, err := net.InterfaceByName(name)
+	if err != nil {
+		return nil
+	}
+	addrs, err := ifc.Addrs()
+	if err != nil {
+		return nil
+	}
+	var out []netip.Prefix
+	for _, a := range addrs {
+		ipNet, ok := a.(*net.IPNet)
+		if !ok {
+			continue
+		}
+		ip, ok := netip.AddrFromSlice(ipNet.IP)
+		if !ok {
+			continue
+		}
+		ones, _ := ipNet.Mask.Size()
+		out = append(out, netip.PrefixFrom(ip.Unmap(), ones))
+	}
+	return out
+}
+
+// normalizeMAC renders a MAC as lower-case colon separated hex, or "" for
+// an empty or all-zero (incomplete) entry.
+func normalizeMAC(mac string) string {
+	hw, err := net.ParseMAC(strings.ReplaceAll(strings.TrimSpace(mac), "-", ":"))
+	if err != nil {
+		return strings.ToLower(strings.TrimSpace(mac))
+	}
+	for _, b := range hw {
+		if b != 0 {
+			return hw.String()
+		}
+	}
+	return ""
+}
+
+func describe(ip netip.Addr) string {
+	if !ip.IsValid() {
+		return "-"
+	}
+	return ip.String()
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
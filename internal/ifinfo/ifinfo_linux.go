@@ -0,0 +1,118 @@
+//go:build linux
+
+package ifinfo
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/hex"
+	"net/netip"
+	"os"
+	"strconv"
+	"strings"
+)
+
+const (
+	routeFile  = "/proc/net/route"
+	arpFile    = "/proc/net/arp"
+	resolvFile = "/etc/resolv.conf"
+)
+
+func inspect() (*Snapshot, error) {
+	iface, gw, err := defaultRoute()
+	if err != nil {
+		return nil, err
+	}
+	s := &Snapshot{Interface: iface, Gateway: gw}
+	if iface == "" {
+		return s, nil
+	}
+	s.GatewayMAC = neighbourMAC(gw, iface)
+	s.DNSSuffix = resolvDomain()
+	return s, nil
+}
+
+// defaultRoute reads the IPv4 default route with the lowest metric from
+// /proc/net/route. No default route is not an error.
+func defaultRoute() (iface string, gw netip.Addr, err error) {
+	f, err := os.Open(routeFile)
+	if err != nil {
+		return "", netip.Addr{}, err
+	}
+	defer f.Close()
+
+	best := -1
+	sc := bufio.NewScanner(f)
+	sc.Scan() // header
+	for sc.Scan() {
+		// Iface Destination Gateway Flags RefCnt Use Metric Mask MTU Window IRTT
+		fields := strings.Fields(sc.Text())
+		if len(fields) < 8 || fields[1] != "00000000" || fields[7] != "00000000" {
+			continue
+		}
+		flags, err := strconv.ParseUint(fields[3], 16, 32)
+		if err != nil || flags&0x3 != 0x3 { // RTF_UP | RTF_GATEWAY
+			continue
+		}
+		metric, err := strconv.Atoi(fields[6])
+		if err != nil || (best >= 0 && metric >= best) {
+			continue
+		}
+		raw, err := hex.DecodeString(fields[2])
+		if err != nil || len(raw) != 4 {
+			continue
+		}
+		// The kernel prints the address in host (little-endian) order.
+		var ip [4]byte
+		binary.BigEndian.PutUint32(ip[:], binary.LittleEndian.Uint32(raw))
+		iface, gw, best = fields[0], netip.AddrFrom4(ip), metric
+	}
+	if err := sc.Err(); err != nil {
+		return "", netip.Addr{}, err
+	}
+	return iface, gw, nil
+}
+
+// neighbourMAC looks the gateway up in the ARP cache.
+func neighbourMAC(gw netip.Addr, iface string) string {
+	f, err := os.Open(arpFile)
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+	sc := bufio.NewScanner(f)
+	sc.Scan() // header
+	for sc.Scan() {
+		// IP address, HW type, Flags, HW address, Mask, Device
+		fields := strings.Fields(sc.Text())
+		if len(fields) >= 6 && fields[0] == gw.String() && fields[5] == iface {
+			return fields[3]
+		}
+	}
+	return ""
+}
+
+// resolvDomain returns the domain (or first search domain) of resolv.conf,
+// which DHCP clients fill from the DHCP domain option.
+func resolvDomain() string {
+	data, err := os.ReadFile(resolvFile)
+	if err != nil {
+		return ""
+	}
+	var search string
+	for _, line := range strings.Split(string(data), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			continue
+		}
+		switch fields[0] {
+		case "domain":
+			return fields[1]
+		case "search":
+			if search == "" {
+				search = fields[1]
+			}
+		}
+	}
+	return search
+}
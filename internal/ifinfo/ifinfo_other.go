@@ -0,0 +1,9 @@
+//go:build !linux && !windows
+
+package ifinfo
+
+import "errors"
+
+func inspect() (*Snapshot, error) {
+	return nil, errors.New("ifinfo: not supported on this platform")
+}
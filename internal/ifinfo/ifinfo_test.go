@@ -0,0 +1,61 @@
+package ifinfo
+
+import (
+	"errors"
+	"net/netip"
+	"strings"
+	"testing"
+
+	"CUMT-autologin/internal/config"
+)
+
+func TestMatchCurrent(t *testing.T) {
+	snap := &Snapshot{
+		Interface:  "WLAN",
+		Addrs:      []netip.Prefix{netip.MustParsePrefix("10.7.12.34/16")},
+		Gateway:    netip.MustParseAddr("10.7.0.1"),
+		GatewayMAC: "00:11:22:aa:bb:cc",
+		DNSSuffix:  "wlan.cumt.edu.cn",
+	}
+	tests := []struct {
+		name   string
+		m      config.MatchConfig
+		ok     bool
+		reason string // substring of the reason when !ok
+	}{
+		{"empty", config.MatchConfig{}, true, ""},
+		{"interface", config.MatchConfig{Interfaces: []string{"wlan"}}, true, ""},
+		{"interface pattern", config.MatchConfig{Interfaces: []string{"eth*", "WL*"}}, true, ""},
+		{"interface mismatch", config.MatchConfig{Interfaces: []string{"eth0", "以太网*"}}, false, "网卡 WLAN"},
+		{"gateway", config.MatchConfig{Gateways: []string{" 10.7.0.1 "}}, true, ""},
+		{"gateway mismatch", config.MatchConfig{Gateways: []string{"192.168.1.1"}}, false, "网关 10.7.0.1"},
+		{"gateway mac", config.MatchConfig{GatewayMACs: []string{"00-11-22-AA-BB-CC"}}, true, ""},
+		{"gateway mac mismatch", config.MatchConfig{GatewayMACs: []string{"00:11:22:33:44:55"}}, false, "网关 MAC"},
+		{"dhcp subdomain", config.MatchConfig{DHCPDomains: []string{"cumt.edu.cn."}}, true, ""},
+		{"dhcp mismatch", config.MatchConfig{DHCPDomains: []string{"edu.cn.example"}}, false, "DHCP 域"},
+		{"subnet", config.MatchConfig{Subnets: []string{"10.7.0.0/16"}}, true, ""},
+		{"subnet mismatch", config.MatchConfig{Subnets: []string{"10.8.0.0/16"}}, false, "subnets"},
+		{"all rules", config.MatchConfig{Interfaces: []string{"wlan*"}, Gateways: []string{"10.7.0.1"}, Subnets: []string{"10.0.0.0/8"}}, true, ""},
+		{"one rule fails", config.MatchConfig{Interfaces: []string{"wlan*"}, Gateways: []string{"10.7.0.254"}}, false, "gateways"},
+	}
+	defer func(f func() (*Snapshot, error)) { inspectCurrent = f }(inspectCurrent)
+	inspectCurrent = func() (*Snapshot, error) { return snap, nil }
+	for _, tt := range tests {
+		ok, reason := MatchCurrent(tt.m)
+		if ok != tt.ok || (!ok && !strings.Contains(reason, tt.reason)) {
+			t.Errorf("%s: MatchCurrent = %v, %q; want %v, %q", tt.name, ok, reason, tt.ok, tt.reason)
+		}
+	}
+
+	inspectCurrent = func() (*Snapshot, error) { return &Snapshot{}, nil }
+	if ok, reason := MatchCurrent(config.MatchConfig{Interfaces: []string{"*"}}); ok || reason != "没有默认路由" {
+		t.Errorf("no default route: %v, %q", ok, reason)
+	}
+	inspectCurrent = func() (*Snapshot, error) { return nil, errors.New("boom") }
+	if ok, _ := MatchCurrent(config.MatchConfig{}); !ok {
+		t.Error("empty match inspected the network")
+	}
+	if ok, reason := MatchCurrent(config.MatchConfig{Subnets: []string{"10.0.0.0/8"}}); ok || !strings.Contains(reason, "boom") {
+		t.Errorf("inspect error: %v, %q", ok, reason)
+	}
+}
@@ -0,0 +1,84 @@
+//go:build windows
+
+package ifinfo
+
+import (
+	"encoding/binary"
+	"net"
+	"net/netip"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+var procSendARP = windows.NewLazySystemDLL("iphlpapi.dll").NewProc("SendARP")
+
+// inspect picks the up adapter with an IPv4 gateway and the lowest metric
+// from GetAdaptersAddresses.
+func inspect() (*Snapshot, error) {
+	adapters, err := adapterAddresses()
+	if err != nil {
+		return nil, err
+	}
+	s := &Snapshot{}
+	best := ^uint32(0)
+	for aa := adapters; aa != nil; aa = aa.Next {
+		if aa.OperStatus != windows.IfOperStatusUp || aa.Ipv4Metric >= best {
+			continue
+		}
+		gw, ok := ipv4Gateway(aa)
+		if !ok {
+			continue
+		}
+		best = aa.Ipv4Metric
+		s.Interface = windows.UTF16PtrToString(aa.FriendlyName)
+		s.Gateway = gw
+		s.DNSSuffix = windows.UTF16PtrToString(aa.DnsSuffix)
+	}
+	if s.Gateway.IsValid() {
+		s.GatewayMAC = arpLookup(s.Gateway)
+	}
+	return s, nil
+}
+
+func adapterAddresses() (*windows.IpAdapterAddresses, error) {
+	size := uint32(15 * 1024)
+	for {
+		buf := make([]byte, size)
+		aa := (*windows.IpAdapterAddresses)(unsafe.Pointer(&buf[0]))
+		err := windows.GetAdaptersAddresses(windows.AF_INET, windows.GAA_FLAG_INCLUDE_GATEWAYS, 0, aa, &size)
+		if err == nil {
+			return aa, nil
+		}
+		if err != windows.ERROR_BUFFER_OVERFLOW {
+			return nil, err
+		}
+	}
+}
+
+func ipv4Gateway(aa *windows.IpAdapterAddresses) (netip.Addr, bool) {
+	for g := aa.FirstGatewayAddress; g != nil; g = g.Next {
+		if ip, ok := netip.AddrFromSlice(g.Address.IP()); ok && ip.Unmap().Is4() {
+			return ip.Unmap(), true
+		}
+	}
+	return netip.Addr{}, false
+}
+
+// arpLookup resolves the MAC of ip with SendARP, which answers from the ARP
+// cache when it can.
+func arpLookup(ip netip.Addr) string {
+	a4 := ip.As4()
+	var mac [8]byte
+	size := uint32(len(mac))
+	ret, _, _ := procSendARP.Call(
+		uintptr(binary.LittleEndian.Uint32(a4[:])), // IPAddr is in network order
+		0,
+		uintptr(unsafe.Pointer(&mac[0])),
+		uintptr(unsafe.Pointer(&size)),
+	)
+	if ret != 0 || size == 0 || size > uint32(len(mac)) {
+		return ""
+	}
+	return net.HardwareAddr(mac[:size]).String()
+}
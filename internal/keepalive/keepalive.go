@@ -0,0 +1,79 @@
+package keepalive
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"CUMT-autologin/internal/config"
+	"CUMT-autologin/internal/portal"
+)
+
+// Keepalive modes, see config.KeepaliveConfig.
+const (
+	ModeKeepalive = "keepalive"
+	ModeStatus    = "status"
+	ModeRelogin   = "relogin"
+	ModeOff       = "off"
+)
+
+const defaultInterval = 2 * time.Minute
+
+// Mode returns the normalized mode of cfg; unknown values mean keepalive.
+func Mode(cfg config.KeepaliveConfig) string {
+	switch m := strings.ToLower(strings.TrimSpace(cfg.Mode)); m {
+	case ModeStatus, ModeOff:
+		return m
+	case ModeRelogin, "force_login", "login":
+		return ModeRelogin
+	case "none", "disabled":
+		return ModeOff
+	}
+	return ModeKeepalive
+}
+
+// Interval returns the time between two keepalive actions.
+func Interval(cfg config.KeepaliveConfig) time.Duration {
+	if cfg.Interval <= 0 {
+		return defaultInterval
+	}
+	return time.Duration(cfg.Interval) * time.Second
+}
+
+// Timer remembers when the session was last refreshed, by a keepalive
+// action or a login. The zero value is due immediately.
+type Timer struct {
+	last time.Time
+}
+
+// Due reports whether the keepalive action should run at now.
+func (t *Timer) Due(cfg config.KeepaliveConfig, now time.Time) bool {
+	if Mode(cfg) == ModeOff {
+		return false
+	}
+	return t.last.IsZero() || now.Sub(t.last) >= Interval(cfg)
+}
+
+// Reset records that the session was refreshed at now.
+func (t *Timer) Reset(now time.Time) {
+	t.last = now
+}
+
+// Last returns the time of the last refresh, zero if none yet.
+func (t *Timer) Last() time.Time {
+	return t.last
+}
+
+// Ping runs the lightweight keepalive action of the keepalive and status
+// modes. The status mode falls back to a heartbeat when the driver cannot
+// query the session. Relogin is left to the caller.
+func Ping(ctx context.Context, cfg *config.Config, opts *portal.Options) error {
+	if Mode(cfg.Keepalive) == ModeStatus {
+		_, err := portal.Status(ctx, &cfg.Portal, opts)
+		if !errors.Is(err, portal.ErrStatusUnsupported) {
+			return err
+		}
+	}
+	return portal.Heartbeat(ctx, &cfg.Portal, cfg.Keepalive.URL, opts)
+}
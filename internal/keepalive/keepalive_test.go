@@ -0,0 +1,83 @@
+package keepalive
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"CUMT-autologin/internal/config"
+	"CUMT-autologin/internal/fakeportal"
+	"CUMT-autologin/internal/portal"
+)
+
+func TestMode(t *testing.T) {
+	for in, want := range map[string]string{
+		"": ModeKeepalive, "keepalive": ModeKeepalive, "bogus": ModeKeepalive,
+		"Status": ModeStatus, " relogin ": ModeRelogin, "force_login": ModeRelogin,
+		"off": ModeOff, "disabled": ModeOff, "none": ModeOff,
+	} {
+		if got := Mode(config.KeepaliveConfig{Mode: in}); got != want {
+			t.Errorf("Mode(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestTimer(t *testing.T) {
+	now := time.Date(2024, 3, 1, 8, 0, 0, 0, time.Local)
+	cfg := config.KeepaliveConfig{Interval: 300}
+	var timer Timer
+
+	if !timer.Due(cfg, now) {
+		t.Fatal("zero timer not due")
+	}
+	timer.Reset(now)
+	if timer.Last() != now {
+		t.Errorf("Last = %v", timer.Last())
+	}
+	for _, step := range []struct {
+		after time.Duration
+		due   bool
+	}{
+		{0, false},
+		{4*time.Minute + 59*time.Second, false},
+		{5 * time.Minute, true},
+		{time.Hour, true},
+	} {
+		if got := timer.Due(cfg, now.Add(step.after)); got != step.due {
+			t.Errorf("Due after %v = %v, want %v", step.after, got, step.due)
+		}
+	}
+
+	// A login in between resets the interval.
+	now = now.Add(4 * time.Minute)
+	timer.Reset(now)
+	if timer.Due(cfg, now.Add(2*time.Minute)) {
+		t.Error("due 2m after a reset")
+	}
+
+	if timer.Due(config.KeepaliveConfig{Mode: ModeOff}, now.Add(24*time.Hour)) {
+		t.Error("off mode is due")
+	}
+	if !timer.Due(config.KeepaliveConfig{}, now.Add(defaultInterval)) {
+		t.Error("not due after the default interval")
+	}
+}
+
+func TestPing(t *testing.T) {
+	ctx := context.Background()
+	for _, driver := range []string{fakeportal.DriverDrcom, fakeportal.DriverSrun} {
+		for _, mode := range []string{ModeKeepalive, ModeStatus} {
+			srv, ts := fakeportal.NewTestServer(fakeportal.Options{Driver: driver, Account: "08123456", Password: "secret"})
+			cfg := &config.Config{Portal: srv.PortalConfig(ts.URL), Keepalive: config.KeepaliveConfig{Mode: mode}}
+			opts := &portal.Options{Proxy: portal.Direct}
+			if err := Ping(ctx, cfg, opts); err != nil {
+				t.Errorf("%s/%s: %v", driver, mode, err)
+			}
+			srv.SetFault(fakeportal.Fault{HTTPStatus: 502})
+			if err := Ping(ctx, cfg, opts); err == nil {
+				t.Errorf("%s/%s: ping ignored http 502", driver, mode)
+			}
+			ts.Close()
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package monitor
+
+import (
+	"context"
+	"errors"
+
+	"CUMT-autologin/internal/config"
+	"CUMT-autologin/internal/netbind"
+	"CUMT-autologin/internal/netcheck"
+	"CUMT-autologin/internal/notify"
+	"CUMT-autologin/internal/portal"
+	"CUMT-autologin/internal/quota"
+	"CUMT-autologin/internal/schedule"
+)
+
+// Logf receives the log lines of a Monitor; level is "INFO" or "WARN".
+type Logf func(level, format string, args ...any)
+
+// Monitor holds the session checks shared by the login loops of the tray,
+// the Windows helper and the GUI.
+type Monitor struct {
+	Tracker  *quota.Tracker
+	Notifier *notify.Notifier
+	Clock    schedule.Clock
+	Log      Logf
+}
+
+// CheckOnline asks the gateway for the session state when the driver supports
+// it and falls back to the NCSI probes otherwise. The result tells which
+// address families work.
+func (m *Monitor) CheckOnline(ctx context.Context, cfg *config.Config, opts *portal.Options) (bool, netcheck.Result) {
+	info, err := portal.Status(ctx, &cfg.Portal, opts)
+	if err == nil {
+		m.ObserveQuota(cfg, info)
+		return info.Online, netcheck.Result{IPv4: info.Online, IPv6: info.IPv6 != "", HasIPv6: info.IPv6 != ""}
+	}
+	if !errors.Is(err, portal.ErrStatusUnsupported) {
+		m.Log("WARN", "portal status query failed: %v", err)
+	}
+	r := netcheck.Check(netbind.FromConfig(cfg.Network))
+	return r.Online(cfg.Portal.DualStack), r
+}
+
+// ObserveQuota records the session in the usage history and raises quota
+// alerts. An alert held back by the notification rate limit is raised again
+// on a later call.
+func (m *Monitor) ObserveQuota(cfg *config.Config, info *portal.SessionInfo) {
+	report, alerts, err := m.Tracker.Observe(cfg.Quota, info, m.Clock.Now())
+	if err != nil {
+		m.Log("WARN", "record usage history failed: %v", err)
+	}
+	m.Notifier.SetSummary(report.Summary())
+	for _, a := range alerts {
+		if !m.Notifier.Alert(a.Event, a.Title, a.Body) {
+			continue
+		}
+		m.Log("INFO", "quota alert: %s", a.Title)
+		if err := m.Tracker.Delivered(a); err != nil {
+			m.Log("WARN", "save quota alert state failed: %v", err)
+		}
+	}
+}
+
+// AutoKick applies the self_service.auto_kick policy to a rejected login and
+// reports whether a session was kicked, in which case the next tick retries.
+func (m *Monitor) AutoKick(ctx context.Context, cfg *config.Config, opts *portal.Options, body string) bool {
+	if !cfg.Portal.SelfService.AutoKick || !portal.IsDeviceLimit(body) {
+		return false
+	}
+	sess, err := portal.KickOldestFor(ctx, cfg, opts)
+	if err != nil {
+		m.Log("WARN", "auto kick failed: %v", err)
+		return false
+	}
+	m.Log("INFO", "device limit hit, kicked session %s (%s, %s)", sess.ID, sess.IP, sess.MAC)
+	return true
+}
@@ -0,0 +1,29 @@
+//go:build linux
+
+package netbind
+
+import (
+	"errors"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// bindControl sets SO_BINDTODEVICE so that routing cannot move the socket to
+// another interface. Kernels before 5.7 require CAP_NET_RAW; without it the
+// source address binding alone still applies.
+func bindControl(iface string) func(network, address string, c syscall.RawConn) error {
+	return func(network, address string, c syscall.RawConn) error {
+		var serr error
+		err := c.Control(func(fd uintptr) {
+			serr = unix.SetsockoptString(int(fd), unix.SOL_SOCKET, unix.SO_BINDTODEVICE, iface)
+		})
+		if err != nil {
+			return err
+		}
+		if errors.Is(serr, unix.EPERM) {
+			return nil
+		}
+		return serr
+	}
+}
@@ -0,0 +1,10 @@
+//go:build !linux
+
+package netbind
+
+import "syscall"
+
+// bindControl is a no-op: elsewhere the source address alone selects the interface.
+func bindControl(string) func(network, address string, c syscall.RawConn) error {
+	return nil
+}
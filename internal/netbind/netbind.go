@@ -0,0 +1,241 @@
+package netbind
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"CUMT-autologin/internal/config"
+)
+
+// Binding pins outgoing connections to an interface or a source address.
+// The zero value does not bind and dials like net.Dialer.
+type Binding struct {
+	Interface string
+	SourceIP  string
+}
+
+// FromConfig returns the binding described by the network section.
+func FromConfig(n config.NetworkConfig) Binding {
+	return Binding{Interface: n.Interface, SourceIP: n.SourceIP}
+}
+
+// IsZero reports whether b leaves routing to the OS.
+func (b Binding) IsZero() bool {
+	return b.Interface == "" && b.SourceIP == ""
+}
+
+func (b Binding) String() string {
+	switch {
+	case b.SourceIP != "":
+		return b.SourceIP
+	case b.Interface != "":
+		return b.Interface
+	}
+	return "default"
+}
+
+// Dialer returns a dialer for network ("tcp", "udp"...) bound according to b.
+// The interface is looked up on every call, so a binding survives the
+// interface going down and coming back with a new address. A plain "tcp" or
+// "udp" binds the IPv4 address; DialContext chooses per destination.
+func (b Binding) Dialer(network string, timeout time.Duration) (*net.Dialer, error) {
+	d := &net.Dialer{Timeout: timeout, KeepAlive: 30 * time.Second}
+	if b.IsZero() {
+		return d, nil
+	}
+	ip, err := b.localIP(network)
+	if err != nil {
+		return nil, err
+	}
+	if ip != nil {
+		switch network {
+		case "udp", "udp4", "udp6":
+			d.LocalAddr = &net.UDPAddr{IP: ip}
+		default:
+			d.LocalAddr = &net.TCPAddr{IP: ip}
+		}
+	}
+	if b.Interface != "" && b.SourceIP == "" {
+		d.Control = bindControl(b.Interface)
+	}
+	return d, nil
+}
+
+// DialContext dials through b; it fits http.Transport.DialContext. For a
+// plain "tcp" or "udp" the source address is picked per destination, so an
+// interface binding reaches IPv6 hosts from the interface's IPv6 address.
+func (b Binding) DialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	if b.IsZero() || (network != "tcp" && network != "udp") {
+		d, err := b.Dialer(network, 0)
+		if err != nil {
+			return nil, err
+		}
+		return d.DialContext(ctx, network, addr)
+	}
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+	var ips []net.IP
+	if ip := net.ParseIP(host); ip != nil {
+		ips = []net.IP{ip}
+	} else {
+		addrs, err := b.Resolver().LookupIPAddr(ctx, host)
+		if err != nil {
+			return nil, err
+		}
+		for _, a := range addrs {
+			ips = append(ips, a.IP)
+		}
+	}
+	var firstErr error
+	for _, ip := range ips {
+		conn, err := b.dialIP(ctx, network+family(ip), ip, port)
+		if err == nil {
+			return conn, nil
+		}
+		if firstErr == nil {
+			firstErr = err
+		}
+	}
+	return nil, firstErr
+}
+
+// dialIP dials ip:port over network, a family-specific "tcp4" / "udp6"...
+func (b Binding) dialIP(ctx context.Context, network string, ip net.IP, port string) (net.Conn, error) {
+	if b.SourceIP != "" && family(net.ParseIP(b.SourceIP)) != family(ip) {
+		return nil, fmt.Errorf("netbind: source_ip %s cannot reach %s", b.SourceIP, ip)
+	}
+	d, err := b.Dialer(network, 0)
+	if err != nil {
+		return nil, err
+	}
+	return d.DialContext(ctx, network, net.JoinHostPort(ip.String(), port))
+}
+
+// Resolver returns a resolver whose DNS queries also leave through b, or
+// nil (the default resolver) when b does not bind. Nameservers b cannot
+// reach are queried as usual, see bindsDNS.
+func (b Binding) Resolver() *net.Resolver {
+	if b.IsZero() {
+		return nil
+	}
+	return &net.Resolver{PreferGo: true, Dial: b.dialDNS}
+}
+
+// dialDNS dials the nameserver at addr through b, in the nameserver's
+// address family, when bindsDNS allows it.
+func (b Binding) dialDNS(ctx context.Context, network, addr string) (net.Conn, error) {
+	host, _, err := net.SplitHostPort(addr)
+	ip := net.ParseIP(host)
+	if err != nil || ip == nil || !b.bindsDNS(ip) {
+		var d net.Dialer
+		return d.DialContext(ctx, network, addr)
+	}
+	return b.DialContext(ctx, network+family(ip), addr)
+}
+
+// bindsDNS reports whether queries to the nameserver ip should leave through
+// b. A loopback stub resolver (systemd-resolved, dnsmasq) cannot be reached
+// from an outside address, and neither can a nameserver of the other
+// address family than the bound source.
+func (b Binding) bindsDNS(ip net.IP) bool {
+	if ip.IsLoopback() {
+		return false
+	}
+	_, err := b.localIP("udp" + family(ip))
+	if err != nil {
+		return false
+	}
+	return b.SourceIP == "" || family(net.ParseIP(b.SourceIP)) == family(ip)
+}
+
+// family returns the network suffix ("4" or "6") for ip.
+func family(ip net.IP) string {
+	if ip.To4() != nil {
+		return "4"
+	}
+	return "6"
+}
+
+// localIP returns the source address to bind for network: SourceIP as is,
+// otherwise the first address of Interface in the network's family (IPv4
+// for plain "tcp" / "udp").
+func (b Binding) localIP(network string) (net.IP, error) {
+	if b.SourceIP != "" {
+		ip := net.ParseIP(b.SourceIP)
+		if ip == nil {
+			return nil, fmt.Errorf("netbind: invalid source_ip %q", b.SourceIP)
+		}
+		return ip, nil
+	}
+	ifc, err := net.InterfaceByName(b.Interface)
+	if err != nil {
+		return nil, fmt.Errorf("netbind: interface %q: %w", b.Interface, err)
+	}
+	if ifc.Flags&net.FlagUp == 0 {
+		return nil, fmt.Errorf("netbind: interface %q is down", b.Interface)
+	}
+	addrs, err := ifc.Addrs()
+	if err != nil {
+		return nil, fmt.Errorf("netbind: interface %q: %w", b.Interface, err)
+	}
+	want6 := strings.HasSuffix(network, "6")
+	for _, a := range addrs {
+		ipNet, ok := a.(*net.IPNet)
+		if !ok || (ipNet.IP.To4() == nil) != want6 {
+			continue
+		}
+		if want6 && !ipNet.IP.IsGlobalUnicast() {
+			continue // link-local addresses need a zone and never reach the gateway
+		}
+		return ipNet.IP, nil
+	}
+	family := "IPv4"
+	if want6 {
+		family = "IPv6"
+	}
+	return nil, fmt.Errorf("netbind: interface %q has no %s address", b.Interface, family)
+}
+
+// Probe targets used to learn the source address the OS would pick. No
+// packet is sent: connecting a UDP socket only consults the routing table.
+const (
+	probeV4 = "202.112.0.36:53"    // CERNET DNS
+	probeV6 = "[2001:da8::666]:53" // CERNET2 DNS
+)
+
+// SourceAddrs returns the IPv4 and IPv6 addresses traffic through b leaves
+// from; either is nil when that family has no route.
+func (b Binding) SourceAddrs() (v4, v6 net.IP) {
+	return b.sourceAddr("udp4", probeV4), b.sourceAddr("udp6", probeV6)
+}
+
+func (b Binding) sourceAddr(network, remote string) net.IP {
+	d, err := b.Dialer(network, time.Second)
+	if err != nil {
+		return nil
+	}
+	conn, err := d.Dial(network, remote)
+	if err != nil {
+		return nil
+	}
+	defer conn.Close()
+	addr, ok := conn.LocalAddr().(*net.UDPAddr)
+	if !ok || addr.IP.IsUnspecified() {
+		return nil
+	}
+	if network == "udp6" && !addr.IP.IsGlobalUnicast() {
+		return nil
+	}
+	return addr.IP
+}
+
+// HasIPv6 reports whether b has a routable IPv6 source address, i.e. whether
+// IPv6 connectivity is expected at all.
+func (b Binding) HasIPv6() bool {
+	return b.sourceAddr("udp6", probeV6) != nil
+}
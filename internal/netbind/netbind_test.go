@@ -0,0 +1,105 @@
+package netbind
+
+import (
+	"context"
+	"net"
+	"testing"
+)
+
+func TestBindsDNS(t *testing.T) {
+	tests := []struct {
+		b    Binding
+		ns   string
+		want bool
+	}{
+		{Binding{SourceIP: "10.2.3.4"}, "127.0.0.53", false},
+		{Binding{SourceIP: "10.2.3.4"}, "::1", false},
+		{Binding{SourceIP: "10.2.3.4"}, "2001:da8::666", false},
+		{Binding{SourceIP: "10.2.3.4"}, "202.112.0.36", true},
+		{Binding{SourceIP: "2001:da8::1"}, "202.112.0.36", false},
+		{Binding{SourceIP: "2001:da8::1"}, "2001:da8::666", true},
+		{Binding{Interface: "no-such-if0"}, "202.112.0.36", false},
+	}
+	for _, tt := range tests {
+		if got := tt.b.bindsDNS(net.ParseIP(tt.ns)); got != tt.want {
+			t.Errorf("%v.bindsDNS(%s) = %v, want %v", tt.b, tt.ns, got, tt.want)
+		}
+	}
+}
+
+func TestResolverReachesLoopbackStub(t *testing.T) {
+	pc, err := net.ListenPacket("udp4", "127.0.0.1:0")
+	if err != nil {
+		t.Skip(err)
+	}
+	defer pc.Close()
+
+	// 192.0.2.1 is not a local address: a bound dial would fail.
+	b := Binding{SourceIP: "192.0.2.1"}
+	conn, err := b.dialDNS(context.Background(), "udp", pc.LocalAddr().String())
+	if err != nil {
+		t.Fatalf("dial loopback nameserver: %v", err)
+	}
+	conn.Close()
+}
+
+// dualStackInterface returns an up, non-loopback interface with both an IPv4
+// and a global IPv6 address.
+func dualStackInterface(t *testing.T) (name string, v4, v6 net.IP) {
+	ifcs, _ := net.Interfaces()
+	for _, ifc := range ifcs {
+		if ifc.Flags&net.FlagUp == 0 || ifc.Flags&net.FlagLoopback != 0 {
+			continue
+		}
+		addrs, _ := ifc.Addrs()
+		v4, v6 = nil, nil
+		for _, a := range addrs {
+			ipNet, ok := a.(*net.IPNet)
+			switch {
+			case !ok:
+			case ipNet.IP.To4() != nil:
+				v4 = ipNet.IP
+			case ipNet.IP.IsGlobalUnicast():
+				v6 = ipNet.IP
+			}
+		}
+		if v4 != nil && v6 != nil {
+			return ifc.Name, v4, v6
+		}
+	}
+	t.Skip("no dual-stack interface")
+	return "", nil, nil
+}
+
+func TestDialContextPicksFamily(t *testing.T) {
+	name, v4, v6 := dualStackInterface(t)
+	b := Binding{Interface: name}
+	for _, ip := range []net.IP{v4, v6} {
+		ln, err := net.Listen("tcp", net.JoinHostPort(ip.String(), "0"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer ln.Close()
+		go func() {
+			if c, err := ln.Accept(); err == nil {
+				c.Close()
+			}
+		}()
+		conn, err := b.DialContext(context.Background(), "tcp", ln.Addr().String())
+		if err != nil {
+			t.Errorf("dial %s via %s: %v", ln.Addr(), name, err)
+			continue
+		}
+		if local := conn.LocalAddr().(*net.TCPAddr).IP; !local.Equal(ip) {
+			t.Errorf("dial %s left from %s", ln.Addr(), local)
+		}
+		conn.Close()
+	}
+}
+
+func TestDialContextSourceFamily(t *testing.T) {
+	b := Binding{SourceIP: "127.0.0.1"}
+	if _, err := b.DialContext(context.Background(), "tcp", "[::1]:9"); err == nil {
+		t.Error("IPv4 source dialled an IPv6 host")
+	}
+}
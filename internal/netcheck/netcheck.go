@@ -6,29 +6,40 @@ import (
 	"net"
 	"net/http"
 	"strings"
+	"sync"
 	"time"
+
+	"CUMT-autologin/internal/netbind"
+)
+
+// NCSI 探测地址。IPv6 使用独立的主机名，保证只走 IPv6。
+const (
+	ncsiURLv4  = "http://www.msftconnecttest.com/connecttest.txt"
+	ncsiURLv6  = "http://ipv6.msftconnecttest.com/connecttest.txt"
+	ncsiHost   = "dns.msftncsi.com"
+	ncsiDNSv4  = "131.107.255.255"
+	ncsiDNSv6  = "fd3e:4f5a:5b81::1"
+	ncsiExpect = "Microsoft Connect Test"
 )
 
 // ---------- NCSI HTTP 检测 ----------
-// 访问 http://www.msftconnecttest.com/connecttest.txt
-// 期望响应体为 "Microsoft Connect Test"
-func ncsiHTTP() bool {
+// 访问 connecttest.txt，期望响应体为 "Microsoft Connect Test"。
+// network 为 "tcp4" 或 "tcp6"，强制按地址族探测。
+func ncsiHTTP(b netbind.Binding, network, target string) bool {
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
-	req, err := http.NewRequestWithContext(
-		ctx,
-		http.MethodGet,
-		"http://www.msftconnecttest.com/connecttest.txt",
-		nil,
-	)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
 	if err != nil {
 		return false
 	}
 
-	// 不用代理，避免本地代理干扰判断
+	// 不用代理，避免本地代理干扰判断；按配置绑定出口网卡
 	transport := &http.Transport{
 		Proxy: nil,
+		DialContext: func(ctx context.Context, _, addr string) (net.Conn, error) {
+			return b.DialContext(ctx, network, addr)
+		},
 	}
 	client := &http.Client{
 		Timeout:   3 * time.Second,
@@ -52,42 +63,99 @@ func ncsiHTTP() bool {
 	body := strings.TrimSpace(string(bodyBytes))
 
 	// NCSI 期望的精确内容
-	return body == "Microsoft Connect Test"
+	return body == ncsiExpect
 }
 
 // ---------- NCSI DNS 检测 ----------
-// 解析 dns.msftncsi.com，期望 IP 为 131.107.255.255
-func ncsiDNS() bool {
+// 解析 dns.msftncsi.com：A 记录期望 131.107.255.255，AAAA 记录期望 fd3e:4f5a:5b81::1
+func ncsiDNS(b netbind.Binding, network, want string) bool {
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
-	resolver := &net.Resolver{}
-	ips, err := resolver.LookupIPAddr(ctx, "dns.msftncsi.com")
+	resolver := b.Resolver()
+	if resolver == nil {
+		resolver = &net.Resolver{}
+	}
+	ips, err := resolver.LookupIP(ctx, network, ncsiHost)
 	if err != nil {
 		return false
 	}
 
-	expected := net.ParseIP("131.107.255.255")
+	expected := net.ParseIP(want)
 	if expected == nil {
 		return false
 	}
 
 	for _, ip := range ips {
-		if ip.IP.Equal(expected) {
+		if ip.Equal(expected) {
 			return true
 		}
 	}
 	return false
 }
 
+// Result 是按地址族分别探测的结果。
+type Result struct {
+	IPv4 bool `json:"ipv4"`
+	IPv6 bool `json:"ipv6"`
+	// HasIPv6 为 false 表示本机没有可路由的 IPv6 地址，此时不探测 IPv6。
+	HasIPv6 bool `json:"has_ipv6"`
+}
+
+// Online 判断是否在线。dualStack 时若本机有 IPv6 地址，要求两个地址族都通。
+func (r Result) Online(dualStack bool) bool {
+	if !r.IPv4 {
+		return false
+	}
+	return !dualStack || !r.HasIPv6 || r.IPv6
+}
+
+// Check 同时探测 IPv4 与 IPv6，探测流量从 b 指定的网卡 / 源地址发出。
+func Check(b netbind.Binding) Result {
+	var r Result
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		r.IPv4 = probe(b, "4", ncsiURLv4, ncsiDNSv4)
+	}()
+	if b.HasIPv6() {
+		r.HasIPv6 = true
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			r.IPv6 = probe(b, "6", ncsiURLv6, ncsiDNSv6)
+		}()
+	}
+	wg.Wait()
+	return r
+}
+
+// probe 要求 HTTP 与 DNS 两个检测都通过。
+func probe(b netbind.Binding, family, target, dnsWant string) bool {
+	var httpOK, dnsOK bool
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		httpOK = ncsiHTTP(b, "tcp"+family, target)
+	}()
+	go func() {
+		defer wg.Done()
+		dnsOK = ncsiDNS(b, "ip"+family, dnsWant)
+	}()
+	wg.Wait()
+	return httpOK && dnsOK
+}
+
 // 对外接口：checkURL 参数目前不使用，只为了兼容原来的签名。
 func IsOnline(checkURL string) bool {
-	httpOK := ncsiHTTP()
-	dnsOK := ncsiDNS()
+	return IsOnlineVia(netbind.Binding{})
+}
 
+// IsOnlineVia 与 IsOnline 相同，但探测流量从 b 指定的网卡 / 源地址发出。
+// 只看 IPv4；需要区分地址族时使用 Check。
+func IsOnlineVia(b netbind.Binding) bool {
 	// 两个都 OK 时认为“网络基本是通的”
-	if httpOK && dnsOK {
-		return true
-	}
-	return false
+	return probe(b, "4", ncsiURLv4, ncsiDNSv4)
 }
@@ -0,0 +1,25 @@
+package netcheck
+
+import "testing"
+
+func TestResultOnline(t *testing.T) {
+	tests := []struct {
+		name      string
+		r         Result
+		dualStack bool
+		want      bool
+	}{
+		{"v4 down", Result{}, false, false},
+		{"v4 down, v6 up", Result{IPv6: true, HasIPv6: true}, true, false},
+		{"v4 up", Result{IPv4: true}, false, true},
+		{"v4 up, no v6 address", Result{IPv4: true}, true, true},
+		{"v4 up, v6 down", Result{IPv4: true, HasIPv6: true}, true, false},
+		{"v4 up, v6 down, v4 only", Result{IPv4: true, HasIPv6: true}, false, true},
+		{"both up", Result{IPv4: true, IPv6: true, HasIPv6: true}, true, true},
+	}
+	for _, tt := range tests {
+		if got := tt.r.Online(tt.dualStack); got != tt.want {
+			t.Errorf("%s: %+v.Online(%v) = %v, want %v", tt.name, tt.r, tt.dualStack, got, tt.want)
+		}
+	}
+}
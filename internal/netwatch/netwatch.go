@@ -0,0 +1,159 @@
+package netwatch
+
+import (
+	"context"
+	"net"
+	"strconv"
+	"time"
+)
+
+// Kind classifies a network change.
+type Kind int
+
+const (
+	LinkUp Kind = iota + 1
+	LinkDown
+	AddrChange
+	RouteChange
+	SSIDChange
+)
+
+func (k Kind) String() string {
+	switch k {
+	case LinkUp:
+		return "link up"
+	case LinkDown:
+		return "link down"
+	case AddrChange:
+		return "address change"
+	case RouteChange:
+		return "default route change"
+	case SSIDChange:
+		return "ssid change"
+	}
+	return "kind " + strconv.Itoa(int(k))
+}
+
+// Event is one network change. Detail names the interface, or the new SSID
+// for SSIDChange.
+type Event struct {
+	Kind   Kind
+	Detail string
+}
+
+func (e Event) String() string {
+	if e.Detail == "" {
+		return e.Kind.String()
+	}
+	return e.Kind.String() + " " + e.Detail
+}
+
+const (
+	defaultSettle       = time.Second
+	defaultSSIDInterval = 10 * time.Second
+)
+
+// Options tunes Watch. The zero value watches the OS only.
+type Options struct {
+	// SSID reports the current Wi-Fi network; nil disables SSID watching.
+	SSID func() (string, error)
+	// SSIDInterval is how often SSID is polled, 10s by default. The OS has
+	// no portable roaming notification, so this part is still a poll.
+	SSIDInterval time.Duration
+	// Settle is how long Watch waits for a burst of changes (a reconnect
+	// brings link, address and route events) to end, 1s by default.
+	Settle time.Duration
+}
+
+// Watch reports network changes until ctx is done. A burst of changes is
+// delivered as one event, and an event is dropped while the previous one
+// is still unread, so the receiver runs at most one check per burst.
+//
+// The error tells that OS notifications are unavailable; the channel is
+// valid anyway and still carries SSID changes, so callers should keep
+// polling as a fallback.
+func Watch(ctx context.Context, opts Options) (<-chan Event, error) {
+	raw := make(chan Event, 64)
+	emit := func(ev Event) {
+		select {
+		case raw <- ev:
+		default:
+		}
+	}
+	err := watchOS(ctx, emit)
+	if opts.SSID != nil {
+		interval := opts.SSIDInterval
+		if interval <= 0 {
+			interval = defaultSSIDInterval
+		}
+		go pollSSID(ctx, opts.SSID, interval, emit)
+	}
+
+	settle := opts.Settle
+	if settle <= 0 {
+		settle = defaultSettle
+	}
+	out := make(chan Event, 1)
+	go coalesce(ctx, raw, out, settle)
+	return out, err
+}
+
+// coalesce forwards the last event of every burst from raw to out.
+func coalesce(ctx context.Context, raw <-chan Event, out chan<- Event, settle time.Duration) {
+	for {
+		var pending Event
+		select {
+		case <-ctx.Done():
+			return
+		case pending = <-raw:
+		}
+		timer := time.NewTimer(settle)
+	burst:
+		for {
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return
+			case ev := <-raw:
+				pending = ev
+			case <-timer.C:
+				break burst
+			}
+		}
+		select {
+		case out <- pending:
+		default:
+		}
+	}
+}
+
+func pollSSID(ctx context.Context, current func() (string, error), interval time.Duration, emit func(Event)) {
+	last, err := current()
+	known := err == nil
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		ssid, err := current()
+		if err != nil {
+			continue
+		}
+		if known && ssid != last {
+			emit(Event{Kind: SSIDChange, Detail: ssid})
+		}
+		last, known = ssid, true
+	}
+}
+
+// ifaceName returns the name of the interface with the given index, or the
+// index itself when the interface is already gone.
+func ifaceName(index int) string {
+	if ifc, err := net.InterfaceByIndex(index); err == nil {
+		return ifc.Name
+	}
+	return "#" + strconv.Itoa(index)
+}
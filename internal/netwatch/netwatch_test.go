@@ -0,0 +1,91 @@
+package netwatch
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestCoalesceBurst(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	raw := make(chan Event, 64)
+	out := make(chan Event, 1)
+	go coalesce(ctx, raw, out, 50*time.Millisecond)
+
+	// A reconnect: link, address and route events in quick succession.
+	for _, ev := range []Event{{LinkDown, "wlan0"}, {LinkUp, "wlan0"}, {AddrChange, "wlan0"}, {RouteChange, "wlan0"}} {
+		raw <- ev
+		time.Sleep(10 * time.Millisecond)
+	}
+	select {
+	case ev := <-out:
+		if ev != (Event{RouteChange, "wlan0"}) {
+			t.Errorf("got %v, want the last event of the burst", ev)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("no event after the burst settled")
+	}
+	select {
+	case ev := <-out:
+		t.Errorf("second wake-up %v for one burst", ev)
+	case <-time.After(150 * time.Millisecond):
+	}
+}
+
+func TestCoalesceDropsWhileUnread(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	raw := make(chan Event, 64)
+	out := make(chan Event, 1)
+	go coalesce(ctx, raw, out, 20*time.Millisecond)
+
+	// Two bursts while the receiver is busy with a check.
+	raw <- Event{Kind: LinkUp}
+	time.Sleep(100 * time.Millisecond)
+	raw <- Event{Kind: AddrChange}
+	time.Sleep(100 * time.Millisecond)
+
+	if ev := <-out; ev.Kind != LinkUp {
+		t.Errorf("got %v", ev)
+	}
+	select {
+	case ev := <-out:
+		t.Errorf("queued a second wake-up %v", ev)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestWatchSSIDChange(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var mu sync.Mutex
+	ssid, polls := "CUMT_Stu", 0
+	current := func() (string, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		// Roam once the watcher has seen the first network.
+		if polls++; polls == 3 {
+			ssid = "CUMT_Tec"
+		}
+		return ssid, nil
+	}
+	events, _ := Watch(ctx, Options{SSID: current, SSIDInterval: 10 * time.Millisecond, Settle: 50 * time.Millisecond})
+
+	timeout := time.After(2 * time.Second)
+	for {
+		select {
+		case ev := <-events:
+			if ev.Kind == SSIDChange {
+				if ev.Detail != "CUMT_Tec" {
+					t.Errorf("got %v", ev)
+				}
+				return
+			}
+			// OS events from the test machine are fine, keep waiting.
+		case <-timeout:
+			t.Fatal("no ssid change event")
+		}
+	}
+}
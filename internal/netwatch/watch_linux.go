@@ -0,0 +1,115 @@
+//go:build linux
+
+package netwatch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"syscall"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// watchOS subscribes to rtnetlink link, address and route notifications.
+func watchOS(ctx context.Context, emit func(Event)) error {
+	fd, err := unix.Socket(unix.AF_NETLINK, unix.SOCK_RAW|unix.SOCK_CLOEXEC, unix.NETLINK_ROUTE)
+	if err != nil {
+		return fmt.Errorf("netwatch: netlink socket: %w", err)
+	}
+	groups := unix.RTMGRP_LINK | unix.RTMGRP_IPV4_IFADDR | unix.RTMGRP_IPV6_IFADDR |
+		unix.RTMGRP_IPV4_ROUTE | unix.RTMGRP_IPV6_ROUTE
+	if err := unix.Bind(fd, &unix.SockaddrNetlink{Family: unix.AF_NETLINK, Groups: uint32(groups)}); err != nil {
+		unix.Close(fd)
+		return fmt.Errorf("netwatch: netlink bind: %w", err)
+	}
+	// Closing the socket does not wake a blocked recvfrom, so wake up every
+	// second to notice ctx.
+	tv := unix.Timeval{Sec: 1}
+	if err := unix.SetsockoptTimeval(fd, unix.SOL_SOCKET, unix.SO_RCVTIMEO, &tv); err != nil {
+		unix.Close(fd)
+		return fmt.Errorf("netwatch: netlink timeout: %w", err)
+	}
+
+	go func() {
+		defer unix.Close(fd)
+		links := linkStates()
+		buf := make([]byte, 1<<16)
+		for ctx.Err() == nil {
+			n, _, err := unix.Recvfrom(fd, buf, 0)
+			if err != nil {
+				if errors.Is(err, unix.EAGAIN) || errors.Is(err, unix.EINTR) || errors.Is(err, unix.ENOBUFS) {
+					continue
+				}
+				return
+			}
+			msgs, err := syscall.ParseNetlinkMessage(buf[:n])
+			if err != nil {
+				continue
+			}
+			for _, m := range msgs {
+				if ev, ok := parseMessage(m, links); ok {
+					emit(ev)
+				}
+			}
+		}
+	}()
+	return nil
+}
+
+// linkStates snapshots which interfaces are running, so that the frequent
+// RTM_NEWLINK messages that do not change the state are ignored.
+func linkStates() map[int32]bool {
+	states := make(map[int32]bool)
+	ifaces, _ := net.Interfaces()
+	for _, ifc := range ifaces {
+		states[int32(ifc.Index)] = ifc.Flags&net.FlagRunning != 0
+	}
+	return states
+}
+
+func parseMessage(m syscall.NetlinkMessage, links map[int32]bool) (Event, bool) {
+	switch m.Header.Type {
+	case unix.RTM_NEWLINK, unix.RTM_DELLINK:
+		if len(m.Data) < unix.SizeofIfInfomsg {
+			return Event{}, false
+		}
+		info := (*unix.IfInfomsg)(unsafe.Pointer(&m.Data[0]))
+		up := m.Header.Type == unix.RTM_NEWLINK && info.Flags&unix.IFF_UP != 0 && info.Flags&unix.IFF_RUNNING != 0
+		if prev, ok := links[info.Index]; ok && prev == up {
+			return Event{}, false
+		}
+		links[info.Index] = up
+		if m.Header.Type == unix.RTM_DELLINK {
+			delete(links, info.Index)
+		}
+		kind := LinkDown
+		if up {
+			kind = LinkUp
+		}
+		return Event{Kind: kind, Detail: ifaceName(int(info.Index))}, true
+
+	case unix.RTM_NEWADDR, unix.RTM_DELADDR:
+		if len(m.Data) < unix.SizeofIfAddrmsg {
+			return Event{}, false
+		}
+		addr := (*unix.IfAddrmsg)(unsafe.Pointer(&m.Data[0]))
+		if addr.Scope == unix.RT_SCOPE_LINK || addr.Scope == unix.RT_SCOPE_HOST {
+			return Event{}, false // link-local and loopback addresses never reach the gateway
+		}
+		return Event{Kind: AddrChange, Detail: ifaceName(int(addr.Index))}, true
+
+	case unix.RTM_NEWROUTE, unix.RTM_DELROUTE:
+		if len(m.Data) < unix.SizeofRtMsg {
+			return Event{}, false
+		}
+		rt := (*unix.RtMsg)(unsafe.Pointer(&m.Data[0]))
+		if rt.Dst_len != 0 || rt.Table != unix.RT_TABLE_MAIN {
+			return Event{}, false
+		}
+		return Event{Kind: RouteChange}, true
+	}
+	return Event{}, false
+}
@@ -0,0 +1,12 @@
+//go:build !linux && !windows
+
+package netwatch
+
+import (
+	"context"
+	"errors"
+)
+
+func watchOS(context.Context, func(Event)) error {
+	return errors.New("netwatch: os notifications not supported on this platform")
+}
@@ -0,0 +1,106 @@
+//go:build windows
+
+package netwatch
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sync"
+
+	"golang.org/x/sys/windows"
+)
+
+// Callbacks created by windows.NewCallback are never freed, so they are
+// created once and fan out to every active watcher.
+var (
+	callbacksOnce                              sync.Once
+	ifaceCallback, addrCallback, routeCallback uintptr
+
+	sinksMu sync.Mutex
+	sinks   = map[int]func(Event){}
+	nextID  int
+)
+
+// watchOS registers for IP interface, unicast address and route changes
+// through NotifyIpInterfaceChange and friends.
+func watchOS(ctx context.Context, emit func(Event)) error {
+	callbacksOnce.Do(func() {
+		ifaceCallback = windows.NewCallback(onInterfaceChange)
+		addrCallback = windows.NewCallback(onAddressChange)
+		routeCallback = windows.NewCallback(onRouteChange)
+	})
+
+	sinksMu.Lock()
+	id := nextID
+	nextID++
+	sinks[id] = emit
+	sinksMu.Unlock()
+
+	var handles [3]windows.Handle
+	cancel := func() {
+		for _, h := range handles {
+			if h != 0 {
+				_ = windows.CancelMibChangeNotify2(h)
+			}
+		}
+		sinksMu.Lock()
+		delete(sinks, id)
+		sinksMu.Unlock()
+	}
+
+	err := windows.NotifyIpInterfaceChange(windows.AF_UNSPEC, ifaceCallback, nil, false, &handles[0])
+	if err == nil {
+		err = windows.NotifyUnicastIpAddressChange(windows.AF_UNSPEC, addrCallback, nil, false, &handles[1])
+	}
+	if err == nil {
+		err = windows.NotifyRouteChange2(windows.AF_UNSPEC, routeCallback, nil, false, &handles[2])
+	}
+	if err != nil {
+		cancel()
+		return fmt.Errorf("netwatch: register change notification: %w", err)
+	}
+
+	go func() {
+		<-ctx.Done()
+		cancel()
+	}()
+	return nil
+}
+
+func dispatch(ev Event) {
+	sinksMu.Lock()
+	defer sinksMu.Unlock()
+	for _, emit := range sinks {
+		emit(ev)
+	}
+}
+
+func onInterfaceChange(_ uintptr, row *windows.MibIpInterfaceRow, typ uint32) uintptr {
+	if row == nil || typ == windows.MibInitialNotification {
+		return 0
+	}
+	// The row only carries the interface identity; ask for the current state.
+	kind := LinkDown
+	if ifc, err := net.InterfaceByIndex(int(row.InterfaceIndex)); err == nil && ifc.Flags&net.FlagRunning != 0 {
+		kind = LinkUp
+	}
+	dispatch(Event{Kind: kind, Detail: ifaceName(int(row.InterfaceIndex))})
+	return 0
+}
+
+func onAddressChange(_ uintptr, row *windows.MibUnicastIpAddressRow, typ uint32) uintptr {
+	if row == nil || typ == windows.MibInitialNotification {
+		return 0
+	}
+	dispatch(Event{Kind: AddrChange, Detail: ifaceName(int(row.InterfaceIndex))})
+	return 0
+}
+
+func onRouteChange(_ uintptr, row *windows.MibIpForwardRow2, typ uint32) uintptr {
+	if row == nil || typ == windows.MibInitialNotification || row.DestinationPrefix.PrefixLength != 0 {
+		return 0
+	}
+	dispatch(Event{Kind: RouteChange, Detail: ifaceName(int(row.InterfaceIndex))})
+	return 0
+}
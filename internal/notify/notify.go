@@ -0,0 +1,239 @@
+package notify
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"CUMT-autologin/internal/config"
+)
+
+const appName = "CUMT 校园网自动登录"
+
+// Event identifies the kind of state transition being reported.
+type Event string
+
+const (
+	EventOnline      Event = "online"
+	EventOffline     Event = "offline"
+	EventLoginFailed Event = "login_failed"
+	EventUnreachable Event = "portal_unreachable"
+	EventQuota       Event = "quota"
+	EventLowBalance  Event = "low_balance"
+)
+
+// Sender delivers a single notification to the user.
+type Sender interface {
+	Send(title, body string) error
+}
+
+// Notifier turns connection observations into rate-limited notifications.
+// The login loops report what they saw on every tick; Notifier decides
+// whether that is a transition worth telling the user about.
+type Notifier struct {
+	mu       sync.Mutex
+	cfg      config.NotifyConfig
+	sender   Sender
+	webhooks *Webhooks
+	host     string
+	summary  string
+	now      func() time.Time
+
+	last map[Event]time.Time
+	// lastHook rate-limits webhooks separately from desktop toasts.
+	lastHook map[Event]time.Time
+
+	known            bool
+	online           bool
+	unreachableSince time.Time
+	unreachableSent  bool
+}
+
+// New returns a Notifier using the platform's desktop notification service.
+func New(cfg config.NotifyConfig) *Notifier {
+	return NewWithSender(cfg, newPlatformSender())
+}
+
+// NewWithSender returns a Notifier that delivers through s.
+func NewWithSender(cfg config.NotifyConfig, s Sender) *Notifier {
+	host, _ := os.Hostname()
+	n := &Notifier{
+		cfg:      cfg,
+		sender:   s,
+		webhooks: NewWebhooks(nil),
+		host:     host,
+		now:      time.Now,
+		last:     make(map[Event]time.Time),
+		lastHook: make(map[Event]time.Time),
+	}
+	n.webhooks.SetHooks(cfg.Webhooks)
+	return n
+}
+
+// Webhooks returns the webhook queue, e.g. to inspect pending deliveries.
+func (n *Notifier) Webhooks() *Webhooks {
+	return n.webhooks
+}
+
+// SetConfig replaces the notification settings, e.g. after config.yaml is reloaded.
+func (n *Notifier) SetConfig(cfg config.NotifyConfig) {
+	n.mu.Lock()
+	n.cfg = cfg
+	n.mu.Unlock()
+	n.webhooks.SetHooks(cfg.Webhooks)
+}
+
+// Online records that the network is usable.
+func (n *Notifier) Online() {
+	n.mu.Lock()
+	wasOffline := n.known && !n.online
+	n.known = true
+	n.online = true
+	n.unreachableSince = time.Time{}
+	n.unreachableSent = false
+	n.mu.Unlock()
+
+	if wasOffline {
+		n.emit(EventOnline, "已连接校园网", n.withSummary("自动登录成功，网络已可用"))
+	}
+	// Anything queued while we were cut off can go out now.
+	go n.webhooks.Flush()
+}
+
+// Offline records that the network is not usable, without a specific failure.
+func (n *Notifier) Offline() {
+	n.mu.Lock()
+	wasOnline := n.known && n.online
+	n.known = true
+	n.online = false
+	n.mu.Unlock()
+
+	if wasOnline {
+		n.emit(EventOffline, "校园网已断开", "检测到网络不可用，正在尝试重新登录")
+	}
+}
+
+// LoginFailed records that the gateway rejected the login.
+func (n *Notifier) LoginFailed(reason string) {
+	n.mu.Lock()
+	n.known = true
+	n.online = false
+	n.unreachableSince = time.Time{}
+	n.unreachableSent = false
+	n.mu.Unlock()
+
+	if reason == "" {
+		reason = "网关响应异常"
+	}
+	n.emit(EventLoginFailed, "校园网登录失败", reason)
+}
+
+// Unreachable records a failed request to the portal. A notification is
+// raised once the portal has been unreachable for UnreachableMinutes.
+func (n *Notifier) Unreachable(err error) {
+	n.mu.Lock()
+	n.known = true
+	n.online = false
+	now := n.now()
+	if n.unreachableSince.IsZero() {
+		n.unreachableSince = now
+	}
+	threshold := time.Duration(n.cfg.UnreachableMinutes) * time.Minute
+	due := !n.unreachableSent && now.Sub(n.unreachableSince) >= threshold
+	if due {
+		n.unreachableSent = true
+	}
+	since := n.unreachableSince
+	n.mu.Unlock()
+
+	if !due {
+		return
+	}
+	body := fmt.Sprintf("认证网关已 %d 分钟无法访问", int(now.Sub(since).Minutes()))
+	if err != nil {
+		body += ": " + err.Error()
+	}
+	n.emit(EventUnreachable, "无法连接认证网关", body)
+}
+
+// Alert raises a notification that is not tied to the online state, such as
+// quota or balance warnings. It is subject to the same toggles and rate limit.
+// It returns false when the rate limit held the alert back on every channel
+// that wanted it, so the caller can raise it again later.
+func (n *Notifier) Alert(ev Event, title, body string) bool {
+	return n.emit(ev, title, body)
+}
+
+// SetSummary sets a short usage line (traffic / balance) appended to the
+// "came online" notification.
+func (n *Notifier) SetSummary(s string) {
+	n.mu.Lock()
+	n.summary = s
+	n.mu.Unlock()
+}
+
+func (n *Notifier) withSummary(body string) string {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.summary == "" {
+		return body
+	}
+	return body + "\n" + n.summary
+}
+
+func (n *Notifier) enabled(ev Event) bool {
+	if !n.cfg.Enabled {
+		return false
+	}
+	switch ev {
+	case EventOnline:
+		return n.cfg.OnOnline
+	case EventOffline:
+		return n.cfg.OnOffline
+	case EventLoginFailed:
+		return n.cfg.OnLoginFailed
+	case EventUnreachable:
+		return n.cfg.OnUnreachable
+	case EventQuota, EventLowBalance:
+		return n.cfg.OnQuota
+	}
+	return false
+}
+
+// emit raises ev on both channels. Desktop toasts follow notify.enabled and
+// the per-event switches; webhooks only follow their own events list. Each
+// channel has its own MinInterval rate limit. emit reports whether ev went
+// out on some channel or no channel wanted it.
+func (n *Notifier) emit(ev Event, title, body string) bool {
+	n.mu.Lock()
+	now := n.now()
+	minGap := time.Duration(n.cfg.MinInterval) * time.Second
+	due := func(last map[Event]time.Time) bool {
+		if t, ok := last[ev]; ok && now.Sub(t) < minGap {
+			return false
+		}
+		last[ev] = now
+		return true
+	}
+	wantToast := n.enabled(ev) && n.sender != nil
+	wantHook := false
+	for _, h := range n.cfg.Webhooks {
+		wantHook = wantHook || h.URL != "" && subscribed(h, ev)
+	}
+	toast := wantToast && due(n.last)
+	hook := wantHook && due(n.lastHook)
+	sender := n.sender
+	n.mu.Unlock()
+
+	if hook {
+		n.webhooks.Enqueue(Message{Event: ev, Title: title, Body: body, Time: now, Host: n.host})
+	}
+	if toast {
+		if err := sender.Send(title, body); err != nil {
+			log.Printf("[notify] send %s failed: %v", ev, err)
+		}
+	}
+	return toast || hook || !wantToast && !wantHook
+}
@@ -0,0 +1,56 @@
+//go:build linux
+
+package notify
+
+import (
+	"sync"
+
+	"github.com/godbus/dbus/v5"
+)
+
+const (
+	dbusDest = "org.freedesktop.Notifications"
+	dbusPath = "/org/freedesktop/Notifications"
+)
+
+// dbusSender talks to org.freedesktop.Notifications on the session bus.
+type dbusSender struct {
+	mu     sync.Mutex
+	conn   *dbus.Conn
+	lastID uint32
+}
+
+func newPlatformSender() Sender {
+	return &dbusSender{}
+}
+
+func (s *dbusSender) Send(title, body string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.conn == nil {
+		conn, err := dbus.ConnectSessionBus()
+		if err != nil {
+			return err
+		}
+		s.conn = conn
+	}
+
+	obj := s.conn.Object(dbusDest, dbusPath)
+	call := obj.Call(dbusDest+".Notify", 0,
+		appName,
+		s.lastID, // replace the previous bubble instead of stacking
+		"network-wireless",
+		title,
+		body,
+		[]string{},
+		map[string]dbus.Variant{},
+		int32(-1),
+	)
+	if call.Err != nil {
+		_ = s.conn.Close()
+		s.conn = nil
+		return call.Err
+	}
+	return call.Store(&s.lastID)
+}
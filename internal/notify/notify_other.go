@@ -0,0 +1,13 @@
+//go:build !linux && !windows
+
+package notify
+
+type nopSender struct{}
+
+func newPlatformSender() Sender {
+	return nopSender{}
+}
+
+func (nopSender) Send(title, body string) error {
+	return nil
+}
@@ -0,0 +1,47 @@
+//go:build windows
+
+package notify
+
+import (
+	"bytes"
+	"encoding/xml"
+	"os"
+	"os/exec"
+	"syscall"
+)
+
+// powershellAppID is the AppUserModelID registered by Windows for PowerShell;
+// toasts need an existing ID and we do not install a Start menu shortcut.
+const powershellAppID = `{1AC14E77-02E7-4E5D-B744-2EB1AE5198B7}\WindowsPowerShell\v1.0\powershell.exe`
+
+const toastScript = `
+[Windows.UI.Notifications.ToastNotificationManager, Windows.UI.Notifications, ContentType = WindowsRuntime] | Out-Null
+[Windows.Data.Xml.Dom.XmlDocument, Windows.Data.Xml.Dom.XmlDocument, ContentType = WindowsRuntime] | Out-Null
+$xml = New-Object Windows.Data.Xml.Dom.XmlDocument
+$xml.LoadXml($env:CUMT_TOAST_XML)
+$toast = New-Object Windows.UI.Notifications.ToastNotification $xml
+[Windows.UI.Notifications.ToastNotificationManager]::CreateToastNotifier('` + powershellAppID + `').Show($toast)
+`
+
+// toastSender shows Windows 10+ toast notifications through PowerShell.
+type toastSender struct{}
+
+func newPlatformSender() Sender {
+	return toastSender{}
+}
+
+func (toastSender) Send(title, body string) error {
+	var buf bytes.Buffer
+	buf.WriteString(`<toast><visual><binding template="ToastGeneric"><text>`)
+	_ = xml.EscapeText(&buf, []byte(title))
+	buf.WriteString(`</text><text>`)
+	_ = xml.EscapeText(&buf, []byte(body))
+	buf.WriteString(`</text><text placement="attribution">`)
+	_ = xml.EscapeText(&buf, []byte(appName))
+	buf.WriteString(`</text></binding></visual></toast>`)
+
+	cmd := exec.Command("powershell.exe", "-NoProfile", "-NonInteractive", "-Command", toastScript)
+	cmd.SysProcAttr = &syscall.SysProcAttr{HideWindow: true}
+	cmd.Env = append(os.Environ(), "CUMT_TOAST_XML="+buf.String())
+	return cmd.Run()
+}
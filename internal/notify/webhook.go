@@ -0,0 +1,272 @@
+package notify
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"CUMT-autologin/internal/config"
+)
+
+const maxWebhookQueue = 100
+
+// Message is the payload handed to webhook sinks.
+type Message struct {
+	Event Event     `json:"event"`
+	Title string    `json:"title"`
+	Body  string    `json:"message"`
+	Time  time.Time `json:"time"`
+	Host  string    `json:"host"`
+}
+
+type webhookJob struct {
+	hook config.WebhookConfig
+	msg  Message
+}
+
+// Webhooks queues messages for the configured webhook sinks. Deliveries that
+// fail (typically because the machine itself is offline) stay queued and are
+// retried on the next Flush, which the Notifier triggers once back online.
+type Webhooks struct {
+	mu       sync.Mutex
+	client   *http.Client
+	hooks    []config.WebhookConfig
+	queue    []webhookJob
+	flushing bool
+}
+
+// NewWebhooks returns a webhook queue delivering through client.
+// A nil client uses a 10 second timeout client without proxy.
+func NewWebhooks(client *http.Client) *Webhooks {
+	if client == nil {
+		t := http.DefaultTransport.(*http.Transport).Clone()
+		t.Proxy = nil
+		client = &http.Client{Timeout: 10 * time.Second, Transport: t}
+	}
+	return &Webhooks{client: client}
+}
+
+// SetHooks replaces the configured sinks. Already queued jobs are kept.
+func (w *Webhooks) SetHooks(hooks []config.WebhookConfig) {
+	w.mu.Lock()
+	w.hooks = append([]config.WebhookConfig(nil), hooks...)
+	w.mu.Unlock()
+}
+
+// Pending returns the number of queued deliveries.
+func (w *Webhooks) Pending() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return len(w.queue)
+}
+
+// Enqueue schedules msg for every sink subscribed to its event and starts a flush.
+func (w *Webhooks) Enqueue(msg Message) {
+	w.mu.Lock()
+	added := false
+	for _, h := range w.hooks {
+		if h.URL == "" || !subscribed(h, msg.Event) {
+			continue
+		}
+		w.queue = append(w.queue, webhookJob{hook: h, msg: msg})
+		added = true
+	}
+	if over := len(w.queue) - maxWebhookQueue; over > 0 {
+		w.queue = w.queue[over:]
+	}
+	w.mu.Unlock()
+
+	if added {
+		go w.Flush()
+	}
+}
+
+// Flush tries to deliver every queued message once. A hook that fails with
+// a network error or a 5xx / 408 / 429 keeps its jobs, in order, for the next
+// flush; other hooks are not held up by it. Jobs that fail permanently (a
+// 4xx, a rejected token, a payload that cannot be built) are dropped.
+func (w *Webhooks) Flush() {
+	w.mu.Lock()
+	if w.flushing || len(w.queue) == 0 {
+		w.mu.Unlock()
+		return
+	}
+	w.flushing = true
+	jobs := w.queue
+	w.queue = nil
+	w.mu.Unlock()
+
+	var failed []webhookJob
+	down := make(map[string]bool)
+	for _, job := range jobs {
+		key := hookKey(job.hook)
+		if down[key] {
+			failed = append(failed, job)
+			continue
+		}
+		err := w.deliver(job)
+		if err == nil {
+			continue
+		}
+		var perm *permanentError
+		if errors.As(err, &perm) {
+			log.Printf("[notify] webhook %q dropped a message: %v", hookName(job.hook), err)
+			continue
+		}
+		// Most likely offline or the sink is down; retry on the next flush.
+		log.Printf("[notify] webhook %q failed: %v", hookName(job.hook), err)
+		down[key] = true
+		failed = append(failed, job)
+	}
+
+	w.mu.Lock()
+	w.queue = append(failed, w.queue...)
+	if over := len(w.queue) - maxWebhookQueue; over > 0 {
+		w.queue = w.queue[over:]
+	}
+	w.flushing = false
+	w.mu.Unlock()
+}
+
+// permanentError marks a delivery failure that retrying cannot fix.
+type permanentError struct{ err error }
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+
+func (w *Webhooks) deliver(job webhookJob) error {
+	req, err := buildWebhookRequest(job.hook, job.msg)
+	if err != nil {
+		return &permanentError{err}
+	}
+	for k, v := range job.hook.Headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		err := fmt.Errorf("http status %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+		if resp.StatusCode >= 500 || resp.StatusCode == http.StatusRequestTimeout || resp.StatusCode == http.StatusTooManyRequests {
+			return err
+		}
+		return &permanentError{err}
+	}
+	if err := checkWebhookReply(body); err != nil {
+		// The bot API answered, so the token or payload is at fault.
+		return &permanentError{err}
+	}
+	return nil
+}
+
+func buildWebhookRequest(h config.WebhookConfig, msg Message) (*http.Request, error) {
+	text := msg.Title + "\n" + msg.Body
+	if msg.Host != "" {
+		text += "\n主机: " + msg.Host
+	}
+	text += "\n时间: " + msg.Time.Format("2006-01-02 15:04:05")
+
+	var payload any
+	switch strings.ToLower(h.Kind) {
+	case "", "generic":
+		payload = msg
+	case "wecom", "dingtalk":
+		payload = map[string]any{
+			"msgtype": "text",
+			"text":    map[string]string{"content": text},
+		}
+	case "feishu", "lark":
+		payload = map[string]any{
+			"msg_type": "text",
+			"content":  map[string]string{"text": text},
+		}
+	case "telegram":
+		payload = map[string]any{
+			"chat_id": h.ChatID,
+			"text":    text,
+		}
+	case "serverchan":
+		form := url.Values{}
+		form.Set("title", msg.Title)
+		form.Set("desp", strings.ReplaceAll(text, "\n", "\n\n"))
+		req, err := http.NewRequest(http.MethodPost, h.URL, strings.NewReader(form.Encode()))
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		return req, nil
+	default:
+		return nil, fmt.Errorf("unknown webhook kind %q", h.Kind)
+	}
+
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequest(http.MethodPost, h.URL, bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	return req, nil
+}
+
+// checkWebhookReply detects chat-bot APIs that report errors with HTTP 200.
+func checkWebhookReply(body []byte) error {
+	var reply struct {
+		ErrCode *int   `json:"errcode"` // wecom / dingtalk
+		ErrMsg  string `json:"errmsg"`
+		Code    *int   `json:"code"` // feishu / serverchan
+		Msg     string `json:"msg"`
+		OK      *bool  `json:"ok"` // telegram
+		Desc    string `json:"description"`
+	}
+	if json.Unmarshal(body, &reply) != nil {
+		return nil
+	}
+	switch {
+	case reply.ErrCode != nil && *reply.ErrCode != 0:
+		return fmt.Errorf("errcode %d: %s", *reply.ErrCode, reply.ErrMsg)
+	case reply.Code != nil && *reply.Code != 0:
+		return fmt.Errorf("code %d: %s", *reply.Code, reply.Msg)
+	case reply.OK != nil && !*reply.OK:
+		return fmt.Errorf("not ok: %s", reply.Desc)
+	}
+	return nil
+}
+
+func subscribed(h config.WebhookConfig, ev Event) bool {
+	if len(h.Events) == 0 {
+		return true
+	}
+	for _, e := range h.Events {
+		if Event(e) == ev {
+			return true
+		}
+	}
+	return false
+}
+
+// hookKey identifies a sink across config reloads.
+func hookKey(h config.WebhookConfig) string {
+	return h.Kind + " " + h.URL
+}
+
+func hookName(h config.WebhookConfig) string {
+	if h.Name != "" {
+		return h.Name
+	}
+	return h.Kind
+}
@@ -0,0 +1,288 @@
+package notify
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"CUMT-autologin/internal/config"
+)
+
+// captured is one request received by a stand-in webhook server.
+type captured struct {
+	path        string
+	contentType string
+	body        string
+}
+
+// sink is a local HTTP stand-in for a webhook API.
+type sink struct {
+	*httptest.Server
+	mu   sync.Mutex
+	got  []captured
+	hits chan struct{}
+}
+
+func newSink(t *testing.T, status int, reply string) *sink {
+	t.Helper()
+	s := &sink{hits: make(chan struct{}, 16)}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		s.mu.Lock()
+		s.got = append(s.got, captured{r.URL.Path, r.Header.Get("Content-Type"), string(body)})
+		s.mu.Unlock()
+		w.WriteHeader(status)
+		io.WriteString(w, reply)
+		s.hits <- struct{}{}
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *sink) requests() []captured {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]captured(nil), s.got...)
+}
+
+func (s *sink) wait(t *testing.T) {
+	t.Helper()
+	select {
+	case <-s.hits:
+	case <-time.After(5 * time.Second):
+		t.Fatal("webhook was not called")
+	}
+}
+
+var testMsg = Message{
+	Event: EventLoginFailed,
+	Title: "校园网登录失败",
+	Body:  "密码错误",
+	Time:  time.Date(2024, 3, 1, 8, 30, 0, 0, time.Local),
+	Host:  "lab-pc",
+}
+
+func TestWebhookPayloads(t *testing.T) {
+	tests := []struct {
+		kind   string
+		reply  string
+		verify func(t *testing.T, c captured)
+	}{
+		{"generic", "", func(t *testing.T, c captured) {
+			var m Message
+			if err := json.Unmarshal([]byte(c.body), &m); err != nil {
+				t.Fatal(err)
+			}
+			if m.Event != EventLoginFailed || m.Title != testMsg.Title || m.Body != testMsg.Body || m.Host != "lab-pc" {
+				t.Errorf("payload = %+v", m)
+			}
+		}},
+		{"wecom", `{"errcode":0,"errmsg":"ok"}`, func(t *testing.T, c captured) {
+			var m struct {
+				MsgType string `json:"msgtype"`
+				Text    struct {
+					Content string `json:"content"`
+				} `json:"text"`
+			}
+			if err := json.Unmarshal([]byte(c.body), &m); err != nil {
+				t.Fatal(err)
+			}
+			if m.MsgType != "text" || !strings.Contains(m.Text.Content, "密码错误") || !strings.Contains(m.Text.Content, "主机: lab-pc") {
+				t.Errorf("payload = %+v", m)
+			}
+		}},
+		{"telegram", `{"ok":true}`, func(t *testing.T, c captured) {
+			var m struct {
+				ChatID string `json:"chat_id"`
+				Text   string `json:"text"`
+			}
+			if err := json.Unmarshal([]byte(c.body), &m); err != nil {
+				t.Fatal(err)
+			}
+			if m.ChatID != "42" || !strings.HasPrefix(m.Text, "校园网登录失败\n密码错误") {
+				t.Errorf("payload = %+v", m)
+			}
+		}},
+		{"serverchan", `{"code":0}`, func(t *testing.T, c captured) {
+			if c.contentType != "application/x-www-form-urlencoded" {
+				t.Errorf("content type = %q", c.contentType)
+			}
+			form, err := url.ParseQuery(c.body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if form.Get("title") != testMsg.Title || !strings.Contains(form.Get("desp"), "密码错误\n\n") {
+				t.Errorf("form = %v", form)
+			}
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.kind, func(t *testing.T) {
+			s := newSink(t, http.StatusOK, tt.reply)
+			w := NewWebhooks(nil)
+			w.SetHooks([]config.WebhookConfig{{Kind: tt.kind, URL: s.URL + "/hook", ChatID: "42"}})
+			w.Enqueue(testMsg)
+			s.wait(t)
+			w.Flush()
+			reqs := s.requests()
+			if len(reqs) != 1 {
+				t.Fatalf("got %d requests", len(reqs))
+			}
+			if tt.kind != "serverchan" && reqs[0].contentType != "application/json" {
+				t.Errorf("content type = %q", reqs[0].contentType)
+			}
+			tt.verify(t, reqs[0])
+			if n := w.Pending(); n != 0 {
+				t.Errorf("pending = %d", n)
+			}
+		})
+	}
+}
+
+func TestFlushDropsPermanentFailures(t *testing.T) {
+	good := newSink(t, http.StatusOK, "")
+	rejected := newSink(t, http.StatusForbidden, "bad token")
+	botErr := newSink(t, http.StatusOK, `{"errcode":93000,"errmsg":"invalid webhook url"}`)
+
+	w := NewWebhooks(nil)
+	w.SetHooks([]config.WebhookConfig{
+		{Name: "rejected", URL: rejected.URL},
+		{Name: "bot", Kind: "wecom", URL: botErr.URL},
+		{Name: "unknown", Kind: "pigeon", URL: good.URL},
+		{Name: "good", URL: good.URL},
+	})
+	w.mu.Lock()
+	w.queue = nil
+	for _, h := range w.hooks {
+		w.queue = append(w.queue, webhookJob{hook: h, msg: testMsg})
+	}
+	w.mu.Unlock()
+	w.Flush()
+
+	if n := w.Pending(); n != 0 {
+		t.Errorf("pending = %d, want permanent failures dropped", n)
+	}
+	if n := len(good.requests()); n != 1 {
+		t.Errorf("good hook got %d requests, want 1", n)
+	}
+}
+
+func TestFlushRetriesOnlyTheHookThatIsDown(t *testing.T) {
+	good := newSink(t, http.StatusOK, "")
+	busy := newSink(t, http.StatusServiceUnavailable, "")
+	down := httptest.NewServer(http.NotFoundHandler())
+	downURL := down.URL
+	down.Close()
+
+	hooks := []config.WebhookConfig{
+		{Name: "down", URL: downURL},
+		{Name: "busy", URL: busy.URL},
+		{Name: "good", URL: good.URL},
+	}
+	w := NewWebhooks(nil)
+	w.mu.Lock()
+	for i := 0; i < 2; i++ {
+		for _, h := range hooks {
+			w.queue = append(w.queue, webhookJob{hook: h, msg: testMsg})
+		}
+	}
+	w.mu.Unlock()
+	w.Flush()
+
+	if n := len(good.requests()); n != 2 {
+		t.Errorf("good hook got %d requests, want 2", n)
+	}
+	// Each failing hook is tried once per flush and keeps both its jobs.
+	if n := len(busy.requests()); n != 1 {
+		t.Errorf("busy hook tried %d times, want 1", n)
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if len(w.queue) != 4 {
+		t.Fatalf("queue = %d jobs, want 4", len(w.queue))
+	}
+	for _, job := range w.queue {
+		if job.hook.Name == "good" {
+			t.Error("delivered job kept in the queue")
+		}
+	}
+}
+
+type recordSender struct {
+	mu   sync.Mutex
+	sent []string
+}
+
+func (r *recordSender) Send(title, body string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.sent = append(r.sent, title)
+	return nil
+}
+
+func TestWebhooksIndependentOfDesktopSwitch(t *testing.T) {
+	s := newSink(t, http.StatusOK, "")
+	sender := &recordSender{}
+	n := NewWithSender(config.NotifyConfig{
+		Enabled:     false,
+		MinInterval: 300,
+		Webhooks:    []config.WebhookConfig{{URL: s.URL, Events: []string{string(EventLoginFailed)}}},
+	}, sender)
+
+	n.LoginFailed("密码错误")
+	s.wait(t)
+	// An unsubscribed event does not reach the hook.
+	n.Alert(EventQuota, "流量提醒", "80%")
+	time.Sleep(50 * time.Millisecond)
+
+	if got := len(s.requests()); got != 1 {
+		t.Errorf("webhook got %d requests, want 1", got)
+	}
+	if len(sender.sent) != 0 {
+		t.Errorf("desktop toasts sent while disabled: %v", sender.sent)
+	}
+}
+
+func TestToastRateLimitDoesNotSuppressWebhooks(t *testing.T) {
+	s := newSink(t, http.StatusOK, "")
+	sender := &recordSender{}
+	now := time.Date(2024, 3, 1, 8, 0, 0, 0, time.Local)
+	n := NewWithSender(config.NotifyConfig{Enabled: true, OnLoginFailed: true, MinInterval: 300}, sender)
+	n.now = func() time.Time { return now }
+
+	n.LoginFailed("a")
+	n.SetConfig(config.NotifyConfig{Enabled: true, OnLoginFailed: true, MinInterval: 300,
+		Webhooks: []config.WebhookConfig{{URL: s.URL}}})
+	now = now.Add(time.Minute)
+	n.LoginFailed("b")
+	s.wait(t)
+
+	if len(sender.sent) != 1 {
+		t.Errorf("toasts = %d, want 1 within MinInterval", len(sender.sent))
+	}
+}
+
+func TestAlertReportsRateLimitedDrops(t *testing.T) {
+	sender := &recordSender{}
+	now := time.Date(2024, 3, 1, 8, 0, 0, 0, time.Local)
+	n := NewWithSender(config.NotifyConfig{Enabled: true, OnQuota: true, MinInterval: 300}, sender)
+	n.now = func() time.Time { return now }
+
+	if !n.Alert(EventQuota, "流量提醒", "80%") {
+		t.Error("first alert not delivered")
+	}
+	now = now.Add(time.Minute)
+	if n.Alert(EventQuota, "流量提醒", "95%") {
+		t.Error("alert within MinInterval reported as delivered")
+	}
+	n.SetConfig(config.NotifyConfig{Enabled: true, OnQuota: false})
+	if !n.Alert(EventQuota, "流量提醒", "95%") {
+		t.Error("alert nobody wants should count as handled")
+	}
+}
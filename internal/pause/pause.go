@@ -0,0 +1,182 @@
+package pause
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"CUMT-autologin/internal/config"
+	"CUMT-autologin/internal/ifinfo"
+	"CUMT-autologin/internal/wifi"
+)
+
+// DefaultPath is pause.json alongside the default config file. The tray, the
+// GUI and the login loops all read and write this file, so it also acts as the
+// control channel between processes and survives restarts.
+var DefaultPath = PathFor(config.DefaultConfigPath)
+
+// PathFor returns the pause file that belongs to the config file at configPath.
+func PathFor(configPath string) string {
+	return filepath.Join(filepath.Dir(configPath), "pause.json")
+}
+
+// State describes whether auto-login is paused and until when.
+type State struct {
+	Paused bool `json:"paused"`
+	// Until ends a timed snooze; zero means paused until resumed.
+	Until time.Time `json:"until,omitempty"`
+	// UntilNetworkChange ends the pause as soon as the network differs from Network.
+	UntilNetworkChange bool      `json:"until_network_change,omitempty"`
+	Network            string    `json:"network,omitempty"`
+	Since              time.Time `json:"since"`
+}
+
+// Active reports whether the pause still applies at now on the given network.
+func (s State) Active(now time.Time, network string) bool {
+	if !s.Paused {
+		return false
+	}
+	if !s.Until.IsZero() && !now.Before(s.Until) {
+		return false
+	}
+	if s.UntilNetworkChange && network != s.Network {
+		return false
+	}
+	return true
+}
+
+// Describe returns a short status line for the tray / GUI.
+func (s State) Describe(now time.Time) string {
+	switch {
+	case !s.Paused:
+		return "自动登录运行中"
+	case !s.Until.IsZero():
+		left := s.Until.Sub(now).Round(time.Minute)
+		if left < time.Minute {
+			left = time.Minute
+		}
+		return fmt.Sprintf("已暂停（%s 后恢复，%s）", left, s.Until.Format("15:04"))
+	case s.UntilNetworkChange:
+		return "已暂停（网络变化后恢复）"
+	}
+	return "已暂停"
+}
+
+// Load reads the pause state; a missing file means not paused.
+func Load(path string) (State, error) {
+	var s State
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return s, err
+	}
+	err = json.Unmarshal(data, &s)
+	return s, err
+}
+
+// Save writes the pause state, removing the file when not paused.
+func Save(path string, s State) error {
+	if !s.Paused {
+		err := os.Remove(path)
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// Pause stops auto-login until Resume is called.
+func Pause(path string) error {
+	return Save(path, State{Paused: true, Since: time.Now()})
+}
+
+// Snooze stops auto-login for d.
+func Snooze(path string, d time.Duration) error {
+	now := time.Now()
+	return Save(path, State{Paused: true, Until: now.Add(d), Since: now})
+}
+
+// SnoozeUntilNetworkChange stops auto-login until the current network changes.
+func SnoozeUntilNetworkChange(path string) error {
+	return Save(path, State{Paused: true, UntilNetworkChange: true, Network: NetworkFingerprint(), Since: time.Now()})
+}
+
+// Resume re-enables auto-login.
+func Resume(path string) error {
+	return Save(path, State{})
+}
+
+// Check loads the state at path and reports whether auto-login is paused.
+// An expired pause is cleared from disk so that every reader agrees on it.
+func Check(path string, now time.Time) (State, bool) {
+	s, err := Load(path)
+	if err != nil || !s.Paused {
+		return s, false
+	}
+	network := ""
+	if s.UntilNetworkChange {
+		network = NetworkFingerprint()
+	}
+	if s.Active(now, network) {
+		return s, true
+	}
+	_ = Resume(path)
+	return State{}, false
+}
+
+// NetworkFingerprint summarises the current network: the Wi-Fi SSID, the
+// default gateway, the IPv4 addresses and the IPv6 /64 prefixes of all
+// active interfaces. It changes whenever the machine joins a different
+// network, wired or not. IPv6 addresses are cut to their prefix because
+// privacy addresses rotate without a change of network.
+func NetworkFingerprint() string {
+	var parts []string
+	if ssid, err := wifi.CurrentSSID(""); err == nil && ssid != "" {
+		parts = append(parts, "ssid="+ssid)
+	}
+	if snap, err := ifinfo.Inspect(); err == nil && snap.Gateway.IsValid() {
+		parts = append(parts, "gw="+snap.Gateway.String())
+	}
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		return strings.Join(parts, ",")
+	}
+	v6Mask := net.CIDRMask(64, 128)
+	for _, ifc := range ifaces {
+		if ifc.Flags&net.FlagUp == 0 || ifc.Flags&net.FlagLoopback != 0 {
+			continue
+		}
+		addrs, err := ifc.Addrs()
+		if err != nil {
+			continue
+		}
+		for _, a := range addrs {
+			ipNet, ok := a.(*net.IPNet)
+			if !ok {
+				continue
+			}
+			switch {
+			case ipNet.IP.To4() != nil:
+				parts = append(parts, ifc.Name+"="+ipNet.String())
+			case ipNet.IP.IsGlobalUnicast():
+				prefix := &net.IPNet{IP: ipNet.IP.Mask(v6Mask), Mask: v6Mask}
+				parts = append(parts, ifc.Name+"="+prefix.String())
+			}
+		}
+	}
+	sort.Strings(parts)
+	return strings.Join(parts, ",")
+}
@@ -0,0 +1,66 @@
+package pause
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestActive(t *testing.T) {
+	now := time.Date(2024, 3, 1, 8, 0, 0, 0, time.Local)
+	tests := []struct {
+		name    string
+		s       State
+		network string
+		want    bool
+	}{
+		{"not paused", State{}, "", false},
+		{"manual", State{Paused: true}, "", true},
+		{"snooze running", State{Paused: true, Until: now.Add(time.Minute)}, "", true},
+		{"snooze over", State{Paused: true, Until: now}, "", false},
+		{"same network", State{Paused: true, UntilNetworkChange: true, Network: "ssid=CUMT_Stu"}, "ssid=CUMT_Stu", true},
+		{"network changed", State{Paused: true, UntilNetworkChange: true, Network: "ssid=CUMT_Stu"}, "ssid=home", false},
+	}
+	for _, tt := range tests {
+		if got := tt.s.Active(now, tt.network); got != tt.want {
+			t.Errorf("%s: Active = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestPathForFollowsConfig(t *testing.T) {
+	got := PathFor(filepath.Join("srv", "cumt", "config.yaml"))
+	if want := filepath.Join("srv", "cumt", "pause.json"); got != want {
+		t.Errorf("PathFor = %q, want %q", got, want)
+	}
+}
+
+func TestCheckClearsExpiredSnooze(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "pause.json")
+	if err := Snooze(path, time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	if _, paused := Check(path, time.Now()); !paused {
+		t.Fatal("snooze not active")
+	}
+	if _, paused := Check(path, time.Now().Add(2*time.Hour)); paused {
+		t.Fatal("expired snooze still active")
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("expired pause file left behind: %v", err)
+	}
+
+	if err := Pause(path); err != nil {
+		t.Fatal(err)
+	}
+	if _, paused := Check(path, time.Now().AddDate(1, 0, 0)); !paused {
+		t.Error("manual pause expired")
+	}
+	if err := Resume(path); err != nil {
+		t.Fatal(err)
+	}
+	if _, paused := Check(path, time.Now()); paused {
+		t.Error("still paused after Resume")
+	}
+}
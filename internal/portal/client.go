@@ -0,0 +1,225 @@
+package portal
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"CUMT-autologin/internal/config"
+	"CUMT-autologin/internal/netbind"
+)
+
+const (
+	defaultTimeout   = 5 * time.Second
+	defaultUserAgent = "Mozilla/5.0 (campus-netlogin-win)"
+	maxBodySize      = 8192
+)
+
+// Options controls how the portal package talks to the gateway. The zero
+// value (and a nil *Options) behaves like the historical defaults: a 5s
+// timeout per request and the proxy from the environment.
+// An Options must not be modified after its first use.
+type Options struct {
+	// Client is used when set; Transport, TLSConfig, Proxy, DialContext and
+	// Binding are then ignored. Jar, or a login flow's jar, is filled in only
+	// if Client has none, and Recorder still wraps its transport.
+	Client *http.Client
+	// Transport replaces the default transport, e.g. a Replayer in tests.
+	Transport http.RoundTripper
+	// Timeout bounds each request; the caller's context can cancel earlier.
+	Timeout time.Duration
+	// UserAgent is sent unless the portal config sets its own header.
+	UserAgent string
+	// TLSConfig is used by the default transport, e.g. to trust a campus CA.
+	TLSConfig *tls.Config
+	// Proxy selects the proxy per request; nil uses HTTP_PROXY and friends.
+	// Use Direct to always bypass proxies.
+	Proxy func(*http.Request) (*url.URL, error)
+	// Jar keeps cookies between requests, required by multi-step portals.
+	Jar http.CookieJar
+	// DialContext replaces the default dialer.
+	DialContext func(ctx context.Context, network, addr string) (net.Conn, error)
+	// Binding pins requests to an interface / source address unless
+	// DialContext is set; it also selects the addresses sent in dual-stack logins.
+	Binding netbind.Binding
+	// Recorder, when set, records every exchange, whatever the transport.
+	Recorder *Recorder
+
+	once   sync.Once
+	client *http.Client
+}
+
+// Direct is a proxy policy that never uses a proxy.
+func Direct(*http.Request) (*url.URL, error) { return nil, nil }
+
+var defaultOptions = &Options{}
+
+// NewOptions returns options for the network section of cfg: requests
+// leave through the configured interface / source address and bypass proxies
+// unless use_system_proxy is set. A recording masks the Secrets of cfg.
+func NewOptions(cfg *config.Config) *Options {
+	n := cfg.Network
+	o := &Options{}
+	if !n.UseSystemProxy {
+		o.Proxy = Direct
+	}
+	o.Binding = netbind.FromConfig(n)
+	if n.Record != "" {
+		o.Recorder = NewRecorder(n.Record, Secrets(cfg)...)
+	}
+	return o
+}
+
+// OptionsCache hands out Options for the current network section and only
+// rebuilds them when it changes, so connections are reused between ticks
+// even though the config is reloaded every time.
+type OptionsCache struct {
+	mu   sync.Mutex
+	key  config.NetworkConfig
+	opts *Options
+}
+
+// Get returns the options for cfg, keeping a recording's secrets current.
+func (c *OptionsCache) Get(cfg *config.Config) *Options {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.opts == nil || c.key != cfg.Network {
+		c.key, c.opts = cfg.Network, NewOptions(cfg)
+	} else if c.opts.Recorder != nil {
+		c.opts.Recorder.SetSecrets(Secrets(cfg)...)
+	}
+	return c.opts
+}
+
+func (o *Options) orDefault() *Options {
+	if o == nil {
+		return defaultOptions
+	}
+	return o
+}
+
+func (o *Options) timeout() time.Duration {
+	if o.Timeout > 0 {
+		return o.Timeout
+	}
+	return defaultTimeout
+}
+
+func (o *Options) userAgent() string {
+	if o.UserAgent != "" {
+		return o.UserAgent
+	}
+	return defaultUserAgent
+}
+
+// httpClient returns the client built from o, creating it on first use so
+// that connections are reused across calls.
+func (o *Options) httpClient() *http.Client {
+	o.once.Do(func() {
+		o.client = o.newClient(o.Jar)
+	})
+	return o.client
+}
+
+// newClient builds a client with the given cookie jar.
+func (o *Options) newClient(jar http.CookieJar) *http.Client {
+	if o.Client != nil {
+		c := *o.Client
+		if c.Jar == nil {
+			c.Jar = jar
+		}
+		if o.Recorder != nil {
+			c.Transport = o.Recorder.Transport(c.Transport)
+		}
+		return &c
+	}
+	rt := o.Transport
+	if rt == nil {
+		proxy := o.Proxy
+		if proxy == nil {
+			proxy = http.ProxyFromEnvironment
+		}
+		dial := o.DialContext
+		if dial == nil && !o.Binding.IsZero() {
+			dial = o.Binding.DialContext
+		}
+		if dial == nil {
+			dial = (&net.Dialer{Timeout: o.timeout(), KeepAlive: 30 * time.Second}).DialContext
+		}
+		rt = &http.Transport{
+			Proxy:                 proxy,
+			DialContext:           dial,
+			TLSClientConfig:       o.TLSConfig,
+			MaxIdleConns:          10,
+			IdleConnTimeout:       90 * time.Second,
+			TLSHandshakeTimeout:   o.timeout(),
+			ExpectContinueTimeout: time.Second,
+		}
+	}
+	if o.Recorder != nil {
+		rt = o.Recorder.Transport(rt)
+	}
+	return &http.Client{Transport: rt, Jar: jar}
+}
+
+// do sends req with the default headers and returns the status code and at
+// most maxBodySize bytes of the response body.
+func (o *Options) do(ctx context.Context, req *http.Request, headers map[string]string) (int, string, error) {
+	r, err := o.send(ctx, req, headers)
+	if r == nil {
+		return 0, "", err
+	}
+	return r.Status, r.Body, err
+}
+
+// send is do returning the whole Reply; it is nil when no response arrived.
+func (o *Options) send(ctx context.Context, req *http.Request, headers map[string]string) (*Reply, error) {
+	return o.sendVia(ctx, o.httpClient(), req, headers)
+}
+
+// sendVia is send through client, e.g. one holding a login flow's cookies.
+func (o *Options) sendVia(ctx context.Context, client *http.Client, req *http.Request, headers map[string]string) (*Reply, error) {
+	ctx, cancel := context.WithTimeout(ctx, o.timeout())
+	defer cancel()
+	req = req.WithContext(ctx)
+	o.setHeaders(req, headers)
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	r := &Reply{Status: resp.StatusCode, URL: resp.Request.URL.String()}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxBodySize))
+	if err != nil {
+		return r, err
+	}
+	r.Body = string(body)
+	return r, nil
+}
+
+// setHeaders adds the User-Agent and then the configured headers to req.
+func (o *Options) setHeaders(req *http.Request, headers map[string]string) {
+	req.Header.Set("User-Agent", o.userAgent())
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+}
+
+// get performs a GET request; non-200 replies are returned with an error.
+func (o *Options) get(ctx context.Context, target string, headers map[string]string) (string, error) {
+	req, err := http.NewRequest(http.MethodGet, target, nil)
+	if err != nil {
+		return "", err
+	}
+	code, body, err := o.do(ctx, req, headers)
+	if err == nil && code != http.StatusOK {
+		err = fmt.Errorf("portal: http %d", code)
+	}
+	return body, err
+}
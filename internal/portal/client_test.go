@@ -0,0 +1,174 @@
+package portal
+
+import (
+	"context"
+	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSetHeadersUserAgent(t *testing.T) {
+	var got http.Header
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Clone()
+	}))
+	defer srv.Close()
+
+	opts := &Options{}
+	if _, err := opts.get(context.Background(), srv.URL, nil); err != nil {
+		t.Fatal(err)
+	}
+	if ua := got.Get("User-Agent"); ua != defaultUserAgent {
+		t.Errorf("User-Agent = %q", ua)
+	}
+	opts = &Options{UserAgent: "custom"}
+	if _, err := opts.get(context.Background(), srv.URL, map[string]string{"X-Portal": "1"}); err != nil {
+		t.Fatal(err)
+	}
+	if got.Get("User-Agent") != "custom" || got.Get("X-Portal") != "1" {
+		t.Errorf("headers = %v", got)
+	}
+	// A configured User-Agent header wins over the option.
+	if _, err := opts.get(context.Background(), srv.URL, map[string]string{"User-Agent": "portal"}); err != nil {
+		t.Fatal(err)
+	}
+	if ua := got.Get("User-Agent"); ua != "portal" {
+		t.Errorf("User-Agent = %q", ua)
+	}
+}
+
+func TestGetRejectsNon200AndLimitsBody(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(strings.Repeat("x", 3*maxBodySize)))
+	}))
+	defer srv.Close()
+
+	opts := &Options{}
+	body, err := opts.get(context.Background(), srv.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(body) != maxBodySize {
+		t.Errorf("body is %d bytes, want %d", len(body), maxBodySize)
+	}
+	if _, err := opts.get(context.Background(), srv.URL+"/missing", nil); err == nil || !strings.Contains(err.Error(), "http 404") {
+		t.Errorf("err = %v", err)
+	}
+}
+
+func TestTimeout(t *testing.T) {
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer srv.Close()
+	defer close(release)
+
+	opts := &Options{Timeout: 50 * time.Millisecond}
+	start := time.Now()
+	if _, err := opts.get(context.Background(), srv.URL, nil); err == nil {
+		t.Fatal("slow gateway did not time out")
+	}
+	if d := time.Since(start); d > 2*time.Second {
+		t.Errorf("timed out after %v", d)
+	}
+}
+
+func TestProxyOption(t *testing.T) {
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("direct"))
+	}))
+	defer target.Close()
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// A forward proxy sees the absolute target URL.
+		w.Write([]byte("proxied " + r.URL.String()))
+	}))
+	defer proxy.Close()
+	proxyURL, _ := url.Parse(proxy.URL)
+
+	opts := &Options{Proxy: http.ProxyURL(proxyURL)}
+	body, err := opts.get(context.Background(), target.URL+"/a", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if body != "proxied "+target.URL+"/a" {
+		t.Errorf("body = %q", body)
+	}
+	opts = &Options{Proxy: Direct}
+	if body, _ := opts.get(context.Background(), target.URL, nil); body != "direct" {
+		t.Errorf("Direct body = %q", body)
+	}
+}
+
+func TestClientReusedAndJar(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/set" {
+			http.SetCookie(w, &http.Cookie{Name: "sid", Value: "abc", Path: "/"})
+			return
+		}
+		c, err := r.Cookie("sid")
+		if err != nil {
+			w.Write([]byte("none"))
+			return
+		}
+		w.Write([]byte(c.Value))
+	}))
+	defer srv.Close()
+	ctx := context.Background()
+
+	jar, _ := cookiejar.New(nil)
+	opts := &Options{Jar: jar}
+	if opts.httpClient() != opts.httpClient() {
+		t.Error("client rebuilt between calls")
+	}
+	opts.get(ctx, srv.URL+"/set", nil)
+	if body, _ := opts.get(ctx, srv.URL+"/get", nil); body != "abc" {
+		t.Errorf("cookie not kept by Jar: %q", body)
+	}
+
+	// A caller's Client without a jar gets Jar filled in ...
+	jar, _ = cookiejar.New(nil)
+	opts = &Options{Client: &http.Client{}, Jar: jar}
+	opts.get(ctx, srv.URL+"/set", nil)
+	if body, _ := opts.get(ctx, srv.URL+"/get", nil); body != "abc" {
+		t.Errorf("Jar ignored with Client set: %q", body)
+	}
+	// ... but its own jar is kept.
+	own, _ := cookiejar.New(nil)
+	opts = &Options{Client: &http.Client{Jar: own}, Jar: jar}
+	if c := opts.httpClient(); c.Jar != own {
+		t.Error("Client's jar replaced")
+	}
+}
+
+func TestClientProxyIgnored(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	defer srv.Close()
+	called := false
+	opts := &Options{
+		Client: srv.Client(),
+		Proxy: func(*http.Request) (*url.URL, error) {
+			called = true
+			return nil, nil
+		},
+	}
+	if body, err := opts.get(context.Background(), srv.URL, nil); err != nil || body != "ok" {
+		t.Fatalf("get = %q, %v", body, err)
+	}
+	if called {
+		t.Error("Proxy consulted although Client is set")
+	}
+}
@@ -0,0 +1,69 @@
+package portal
+
+import (
+	"net"
+	"strings"
+
+	"CUMT-autologin/internal/config"
+	"CUMT-autologin/internal/netbind"
+)
+
+// Form values may reference the local addresses with these placeholders,
+// e.g. "wlan_user_ipv6: {ipv6}" for a generic dual-stack gateway.
+const (
+	placeholderIPv4 = "{ipv4}"
+	placeholderIPv6 = "{ipv6}"
+)
+
+// withAddresses returns cfg with the local addresses filled in: placeholders
+// are always expanded, and with dual_stack the driver's address fields are
+// set unless configured explicitly. cfg itself is not modified.
+func withAddresses(cfg *config.PortalConfig, b netbind.Binding) *config.PortalConfig {
+	needed := cfg.DualStack
+	for _, v := range cfg.Form {
+		if strings.Contains(v, placeholderIPv4) || strings.Contains(v, placeholderIPv6) {
+			needed = true
+			break
+		}
+	}
+	if !needed {
+		return cfg
+	}
+
+	v4, v6 := b.SourceAddrs()
+	out := *cfg
+	out.Form = make(map[string]string, len(cfg.Form)+2)
+	for k, v := range cfg.Form {
+		v = strings.ReplaceAll(v, placeholderIPv4, ipString(v4))
+		out.Form[k] = strings.ReplaceAll(v, placeholderIPv6, ipString(v6))
+	}
+	if !cfg.DualStack {
+		return &out
+	}
+
+	setDefault := func(key, value string) {
+		if out.Form[key] == "" && value != "" {
+			out.Form[key] = value
+		}
+	}
+	switch Driver(cfg) {
+	case DriverDrcom:
+		setDefault("wlan_user_ip", ipString(v4))
+		setDefault("wlan_user_ipv6", ipString(v6))
+	case DriverSrun:
+		setDefault("ip", ipString(v4))
+		if v6 != nil {
+			setDefault("double_stack", "1")
+		} else {
+			setDefault("double_stack", "0")
+		}
+	}
+	return &out
+}
+
+func ipString(ip net.IP) string {
+	if ip == nil {
+		return ""
+	}
+	return ip.String()
+}
@@ -0,0 +1,219 @@
+package portal
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"CUMT-autologin/internal/config"
+	"CUMT-autologin/internal/fakeportal"
+)
+
+// newFake starts a fake portal and returns it with a portal section and
+// options pointing at it.
+func newFake(t *testing.T, fo fakeportal.Options) (*fakeportal.Server, *config.PortalConfig, *Options) {
+	t.Helper()
+	fo.Account, fo.Password = "08123456", "secret"
+	srv, ts := fakeportal.NewTestServer(fo)
+	t.Cleanup(ts.Close)
+	cfg := srv.PortalConfig(ts.URL)
+	return srv, &cfg, &Options{Proxy: Direct}
+}
+
+func TestFakeLoginFailures(t *testing.T) {
+	want := map[string]map[string]string{
+		fakeportal.DriverDrcom: {
+			fakeportal.FailPassword:    "密码错误",
+			fakeportal.FailNoAccount:   "账号不存在",
+			fakeportal.FailArrears:     "账号状态异常（可能已欠费）",
+			fakeportal.FailDeviceLimit: ReasonDeviceLimit,
+		},
+		fakeportal.DriverSrun: {
+			fakeportal.FailPassword:    "密码错误",
+			fakeportal.FailNoAccount:   "账号不存在",
+			fakeportal.FailArrears:     "账号状态异常（可能已欠费）",
+			fakeportal.FailDeviceLimit: ReasonDeviceLimit,
+		},
+	}
+	ctx := context.Background()
+	for driver, reasons := range want {
+		for fail, reason := range reasons {
+			t.Run(driver+"/"+fail, func(t *testing.T) {
+				srv, cfg, opts := newFake(t, fakeportal.Options{Driver: driver})
+				srv.SetFault(fakeportal.Fault{Login: fail, Times: 1})
+				reply, err := Submit(ctx, cfg, opts)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if err := CheckReply(reply, cfg); !errors.Is(err, ErrNotAccepted) {
+					t.Errorf("CheckReply = %v", err)
+				}
+				if got := FailureReason(reply.Body); got != reason {
+					t.Errorf("FailureReason = %q, want %q", got, reason)
+				}
+				if got := IsDeviceLimit(reply.Body); got != (fail == fakeportal.FailDeviceLimit) {
+					t.Errorf("IsDeviceLimit = %v", got)
+				}
+				if len(srv.Sessions()) != 0 {
+					t.Error("failed login opened a session")
+				}
+			})
+		}
+	}
+}
+
+func TestFakeWrongPassword(t *testing.T) {
+	_, cfg, opts := newFake(t, fakeportal.Options{})
+	cfg.Form["user_password"] = "wrong"
+	reply, err := Submit(context.Background(), cfg, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if CheckReply(reply, cfg) == nil || FailureReason(reply.Body) != "密码错误" {
+		t.Errorf("reply = %s", reply.Body)
+	}
+}
+
+func TestFakeSessionLifecycle(t *testing.T) {
+	ctx := context.Background()
+	for _, driver := range []string{fakeportal.DriverDrcom, fakeportal.DriverSrun} {
+		t.Run(driver, func(t *testing.T) {
+			_, cfg, opts := newFake(t, fakeportal.Options{Driver: driver, UsedBytes: 3 << 30, Balance: 12.5})
+			reply, err := Submit(ctx, cfg, opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := CheckReply(reply, cfg); err != nil {
+				t.Fatalf("CheckReply = %v, body %s", err, reply.Body)
+			}
+
+			info, err := Status(ctx, cfg, opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !info.Online || info.Account != "08123456" || !info.HasBalance || info.Balance != 12.5 {
+				t.Errorf("status = %+v", info)
+			}
+			if info.UsedBytes < 3<<30-1024 || info.UsedBytes > 3<<30 {
+				t.Errorf("used bytes = %d", info.UsedBytes)
+			}
+
+			res, err := Logout(ctx, cfg, opts)
+			if err != nil || !res.OK {
+				t.Fatalf("Logout = %+v, %v", res, err)
+			}
+			if info, err := Status(ctx, cfg, opts); err != nil || info.Online {
+				t.Errorf("status after logout = %+v, %v", info, err)
+			}
+			if res, err := Logout(ctx, cfg, opts); err != nil || res.OK {
+				t.Errorf("second logout = %+v, %v", res, err)
+			}
+		})
+	}
+}
+
+func TestFakeUnbindMAC(t *testing.T) {
+	ctx := context.Background()
+	srv, cfg, opts := newFake(t, fakeportal.Options{})
+	cfg.Form["wlan_user_mac"] = "AA-BB-CC-DD-EE-FF"
+	if _, err := Submit(ctx, cfg, opts); err != nil {
+		t.Fatal(err)
+	}
+	if s := srv.Sessions(); len(s) != 1 || s[0].MAC != "aabbccddeeff" {
+		t.Fatalf("sessions = %+v", s)
+	}
+	res, err := UnbindMAC(ctx, cfg, "aa:bb:cc:dd:ee:ff", opts)
+	if err != nil || !res.OK {
+		t.Fatalf("UnbindMAC = %+v, %v", res, err)
+	}
+	if s := srv.Sessions(); len(s) != 0 {
+		t.Errorf("sessions after unbind = %+v", s)
+	}
+
+	_, srunCfg, _ := newFake(t, fakeportal.Options{Driver: fakeportal.DriverSrun})
+	if _, err := UnbindMAC(ctx, srunCfg, "", opts); !errors.Is(err, ErrUnbindUnsupported) {
+		t.Errorf("srun UnbindMAC = %v", err)
+	}
+}
+
+func TestFakeHTTPStatus(t *testing.T) {
+	ctx := context.Background()
+	srv, cfg, opts := newFake(t, fakeportal.Options{})
+	srv.SetFault(fakeportal.Fault{HTTPStatus: 502})
+
+	reply, err := Submit(ctx, cfg, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reply.Status != 502 || CheckReply(reply, cfg) == nil {
+		t.Errorf("reply = %+v", reply)
+	}
+	if _, err := Status(ctx, cfg, opts); err == nil {
+		t.Error("Status ignored http 502")
+	}
+	if _, err := Logout(ctx, cfg, opts); err == nil {
+		t.Error("Logout ignored http 502")
+	}
+	if len(srv.Sessions()) != 0 {
+		t.Error("session opened behind a 502")
+	}
+}
+
+func TestFakeDelayVsTimeout(t *testing.T) {
+	ctx := context.Background()
+	srv, cfg, _ := newFake(t, fakeportal.Options{})
+
+	srv.SetFault(fakeportal.Fault{Delay: time.Second, Times: 1})
+	short := &Options{Proxy: Direct, Timeout: 100 * time.Millisecond}
+	if _, err := Submit(ctx, cfg, short); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Submit past the timeout = %v", err)
+	}
+
+	srv.SetFault(fakeportal.Fault{Delay: 100 * time.Millisecond, Times: 1})
+	long := &Options{Proxy: Direct, Timeout: 2 * time.Second}
+	reply, err := Submit(ctx, cfg, long)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := CheckReply(reply, cfg); err != nil {
+		t.Errorf("delayed login = %v", err)
+	}
+}
+
+func TestFakeIdleTimeout(t *testing.T) {
+	var mu sync.Mutex
+	now := time.Date(2024, 3, 1, 8, 0, 0, 0, time.Local)
+	clock := func() time.Time {
+		mu.Lock()
+		defer mu.Unlock()
+		return now
+	}
+	advance := func(d time.Duration) {
+		mu.Lock()
+		now = now.Add(d)
+		mu.Unlock()
+	}
+	ctx := context.Background()
+	_, cfg, opts := newFake(t, fakeportal.Options{IdleTimeout: 10 * time.Minute, Now: clock})
+
+	if _, err := Submit(ctx, cfg, opts); err != nil {
+		t.Fatal(err)
+	}
+	// Each status query counts as activity and keeps the session alive.
+	for i := 0; i < 3; i++ {
+		advance(8 * time.Minute)
+		info, err := Status(ctx, cfg, opts)
+		if err != nil || !info.Online {
+			t.Fatalf("status after %d checks = %+v, %v", i+1, info, err)
+		}
+	}
+	if info, _ := Status(ctx, cfg, opts); info.OnlineSeconds != 24*60 {
+		t.Errorf("online seconds = %d", info.OnlineSeconds)
+	}
+	advance(11 * time.Minute)
+	if info, err := Status(ctx, cfg, opts); err != nil || info.Online {
+		t.Errorf("status after idle timeout = %+v, %v", info, err)
+	}
+}
@@ -0,0 +1,208 @@
+package portal
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"io"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"CUMT-autologin/internal/config"
+)
+
+// maxPageSize bounds the login page read by a flow; ASP.NET view state
+// easily exceeds the reply limit.
+const maxPageSize = 1 << 20
+
+var (
+	formRe  = regexp.MustCompile(`(?is)<form\b([^>]*)>(.*?)</form>`)
+	inputRe = regexp.MustCompile(`(?is)<input\b([^>]*)>`)
+	attrRe  = regexp.MustCompile(`(?s)([a-zA-Z_:][-a-zA-Z0-9_:.]*)\s*=\s*(?:"([^"]*)"|'([^']*)'|([^\s"'>]+))`)
+)
+
+// htmlForm is a parsed <form>: its attributes and the name/value of every
+// input with its type.
+type htmlForm struct {
+	attrs  map[string]string
+	inputs []htmlInput
+}
+
+type htmlInput struct {
+	typ, name, value string
+}
+
+// session is what the page step leaves to the rest of a login: the client
+// carrying its cookies and the page it fetched.
+type session struct {
+	client  *http.Client
+	pageURL string // as configured
+	page    string
+}
+
+// get fetches target through the session's client; the login page itself is
+// not fetched again, as that may rotate its tokens.
+func (s *session) get(ctx context.Context, opts *Options, target string, headers map[string]string) (string, error) {
+	if s.page != "" && target == s.pageURL {
+		return s.page, nil
+	}
+	page, _, err := fetchPage(ctx, s.client, opts, target, headers)
+	return page, err
+}
+
+// runFlow performs the page step of cfg.Flow. It returns cfg with the page's
+// fields merged under the configured form, and a session whose client
+// carries the cookies for the submit. When no flow is configured it returns
+// cfg and a session on the shared client.
+func runFlow(ctx context.Context, cfg *config.PortalConfig, opts *Options) (*config.PortalConfig, *session, error) {
+	f := cfg.Flow
+	if f.PageURL == "" {
+		return cfg, &session{client: opts.httpClient()}, nil
+	}
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	client := opts.newClient(jar)
+	page, pageURL, err := fetchPage(ctx, client, opts, f.PageURL, cfg.Headers)
+	if err != nil {
+		return nil, nil, fmt.Errorf("portal: fetch login page: %w", err)
+	}
+
+	fields := make(map[string]string)
+	form := pickForm(parseForms(page), f.Form)
+	if form == nil && f.Form != "" {
+		return nil, nil, fmt.Errorf("portal: flow.form %q not found on %s", f.Form, pageURL)
+	}
+	if form != nil {
+		for _, in := range form.inputs {
+			if in.name == "" || !strings.EqualFold(in.typ, "hidden") {
+				continue
+			}
+			if len(f.Fields) == 0 || containsString(f.Fields, in.name) {
+				fields[in.name] = in.value
+			}
+		}
+	}
+	for name, expr := range f.Extract {
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return nil, nil, fmt.Errorf("portal: flow.extract %s: %w", name, err)
+		}
+		m := re.FindStringSubmatch(pageURL.String() + "\n" + page)
+		switch {
+		case len(m) > 1:
+			fields[name] = m[1]
+		case m != nil:
+			fields[name] = m[0]
+		}
+	}
+	if f.QueryField != "" {
+		fields[f.QueryField] = pageURL.RawQuery
+	}
+
+	out := *cfg
+	out.Form = make(map[string]string, len(fields)+len(cfg.Form))
+	for k, v := range fields {
+		out.Form[k] = v
+	}
+	for k, v := range cfg.Form {
+		out.Form[k] = v
+	}
+	if f.UseAction && form != nil {
+		action, err := pageURL.Parse(form.attrs["action"])
+		if err != nil {
+			return nil, nil, fmt.Errorf("portal: form action: %w", err)
+		}
+		out.LoginURL = action.String()
+	}
+	return &out, &session{client: client, pageURL: f.PageURL, page: page}, nil
+}
+
+// fetchPage GETs target with client and returns the body and the URL it
+// ended at after redirects.
+func fetchPage(ctx context.Context, client *http.Client, opts *Options, target string, headers map[string]string) (string, *url.URL, error) {
+	ctx, cancel := context.WithTimeout(ctx, opts.timeout())
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+	if err != nil {
+		return "", nil, err
+	}
+	opts.setHeaders(req, headers)
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", nil, fmt.Errorf("http %d", resp.StatusCode)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxPageSize))
+	if err != nil {
+		return "", nil, err
+	}
+	return string(data), resp.Request.URL, nil
+}
+
+// parseForms finds the forms of an HTML page. It is a tolerant scan, not a
+// parser: enough for the flat login forms portals serve.
+func parseForms(page string) []htmlForm {
+	var forms []htmlForm
+	for _, m := range formRe.FindAllStringSubmatch(page, -1) {
+		form := htmlForm{attrs: parseAttrs(m[1])}
+		for _, in := range inputRe.FindAllStringSubmatch(m[2], -1) {
+			a := parseAttrs(in[1])
+			typ := a["type"]
+			if typ == "" {
+				typ = "text"
+			}
+			form.inputs = append(form.inputs, htmlInput{typ: typ, name: a["name"], value: a["value"]})
+		}
+		forms = append(forms, form)
+	}
+	return forms
+}
+
+func parseAttrs(s string) map[string]string {
+	attrs := make(map[string]string)
+	for _, m := range attrRe.FindAllStringSubmatch(s, -1) {
+		attrs[strings.ToLower(m[1])] = html.UnescapeString(m[2] + m[3] + m[4])
+	}
+	return attrs
+}
+
+// pickForm returns the form with the given id or name, or else the first
+// one with a password input, or else the first one.
+func pickForm(forms []htmlForm, want string) *htmlForm {
+	if want != "" {
+		for i := range forms {
+			if forms[i].attrs["id"] == want || forms[i].attrs["name"] == want {
+				return &forms[i]
+			}
+		}
+		return nil
+	}
+	for i := range forms {
+		for _, in := range forms[i].inputs {
+			if strings.EqualFold(in.typ, "password") {
+				return &forms[i]
+			}
+		}
+	}
+	if len(forms) > 0 {
+		return &forms[0]
+	}
+	return nil
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,202 @@
+package portal
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"CUMT-autologin/internal/config"
+)
+
+const flowPage = `<html><body>
+<form action="/search" method="get"><input name="q"></form>
+<FORM id="loginForm" name='lf' method=post action="/auth?step=2">
+  <input type="hidden" name="__VIEWSTATE" value="dDw&amp;xNz==" />
+  <input type='hidden' name='csrf' value='%s'>
+  <input name=user_account>
+  <input type="password" name="user_password" value="">
+  <input type="checkbox" name="remember" checked>
+</FORM>
+<script>var publicKeyExponent="10001";var publicKeyModulus="%s";</script>
+</body></html>`
+
+func TestParseForms(t *testing.T) {
+	forms := parseForms(fmt.Sprintf(flowPage, "tok", "00"))
+	if len(forms) != 2 {
+		t.Fatalf("%d forms", len(forms))
+	}
+	f := forms[1]
+	for k, want := range map[string]string{"id": "loginForm", "name": "lf", "method": "post", "action": "/auth?step=2"} {
+		if f.attrs[k] != want {
+			t.Errorf("attr %s = %q, want %q", k, f.attrs[k], want)
+		}
+	}
+	want := []htmlInput{
+		{"hidden", "__VIEWSTATE", "dDw&xNz=="},
+		{"hidden", "csrf", "tok"},
+		{"text", "user_account", ""},
+		{"password", "user_password", ""},
+		{"checkbox", "remember", ""},
+	}
+	if len(f.inputs) != len(want) {
+		t.Fatalf("inputs = %+v", f.inputs)
+	}
+	for i := range want {
+		if f.inputs[i] != want[i] {
+			t.Errorf("input %d = %+v, want %+v", i, f.inputs[i], want[i])
+		}
+	}
+	if got := parseForms("<html>no forms</html>"); got != nil {
+		t.Errorf("parseForms without forms = %+v", got)
+	}
+}
+
+func TestPickForm(t *testing.T) {
+	forms := parseForms(fmt.Sprintf(flowPage, "tok", "00"))
+	cases := []struct {
+		want string
+		id   string
+	}{
+		{"", "loginForm"}, // the one with a password input
+		{"loginForm", "loginForm"},
+		{"lf", "loginForm"},
+		{"missing", ""},
+	}
+	for _, c := range cases {
+		f := pickForm(forms, c.want)
+		switch {
+		case c.id == "" && f != nil:
+			t.Errorf("pickForm(%q) = %v, want nil", c.want, f.attrs)
+		case c.id != "" && (f == nil || f.attrs["id"] != c.id):
+			t.Errorf("pickForm(%q) = %v, want #%s", c.want, f, c.id)
+		}
+	}
+	if f := pickForm(forms[:1], ""); f == nil || f.attrs["action"] != "/search" {
+		t.Errorf("without a password input: %v, want the first form", f)
+	}
+	if f := pickForm(nil, ""); f != nil {
+		t.Errorf("no forms: %v", f)
+	}
+}
+
+// flowServer is a form portal that binds the hidden token and the RSA key
+// page to the session cookie set by the login page.
+type flowServer struct {
+	t         *testing.T
+	key       *rsa.PrivateKey
+	pageHits  atomic.Int32
+	keyHits   atomic.Int32
+	logins    atomic.Int32
+	sessionID atomic.Int32
+}
+
+func (s *flowServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.URL.Path {
+	case "/login":
+		s.pageHits.Add(1)
+		sid := fmt.Sprint(s.sessionID.Add(1))
+		http.SetCookie(w, &http.Cookie{Name: "sid", Value: sid, Path: "/"})
+		fmt.Fprintf(w, flowPage, "csrf-"+sid, s.key.N.Text(16))
+	case "/key":
+		s.keyHits.Add(1)
+		if c, err := r.Cookie("sid"); err != nil || c.Value == "" {
+			http.Error(w, "no session", http.StatusForbidden)
+			return
+		}
+		fmt.Fprintf(w, `{"modulus":"%s"}`, s.key.N.Text(16))
+	case "/auth":
+		s.logins.Add(1)
+		c, err := r.Cookie("sid")
+		if err != nil {
+			http.Error(w, "no session", http.StatusForbidden)
+			return
+		}
+		r.ParseForm()
+		if r.URL.Query().Get("step") != "2" || r.PostForm.Get("csrf") != "csrf-"+c.Value || r.PostForm.Get("__VIEWSTATE") != "dDw&xNz==" {
+			s.t.Errorf("auth: sid=%s query=%s form=%v", c.Value, r.URL.RawQuery, r.PostForm)
+			fmt.Fprint(w, `{"result":0,"msg":"token"}`)
+			return
+		}
+		ct, _ := base64.StdEncoding.DecodeString(r.PostForm.Get("user_password"))
+		pw, err := rsa.DecryptPKCS1v15(rand.Reader, s.key, ct)
+		if err != nil || string(pw) != "hunter22" || r.PostForm.Get("user_account") != "08123456" {
+			fmt.Fprint(w, `{"result":0,"msg":"password"}`)
+			return
+		}
+		fmt.Fprint(w, `{"result":1}`)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func flowConfig(base string) *config.PortalConfig {
+	return &config.PortalConfig{
+		LoginURL: base + "/unused",
+		Method:   "POST",
+		Form:     map[string]string{"user_account": "08123456", "user_password": "hunter22"},
+		PasswordEncoding: config.PasswordEncoding{
+			Scheme:    SchemeRSA,
+			RSAKeyURL: base + "/login",
+		},
+		Flow: config.LoginFlow{PageURL: base + "/login", UseAction: true},
+	}
+}
+
+func TestFlowCookieRoundTrip(t *testing.T) {
+	fs := &flowServer{t: t, key: testKey(t)}
+	srv := httptest.NewServer(fs)
+	defer srv.Close()
+	ctx := context.Background()
+
+	// The key is on the login page itself: it must not be fetched twice,
+	// which would start a new session.
+	r, err := Submit(ctx, flowConfig(srv.URL), &Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Body != `{"result":1}` {
+		t.Errorf("reply = %s", r.Body)
+	}
+	if n := fs.pageHits.Load(); n != 1 {
+		t.Errorf("login page fetched %d times", n)
+	}
+
+	// A separate key page is fetched with the session cookie.
+	cfg := flowConfig(srv.URL)
+	cfg.PasswordEncoding.RSAKeyURL = srv.URL + "/key"
+	if r, err = Submit(ctx, cfg, &Options{}); err != nil {
+		t.Fatal(err)
+	}
+	if r.Body != `{"result":1}` {
+		t.Errorf("reply = %s", r.Body)
+	}
+	if n := fs.keyHits.Load(); n != 1 {
+		t.Errorf("key page fetched %d times", n)
+	}
+	if n := fs.logins.Load(); n != 2 {
+		t.Errorf("%d logins, want 2", n)
+	}
+}
+
+func TestFlowMissingForm(t *testing.T) {
+	fs := &flowServer{t: t, key: testKey(t)}
+	srv := httptest.NewServer(fs)
+	defer srv.Close()
+
+	cfg := flowConfig(srv.URL)
+	cfg.Flow.Form = "portalForm"
+	_, err := Submit(context.Background(), cfg, &Options{})
+	if err == nil || !strings.Contains(err.Error(), `"portalForm"`) {
+		t.Fatalf("err = %v, want one naming the form", err)
+	}
+	if n := fs.logins.Load(); n != 0 {
+		t.Errorf("submitted %d times despite the missing form", n)
+	}
+}
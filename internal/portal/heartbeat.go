@@ -0,0 +1,46 @@
+package portal
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"CUMT-autologin/internal/config"
+)
+
+// HeartbeatURL returns the endpoint pinged to keep the session alive:
+// override when set, otherwise the status endpoint, otherwise the root of
+// login_url.
+func HeartbeatURL(cfg *config.PortalConfig, override string) (string, error) {
+	if override != "" {
+		return override, nil
+	}
+	target, err := StatusURL(cfg)
+	if errors.Is(err, ErrStatusUnsupported) {
+		return baseURL(cfg.LoginURL)
+	}
+	return target, err
+}
+
+// Heartbeat sends a lightweight GET that the gateway counts as activity of
+// this machine. Any HTTP reply is fine; only 5xx and transport errors fail.
+func Heartbeat(ctx context.Context, cfg *config.PortalConfig, override string, opts *Options) error {
+	opts = opts.orDefault()
+	target, err := HeartbeatURL(cfg, override)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodGet, target, nil)
+	if err != nil {
+		return err
+	}
+	code, _, err := opts.do(ctx, req, cfg.Headers)
+	if err != nil {
+		return err
+	}
+	if code >= http.StatusInternalServerError {
+		return fmt.Errorf("portal: heartbeat http %d", code)
+	}
+	return nil
+}
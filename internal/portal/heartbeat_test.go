@@ -0,0 +1,71 @@
+package portal
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"CUMT-autologin/internal/config"
+	"CUMT-autologin/internal/fakeportal"
+)
+
+func TestHeartbeatURL(t *testing.T) {
+	cases := []struct {
+		cfg      config.PortalConfig
+		override string
+		want     string
+	}{
+		{config.PortalConfig{LoginURL: "http://10.2.5.251:801/eportal/portal/login"}, "http://gw/hb", "http://gw/hb"},
+		{config.PortalConfig{LoginURL: "http://10.2.5.251:801/eportal/portal/login", StatusURL: "http://10.2.5.251/drcom/chkstatus"}, "", "http://10.2.5.251/drcom/chkstatus"},
+		{config.PortalConfig{LoginURL: "http://10.2.5.251:801/auth/login.php"}, "", "http://10.2.5.251:801"},
+	}
+	for _, c := range cases {
+		got, err := HeartbeatURL(&c.cfg, c.override)
+		if err != nil || got != c.want {
+			t.Errorf("HeartbeatURL(%s, %q) = %s, %v; want %s", c.cfg.LoginURL, c.override, got, err, c.want)
+		}
+	}
+}
+
+func TestFakeHeartbeatKeepsSession(t *testing.T) {
+	var mu sync.Mutex
+	now := time.Date(2024, 3, 1, 8, 0, 0, 0, time.Local)
+	clock := func() time.Time {
+		mu.Lock()
+		defer mu.Unlock()
+		return now
+	}
+	advance := func(d time.Duration) {
+		mu.Lock()
+		now = now.Add(d)
+		mu.Unlock()
+	}
+	ctx := context.Background()
+	srv, cfg, opts := newFake(t, fakeportal.Options{IdleTimeout: 10 * time.Minute, Now: clock})
+	if _, err := Submit(ctx, cfg, opts); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 3; i++ {
+		advance(8 * time.Minute)
+		if err := Heartbeat(ctx, cfg, "", opts); err != nil {
+			t.Fatalf("heartbeat %d: %v", i+1, err)
+		}
+	}
+	if len(srv.Sessions()) != 1 {
+		t.Fatalf("session expired despite heartbeats")
+	}
+	advance(11 * time.Minute)
+	if info, err := Status(ctx, cfg, opts); err != nil || info.Online {
+		t.Errorf("status without heartbeats = %+v, %v", info, err)
+	}
+
+	srv.SetFault(fakeportal.Fault{HTTPStatus: 503, Times: 1})
+	if err := Heartbeat(ctx, cfg, "", opts); err == nil {
+		t.Error("heartbeat ignored http 503")
+	}
+	srv.SetFault(fakeportal.Fault{HTTPStatus: 404, Times: 1})
+	if err := Heartbeat(ctx, cfg, "", opts); err != nil {
+		t.Errorf("heartbeat failed on http 404: %v", err)
+	}
+}
@@ -0,0 +1,481 @@
+package portal
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+
+	"CUMT-autologin/internal/config"
+)
+
+// ErrNoLoginRequest is returned when an import holds no request that
+// looks like a login.
+var ErrNoLoginRequest = errors.New("portal: no login request found")
+
+// Imported is a portal config generated from a browser capture.
+type Imported struct {
+	Portal config.PortalConfig `json:"portal"`
+	// Request is the method and URL of the request that was picked.
+	Request string `json:"request"`
+	// Notes lists what could not be inferred and should be checked.
+	Notes []string `json:"notes"`
+}
+
+// Import builds a portal config from a HAR file or a "Copy as cURL"
+// command, whichever text is.
+func Import(text string) (*Imported, error) {
+	text = strings.TrimSpace(strings.TrimPrefix(text, "\ufeff"))
+	if strings.HasPrefix(text, "{") {
+		var h HAR
+		if err := json.Unmarshal([]byte(text), &h); err != nil {
+			return nil, fmt.Errorf("portal: parse har: %w", err)
+		}
+		return ImportHAR(&h)
+	}
+	if strings.HasPrefix(strings.ToLower(text), "curl") {
+		return ImportCurl(text)
+	}
+	return nil, errors.New("portal: expected a HAR file or a curl command")
+}
+
+// ImportHAR picks the most login-like request of h. Its response, when
+// recorded, is used to infer the success keywords.
+func ImportHAR(h *HAR) (*Imported, error) {
+	best, bestScore := -1, 0
+	for i, e := range h.Log.Entries {
+		body := ""
+		if e.Request.PostData != nil {
+			body = e.Request.PostData.Text
+		}
+		if s := loginScore(e.Request.Method, e.Request.URL, body); s > bestScore {
+			best, bestScore = i, s
+		}
+	}
+	if best < 0 {
+		return nil, ErrNoLoginRequest
+	}
+	e := h.Log.Entries[best]
+	headers := make(map[string]string)
+	for _, hv := range e.Request.Headers {
+		headers[hv.Name] = hv.Value
+	}
+	body, mime := "", ""
+	if e.Request.PostData != nil {
+		body, mime = e.Request.PostData.Text, e.Request.PostData.MimeType
+	}
+	imp, err := importRequest(e.Request.Method, e.Request.URL, headers, body, mime)
+	if err != nil {
+		return nil, err
+	}
+	text, err := e.Response.Content.Decoded()
+	if err != nil {
+		return nil, err
+	}
+	if text != "" {
+		imp.Portal.SuccessKeywords = successKeywords(text)
+	}
+	if len(imp.Portal.SuccessKeywords) == 0 {
+		imp.Notes = append(imp.Notes, "无法从响应推断 success_keywords，请参照登录成功时的响应手动填写")
+	}
+	return imp, nil
+}
+
+// ImportCurl parses a "Copy as cURL" command, bash or cmd flavoured. A
+// command carries no response, so success_keywords are left to the user.
+func ImportCurl(cmd string) (*Imported, error) {
+	args, err := splitShell(cmd)
+	if err != nil {
+		return nil, err
+	}
+	method, target, body := "", "", ""
+	var data []string
+	forceGet := false
+	headers := make(map[string]string)
+	for i := 1; i < len(args); i++ {
+		a := args[i]
+		next := func() string {
+			if i+1 < len(args) {
+				i++
+				return args[i]
+			}
+			return ""
+		}
+		switch {
+		case a == "-X" || a == "--request":
+			method = strings.ToUpper(next())
+		case a == "-H" || a == "--header":
+			if k, v, ok := strings.Cut(next(), ":"); ok {
+				headers[strings.TrimSpace(k)] = strings.TrimSpace(v)
+			}
+		case a == "-d" || a == "--data" || a == "--data-raw" || a == "--data-binary" || a == "--data-ascii":
+			data = append(data, next())
+		case a == "--data-urlencode":
+			d := next()
+			if k, v, ok := strings.Cut(d, "="); ok {
+				d = k + "=" + url.QueryEscape(v)
+			}
+			data = append(data, d)
+		case a == "-A" || a == "--user-agent":
+			headers["User-Agent"] = next()
+		case a == "-b" || a == "--cookie" || a == "-u" || a == "--user" || a == "-o" || a == "--output":
+			next()
+		case a == "-e" || a == "--referer":
+			headers["Referer"] = next()
+		case a == "-G" || a == "--get":
+			forceGet = true
+		case a == "--url":
+			target = next()
+		case strings.HasPrefix(a, "-"):
+			// --compressed, --insecure, -L and friends change nothing we keep.
+		default:
+			if target == "" {
+				target = a
+			}
+		}
+	}
+	if target == "" {
+		return nil, errors.New("portal: curl command has no url")
+	}
+	body = strings.Join(data, "&")
+	if forceGet && body != "" {
+		sep := "?"
+		if strings.Contains(target, "?") {
+			sep = "&"
+		}
+		target, body = target+sep+body, ""
+	}
+	if method == "" {
+		method = http.MethodGet
+		if body != "" {
+			method = http.MethodPost
+		}
+	}
+	imp, err := importRequest(method, target, headers, body, headers["Content-Type"])
+	if err != nil {
+		return nil, err
+	}
+	imp.Notes = append(imp.Notes, "cURL 命令不含响应，请参照登录成功时的响应填写 success_keywords")
+	return imp, nil
+}
+
+// loginScore rates how much a request looks like the login one; 0 means
+// not at all.
+func loginScore(method, rawURL, body string) int {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return 0
+	}
+	p := strings.ToLower(u.Path)
+	switch path.Ext(p) {
+	case ".js", ".css", ".png", ".jpg", ".gif", ".svg", ".ico", ".woff", ".woff2", ".ttf":
+		return 0
+	}
+	lower := strings.ToLower(rawURL + "&" + body)
+	if strings.Contains(lower, "logout") || strings.Contains(lower, "a=unbind") {
+		return 0
+	}
+	score := 0
+	params := u.Query()
+	if v, err := url.ParseQuery(body); err == nil {
+		for k, vals := range v {
+			params[k] = append(params[k], vals...)
+		}
+	}
+	for k, vals := range params {
+		if isSecretField(k) && len(vals) > 0 && vals[0] != "" {
+			score += 10
+		}
+		if accountField(k) {
+			score += 3
+		}
+	}
+	for _, hint := range []string{"login", "srun_portal", "eportal", "auth"} {
+		if strings.Contains(lower, hint) {
+			score += 2
+		}
+	}
+	if score > 0 && strings.EqualFold(method, http.MethodPost) {
+		score++
+	}
+	return score
+}
+
+var accountFields = []string{"user_account", "username", "ddddd", "account", "user", "userid", "user_id", "uname", "login_name", "loginname"}
+
+func accountField(name string) bool {
+	n := strings.ToLower(name)
+	for _, f := range accountFields {
+		if n == f {
+			return true
+		}
+	}
+	return false
+}
+
+// volatileFields change on every request and must not be replayed.
+var volatileFields = map[string]bool{"_": true, "v": true, "t": true, "timestamp": true, "jsVersion": true}
+
+// sessionFields are per-session tokens a login flow must fetch afresh.
+var sessionFields = regexp.MustCompile(`(?i)^(__viewstate\w*|__eventvalidation|_?csrf\w*|\w*_token|lt|execution)$`)
+
+// accountPrefix keeps the ",0," login method prefix some ePortals put in
+// front of the account.
+var accountPrefix = regexp.MustCompile(`^,\d+,`)
+
+// droppedHeaders are set by the HTTP client or tied to the browser session.
+var droppedHeaders = map[string]bool{
+	"Host": true, "Content-Length": true, "Content-Type": true, "Cookie": true, "User-Agent": true,
+	"Accept": true, "Accept-Encoding": true, "Accept-Language": true, "Connection": true,
+	"Cache-Control": true, "Pragma": true, "Upgrade-Insecure-Requests": true, "Dnt": true,
+	"Priority": true, "Authorization": true,
+}
+
+func importRequest(method, rawURL string, headers map[string]string, body, mime string) (*Imported, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host == "" {
+		return nil, fmt.Errorf("portal: bad url %q", rawURL)
+	}
+	method = strings.ToUpper(method)
+	imp := &Imported{Request: method + " " + u.Scheme + "://" + u.Host + u.Path}
+	p := &imp.Portal
+	p.Method = method
+	p.Form = make(map[string]string)
+	p.Headers = make(map[string]string)
+
+	var params url.Values
+	switch method {
+	case http.MethodGet:
+		params = u.Query()
+		u.RawQuery = ""
+	case http.MethodPost:
+		if mime != "" && !strings.Contains(mime, "x-www-form-urlencoded") {
+			imp.Notes = append(imp.Notes, "请求体不是表单（"+mime+"），只能按表单方式导入，请确认网关接受")
+		}
+		params, err = url.ParseQuery(body)
+		if err != nil {
+			return nil, fmt.Errorf("portal: parse body: %w", err)
+		}
+	default:
+		return nil, fmt.Errorf("portal: unsupported method %s", method)
+	}
+	p.LoginURL = u.String()
+
+	for k, vals := range params {
+		if len(vals) == 0 || volatileFields[k] {
+			continue
+		}
+		v := vals[0]
+		if k == "user_account" || k == "user_password" {
+			// Filled in from the account settings on every login.
+			if prefix := accountPrefix.FindString(v); prefix != "" {
+				imp.Notes = append(imp.Notes, fmt.Sprintf("user_account 带有前缀 %q，请确认 account.carrier 与之对应", prefix))
+			}
+			continue
+		}
+		switch {
+		case isSecretField(k) && v != "":
+			if looksHashed(v) {
+				imp.Notes = append(imp.Notes, fmt.Sprintf("%s 的值像是加密后的密码，请在 portal.password_encoding 中配置对应的加密方式", k))
+			}
+			v = placeholderPassword
+		case accountField(k) && v != "":
+			v = accountPrefix.FindString(v) + placeholderAccount
+		case strings.Contains(strings.ToLower(k), "ip") && net.ParseIP(v) != nil:
+			if net.ParseIP(v).To4() != nil {
+				v = placeholderIPv4
+			} else {
+				v = placeholderIPv6
+			}
+		}
+		p.Form[k] = v
+	}
+	_, hasAccount := params["user_account"]
+	for _, v := range p.Form {
+		hasAccount = hasAccount || strings.Contains(v, placeholderAccount)
+	}
+	if !hasAccount {
+		imp.Notes = append(imp.Notes, "没有找到账号字段，请在 form 中用 {account} 标出账号")
+	}
+
+	for k, v := range headers {
+		k = http.CanonicalHeaderKey(k)
+		if droppedHeaders[k] || strings.HasPrefix(k, "Sec-") || strings.HasPrefix(k, ":") {
+			continue
+		}
+		p.Headers[k] = v
+	}
+
+	var tokens []string
+	for k := range p.Form {
+		switch {
+		case sessionFields.MatchString(k):
+			tokens = append(tokens, k)
+			delete(p.Form, k)
+		case strings.EqualFold(k, "queryString"):
+			p.Flow.QueryField = k
+			delete(p.Form, k)
+		}
+	}
+	if len(tokens) > 0 || p.Flow.QueryField != "" {
+		p.Flow.PageURL = p.Headers["Referer"]
+		if p.Flow.PageURL == "" {
+			p.Flow.PageURL = p.LoginURL
+		}
+		if p.Flow.QueryField != "" {
+			tokens = append(tokens, p.Flow.QueryField)
+		}
+		sort.Strings(tokens)
+		imp.Notes = append(imp.Notes, fmt.Sprintf("登录需要先打开 %s 获取会话，已配置 flow（会话字段: %s），请确认 flow.page_url", p.Flow.PageURL, strings.Join(tokens, " ")))
+	}
+
+	lower := strings.ToLower(p.LoginURL)
+	switch {
+	case strings.Contains(lower, "/eportal/") || p.Form["callback"] == "dr1003":
+		p.Driver = DriverDrcom
+	case strings.Contains(lower, "srun_portal"):
+		p.Driver = DriverSrun
+	default:
+		p.Driver = DriverGeneric
+	}
+	return imp, nil
+}
+
+var hexHash = regexp.MustCompile(`^(\{[A-Za-z0-9]+\})?[0-9a-fA-F]{32,}$`)
+
+func looksHashed(v string) bool {
+	return hexHash.MatchString(v)
+}
+
+// successKeywords picks a marker of success from a successful login reply.
+// A JSON field is only used when it holds a value portals use for success;
+// a field like "code":0 could equally mean failure on another portal.
+func successKeywords(body string) []string {
+	if m := jsonpPayload(body); m != nil {
+		for _, key := range []string{"result", "error", "res", "code", "status", "success"} {
+			v, ok := m[key]
+			if !ok || !successValue(v) {
+				continue
+			}
+			raw, _ := json.Marshal(v)
+			kw := fmt.Sprintf("%q:%s", key, raw)
+			if strings.Contains(body, kw) {
+				return []string{kw}
+			}
+		}
+		return nil
+	}
+	for _, kw := range []string{"认证成功", "登录成功", "成功登录", "success"} {
+		if strings.Contains(body, kw) {
+			return []string{kw}
+		}
+	}
+	return nil
+}
+
+// successValue reports whether v is one of 1, "1", "ok" or true.
+func successValue(v any) bool {
+	switch v := v.(type) {
+	case float64:
+		return v == 1
+	case string:
+		return v == "1" || strings.EqualFold(v, "ok")
+	case bool:
+		return v
+	}
+	return false
+}
+
+// splitShell splits a command line the way bash (or cmd for "Copy as cURL
+// (cmd)") would: quotes, backslash escapes and line continuations.
+func splitShell(s string) ([]string, error) {
+	if strings.Contains(s, `^"`) {
+		// cmd flavour: ^ escapes the next character, "" quoting only.
+		var b strings.Builder
+		for i := 0; i < len(s); i++ {
+			if s[i] == '^' && i+1 < len(s) {
+				i++
+				if s[i] == '\n' || s[i] == '\r' {
+					continue
+				}
+			}
+			b.WriteByte(s[i])
+		}
+		s = strings.ReplaceAll(b.String(), `\"`, `"`)
+		s = strings.ReplaceAll(s, "\\\n", " ")
+	}
+	var args []string
+	var cur strings.Builder
+	inArg := false
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '\'':
+			j := strings.IndexByte(s[i+1:], '\'')
+			if j < 0 {
+				return nil, errors.New("portal: unterminated quote in curl command")
+			}
+			cur.WriteString(s[i+1 : i+1+j])
+			i += j + 1
+			inArg = true
+		case c == '"':
+			i++
+			for ; i < len(s) && s[i] != '"'; i++ {
+				if s[i] == '\\' && i+1 < len(s) && strings.IndexByte("\"\\$`", s[i+1]) >= 0 {
+					i++
+				}
+				cur.WriteByte(s[i])
+			}
+			if i >= len(s) {
+				return nil, errors.New("portal: unterminated quote in curl command")
+			}
+			inArg = true
+		case c == '$' && i+1 < len(s) && s[i+1] == '\'':
+			// $'...' ANSI-C quoting, used by Chrome for bodies with escapes.
+			i += 2
+			for ; i < len(s) && s[i] != '\''; i++ {
+				if s[i] == '\\' && i+1 < len(s) {
+					i++
+					switch s[i] {
+					case 'n':
+						cur.WriteByte('\n')
+					case 't':
+						cur.WriteByte('\t')
+					case 'r':
+						cur.WriteByte('\r')
+					default:
+						cur.WriteByte(s[i])
+					}
+					continue
+				}
+				cur.WriteByte(s[i])
+			}
+			inArg = true
+		case c == '\\' && i+1 < len(s):
+			i++
+			if s[i] != '\n' && s[i] != '\r' {
+				cur.WriteByte(s[i])
+				inArg = true
+			}
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			if inArg {
+				args = append(args, cur.String())
+				cur.Reset()
+				inArg = false
+			}
+		default:
+			cur.WriteByte(c)
+			inArg = true
+		}
+	}
+	if inArg {
+		args = append(args, cur.String())
+	}
+	return args, nil
+}
@@ -0,0 +1,101 @@
+package portal
+
+import (
+	"encoding/base64"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestSuccessKeywords(t *testing.T) {
+	cases := []struct {
+		body string
+		want string
+	}{
+		{`dr1004({"result":1,"msg":"认证成功"})`, `"result":1`},
+		{`jQuery1({"error":"ok","res":"ok"})`, `"error":"ok"`},
+		{`{"code":"1","message":""}`, `"code":"1"`},
+		{`{"success":true}`, `"success":true`},
+		// Values that mean success on one portal and failure on another
+		// are left to the user.
+		{`{"code":0,"msg":"success"}`, ""},
+		{`{"result":0,"msg":"认证成功"}`, ""},
+		{`{"status":"200"}`, ""},
+		{`<html><h1>认证成功页</h1></html>`, "认证成功"},
+		{`<html>请重新登录</html>`, ""},
+	}
+	for _, c := range cases {
+		got := successKeywords(c.body)
+		if c.want == "" {
+			if len(got) != 0 {
+				t.Errorf("successKeywords(%s) = %q, want none", c.body, got)
+			}
+			continue
+		}
+		if len(got) != 1 || got[0] != c.want {
+			t.Errorf("successKeywords(%s) = %q, want %q", c.body, got, c.want)
+		}
+	}
+}
+
+func TestImportHARNotesUnknownSuccess(t *testing.T) {
+	h := loginHAR(HARContent{Text: `{"code":0}`})
+	imp, err := ImportHAR(h)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(imp.Portal.SuccessKeywords) != 0 {
+		t.Errorf("SuccessKeywords = %q", imp.Portal.SuccessKeywords)
+	}
+	if !strings.Contains(strings.Join(imp.Notes, "\n"), "success_keywords") {
+		t.Errorf("no success_keywords note in %q", imp.Notes)
+	}
+}
+
+func TestImportHARBase64Content(t *testing.T) {
+	body := `dr1003({"result":1,"msg":"认证成功"})`
+	h := loginHAR(HARContent{
+		MimeType: "application/javascript",
+		Text:     base64.StdEncoding.EncodeToString([]byte(body)),
+		Encoding: "base64",
+	})
+	imp, err := ImportHAR(h)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := imp.Portal.SuccessKeywords; len(got) != 1 || got[0] != `"result":1` {
+		t.Errorf("SuccessKeywords = %q", got)
+	}
+
+	h.Log.Entries[0].Response.Content.Text = "%%%"
+	if _, err := ImportHAR(h); err == nil {
+		t.Error("ImportHAR accepted invalid base64 content")
+	}
+}
+
+func loginHAR(c HARContent) *HAR {
+	var h HAR
+	h.Log.Entries = []HAREntry{{
+		Request: HARRequest{
+			Method: "GET",
+			URL:    "http://10.2.5.251:801/eportal/?c=Portal&a=login&callback=dr1003&login_method=1&user_account=08123456&user_password=hunter22",
+		},
+		Response: HARResponse{Status: 200, Content: c},
+	}}
+	return &h
+}
+
+func TestReplayerDecodesBase64(t *testing.T) {
+	body := `dr1003({"result":1})`
+	h := loginHAR(HARContent{Text: base64.StdEncoding.EncodeToString([]byte(body)), Encoding: "base64"})
+	req, _ := http.NewRequest(h.Log.Entries[0].Request.Method, h.Log.Entries[0].Request.URL, nil)
+	resp, err := NewReplayer(h).RoundTrip(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, _ := io.ReadAll(resp.Body)
+	if string(got) != body {
+		t.Errorf("body = %q, want %q", got, body)
+	}
+}
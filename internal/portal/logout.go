@@ -0,0 +1,230 @@
+package portal
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"CUMT-autologin/internal/config"
+)
+
+var (
+	// ErrLogoutNotConfigured is returned for the generic driver when neither
+	// logout_url nor logout_form is set.
+	ErrLogoutNotConfigured = errors.New("portal: logout not configured")
+	// ErrUnbindUnsupported is returned when the driver cannot unbind MACs.
+	ErrUnbindUnsupported = errors.New("portal: unbind mac not supported by driver")
+)
+
+// Result is the parsed outcome of a logout or unbind request.
+type Result struct {
+	OK      bool   `json:"ok"`
+	Message string `json:"message"`
+	Body    string `json:"-"`
+}
+
+// Logout ends the session of this machine on the gateway.
+func Logout(ctx context.Context, cfg *config.PortalConfig, opts *Options) (*Result, error) {
+	opts = opts.orDefault()
+	cfg = withAddresses(withCredentials(cfg), opts.Binding)
+	switch Driver(cfg) {
+	case DriverDrcom:
+		target, err := drcomEndpoint(cfg, cfg.LogoutURL, "logout")
+		if err != nil {
+			return nil, err
+		}
+		params := map[string]string{
+			"callback":       "dr1004",
+			"login_method":   "1",
+			"user_account":   "drcom",
+			"user_password":  "123",
+			"ac_logout":      "1",
+			"register_mode":  "1",
+			"wlan_user_ip":   cfg.Form["wlan_user_ip"],
+			"wlan_user_ipv6": cfg.Form["wlan_user_ipv6"],
+			"wlan_vlan_id":   "0",
+			"wlan_user_mac":  "000000000000",
+			"wlan_ac_ip":     cfg.Form["wlan_ac_ip"],
+			"wlan_ac_name":   cfg.Form["wlan_ac_name"],
+		}
+		body, err := opts.get(ctx, buildQuery(target, merge(params, cfg.LogoutForm)), cfg.Headers)
+		if err != nil {
+			return nil, err
+		}
+		return parseDrcomResult(body, "注销成功"), nil
+
+	case DriverSrun:
+		target := cfg.LogoutURL
+		if target == "" {
+			base, err := baseURL(cfg.LoginURL)
+			if err != nil {
+				return nil, err
+			}
+			target = base + "/cgi-bin/srun_portal"
+		}
+		username := cfg.Form["username"]
+		if username == "" {
+			username = cfg.Form["user_account"]
+		}
+		acID := cfg.Form["ac_id"]
+		if acID == "" {
+			acID = "1"
+		}
+		params := map[string]string{
+			"callback": "jsonp",
+			"action":   "logout",
+			"username": username,
+			"ip":       cfg.Form["ip"],
+			"ac_id":    acID,
+		}
+		body, err := opts.get(ctx, buildQuery(target, merge(params, cfg.LogoutForm)), cfg.Headers)
+		if err != nil {
+			return nil, err
+		}
+		m := jsonpPayload(body)
+		if m == nil {
+			return &Result{Message: "网关响应无法识别", Body: body}, nil
+		}
+		if res := strField(m, "error"); res == "ok" || strField(m, "res") == "ok" {
+			return &Result{OK: true, Message: "注销成功", Body: body}, nil
+		}
+		msg := strField(m, "error_msg")
+		if msg == "" {
+			msg = strField(m, "error")
+		}
+		return &Result{Message: msg, Body: body}, nil
+	}
+
+	if cfg.LogoutURL == "" && len(cfg.LogoutForm) == 0 {
+		return nil, ErrLogoutNotConfigured
+	}
+	target := cfg.LogoutURL
+	if target == "" {
+		target = cfg.LoginURL
+	}
+	if target == "" {
+		return nil, ErrEmptyURL
+	}
+	body, err := opts.get(ctx, buildQuery(target, cfg.LogoutForm), cfg.Headers)
+	if err != nil {
+		return nil, err
+	}
+	if len(cfg.LogoutKeywords) == 0 {
+		// Without keywords a reply from the gateway is the best we can tell.
+		return &Result{OK: true, Message: "注销请求已发送", Body: body}, nil
+	}
+	for _, kw := range cfg.LogoutKeywords {
+		if kw != "" && strings.Contains(body, kw) {
+			return &Result{OK: true, Message: "注销成功", Body: body}, nil
+		}
+	}
+	return &Result{Message: "注销响应未匹配 logout_keywords", Body: body}, nil
+}
+
+// UnbindMAC clears a MAC binding of the configured account, which also kicks
+// the device holding it off the network. An empty mac unbinds every device
+// bound to the account. Only Dr.COM supports this.
+func UnbindMAC(ctx context.Context, cfg *config.PortalConfig, mac string, opts *Options) (*Result, error) {
+	opts = opts.orDefault()
+	if Driver(cfg) != DriverDrcom {
+		return nil, ErrUnbindUnsupported
+	}
+	target, err := drcomEndpoint(cfg, cfg.UnbindURL, "unbind_mac")
+	if err != nil {
+		return nil, err
+	}
+	account := cfg.Form["user_account"]
+	if account == "" {
+		return nil, fmt.Errorf("portal: unbind mac needs an account")
+	}
+	params := map[string]string{
+		"callback":      "dr1002",
+		"user_account":  account,
+		"wlan_user_mac": normalizeMAC(mac),
+		"wlan_user_ip":  cfg.Form["wlan_user_ip"],
+	}
+	body, err := opts.get(ctx, buildQuery(target, params), cfg.Headers)
+	if err != nil {
+		return nil, err
+	}
+	return parseDrcomResult(body, "解绑成功"), nil
+}
+
+// drcomEndpoint derives an ePortal action URL from login_url. Both the old
+// "/eportal/?c=Portal&a=login" and the newer "/eportal/portal/login" layouts
+// are understood; override is used verbatim when set.
+func drcomEndpoint(cfg *config.PortalConfig, override, action string) (string, error) {
+	if override != "" {
+		return override, nil
+	}
+	if cfg.LoginURL == "" {
+		return "", ErrEmptyURL
+	}
+	u, err := url.Parse(cfg.LoginURL)
+	if err != nil {
+		return "", err
+	}
+	if strings.Contains(u.Path, "/eportal/portal/") {
+		path := "/eportal/portal/logout"
+		if action == "unbind_mac" {
+			path = "/eportal/portal/mac/unbind"
+		}
+		return u.Scheme + "://" + u.Host + path, nil
+	}
+	path := u.Path
+	if path == "" {
+		path = "/eportal/"
+	}
+	return u.Scheme + "://" + u.Host + path + "?c=Portal&a=" + action, nil
+}
+
+// parseDrcomResult reads a dr100x({"result":"1","msg":...}) reply.
+func parseDrcomResult(body, okMsg string) *Result {
+	m := jsonpPayload(body)
+	if m == nil {
+		return &Result{Message: "网关响应无法识别", Body: body}
+	}
+	if numField(m, "result") == 1 || strField(m, "result") == "ok" {
+		return &Result{OK: true, Message: okMsg, Body: body}
+	}
+	msg := FailureReason(body)
+	if msg == "" {
+		msg = "网关拒绝请求"
+	}
+	return &Result{Message: msg, Body: body}
+}
+
+func baseURL(raw string) (string, error) {
+	if raw == "" {
+		return "", ErrEmptyURL
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		return "", err
+	}
+	return u.Scheme + "://" + u.Host, nil
+}
+
+// merge returns defaults overridden by extra.
+func merge(defaults, extra map[string]string) map[string]string {
+	out := make(map[string]string, len(defaults)+len(extra))
+	for k, v := range defaults {
+		out[k] = v
+	}
+	for k, v := range extra {
+		out[k] = v
+	}
+	return out
+}
+
+// normalizeMAC strips separators; Dr.COM expects 12 lowercase hex digits and
+// treats all zeros as "any device".
+func normalizeMAC(mac string) string {
+	mac = strings.ToLower(strings.NewReplacer(":", "", "-", "", ".", "").Replace(strings.TrimSpace(mac)))
+	if mac == "" {
+		return "000000000000"
+	}
+	return mac
+}
@@ -0,0 +1,117 @@
+package portal
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"sync"
+	"testing"
+
+	"CUMT-autologin/internal/config"
+	"CUMT-autologin/internal/fakeportal"
+)
+
+// urlLog records the URL of every request sent through it.
+type urlLog struct {
+	mu   sync.Mutex
+	urls []*url.URL
+}
+
+func (l *urlLog) RoundTrip(req *http.Request) (*http.Response, error) {
+	l.mu.Lock()
+	l.urls = append(l.urls, req.URL)
+	l.mu.Unlock()
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+func (l *urlLog) last() *url.URL {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.urls[len(l.urls)-1]
+}
+
+func TestDrcomEndpoint(t *testing.T) {
+	cases := []struct {
+		login, override, action, want string
+	}{
+		{"http://10.2.5.251:801/eportal/portal/login", "", "logout", "http://10.2.5.251:801/eportal/portal/logout"},
+		{"http://10.2.5.251:801/eportal/portal/login", "", "unbind_mac", "http://10.2.5.251:801/eportal/portal/mac/unbind"},
+		{"http://10.2.5.251:801/eportal/?c=Portal&a=login", "", "logout", "http://10.2.5.251:801/eportal/?c=Portal&a=logout"},
+		{"http://10.2.5.251:801", "", "unbind_mac", "http://10.2.5.251:801/eportal/?c=Portal&a=unbind_mac"},
+		{"http://10.2.5.251:801/eportal/portal/login", "http://gw/out", "logout", "http://gw/out"},
+	}
+	for _, c := range cases {
+		got, err := drcomEndpoint(&config.PortalConfig{LoginURL: c.login}, c.override, c.action)
+		if err != nil || got != c.want {
+			t.Errorf("drcomEndpoint(%s, %q, %s) = %s, %v; want %s", c.login, c.override, c.action, got, err, c.want)
+		}
+	}
+	if _, err := drcomEndpoint(&config.PortalConfig{}, "", "logout"); !errors.Is(err, ErrEmptyURL) {
+		t.Errorf("empty login_url: %v", err)
+	}
+}
+
+func TestFakeLogoutURLs(t *testing.T) {
+	cases := []struct {
+		name, driver, login string
+		path                string
+		query               map[string]string
+	}{
+		{"drcom", fakeportal.DriverDrcom, "", "/eportal/portal/logout",
+			map[string]string{"callback": "dr1004", "ac_logout": "1", "wlan_user_mac": "000000000000"}},
+		{"drcom legacy", fakeportal.DriverDrcom, "/eportal/?c=Portal&a=login", "/eportal/",
+			map[string]string{"c": "Portal", "a": "logout", "callback": "dr1004"}},
+		{"srun", fakeportal.DriverSrun, "", "/cgi-bin/srun_portal",
+			map[string]string{"action": "logout", "username": "08123456", "ac_id": "1", "callback": "jsonp"}},
+	}
+	ctx := context.Background()
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			srv, cfg, _ := newFake(t, fakeportal.Options{Driver: c.driver})
+			if c.login != "" {
+				u, _ := url.Parse(cfg.LoginURL)
+				cfg.LoginURL = u.Scheme + "://" + u.Host + c.login
+			}
+			log := &urlLog{}
+			opts := &Options{Transport: log}
+			if _, err := Submit(ctx, cfg, opts); err != nil || len(srv.Sessions()) != 1 {
+				t.Fatalf("login: %v, sessions %+v", err, srv.Sessions())
+			}
+
+			res, err := Logout(ctx, cfg, opts)
+			if err != nil || !res.OK {
+				t.Fatalf("Logout = %+v, %v", res, err)
+			}
+			u := log.last()
+			if u.Path != c.path {
+				t.Errorf("logout path = %s, want %s", u.Path, c.path)
+			}
+			for k, v := range c.query {
+				if got := u.Query().Get(k); got != v {
+					t.Errorf("logout %s = %q, want %q (%s)", k, got, v, u.RawQuery)
+				}
+			}
+			if len(srv.Sessions()) != 0 {
+				t.Errorf("sessions after logout = %+v", srv.Sessions())
+			}
+		})
+	}
+}
+
+func TestLogoutUnsupported(t *testing.T) {
+	ctx := context.Background()
+	generic := &config.PortalConfig{LoginURL: "http://127.0.0.1:9/login", Form: map[string]string{"user_account": "08123456"}}
+	if _, err := Logout(ctx, generic, &Options{Proxy: Direct}); !errors.Is(err, ErrLogoutNotConfigured) {
+		t.Errorf("generic Logout = %v, want ErrLogoutNotConfigured", err)
+	}
+	if _, err := UnbindMAC(ctx, generic, "", &Options{Proxy: Direct}); !errors.Is(err, ErrUnbindUnsupported) {
+		t.Errorf("generic UnbindMAC = %v, want ErrUnbindUnsupported", err)
+	}
+
+	_, cfg, opts := newFake(t, fakeportal.Options{})
+	delete(cfg.Form, "user_account")
+	if _, err := UnbindMAC(ctx, cfg, "", opts); err == nil {
+		t.Error("UnbindMAC without an account succeeded")
+	}
+}
@@ -0,0 +1,245 @@
+package portal
+
+import (
+	"context"
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"regexp"
+	"strings"
+
+	"CUMT-autologin/internal/config"
+)
+
+// Password encoding schemes, see config.PasswordEncoding.
+const (
+	SchemePlain        = "plain"
+	SchemeMD5          = "md5"
+	SchemeSHA1         = "sha1"
+	SchemeSHA256       = "sha256"
+	SchemeBase64       = "base64"
+	SchemeDrcomMD5     = "drcom_md5"
+	SchemeDrcomBase64  = "drcom_base64"
+	SchemeRSA          = "rsa"
+	SchemeRSANoPadding = "rsa_no_padding"
+)
+
+// ErrNoRSAKey is returned when no public key is configured or found on the
+// key page.
+var ErrNoRSAKey = errors.New("portal: no rsa public key")
+
+var (
+	pemKeyRe    = regexp.MustCompile(`-----BEGIN (?:RSA )?PUBLIC KEY-----[\s\S]+?-----END (?:RSA )?PUBLIC KEY-----`)
+	derKeyRe    = regexp.MustCompile(`MI[GI][A-Za-z0-9+/]{100,}={0,2}`)
+	modulusRe   = regexp.MustCompile(`(?i)modulus["']?\s*[:=,(]\s*["']?([0-9a-f]{64,})`)
+	exponentRe  = regexp.MustCompile(`(?i)exponent["']?\s*[:=,(]\s*["']?([0-9a-f]{1,8})\b`)
+	rsaSetKeyRe = regexp.MustCompile(`(?i)getKeyPair\(\s*["']([0-9a-f]{1,8})["']\s*,\s*["'][0-9a-f]*["']\s*,\s*["']([0-9a-f]{64,})["']`)
+)
+
+// IsRSAScheme reports whether scheme needs a public key.
+func IsRSAScheme(scheme string) bool {
+	s := strings.ToLower(scheme)
+	return s == SchemeRSA || s == SchemeRSANoPadding
+}
+
+// withPassword returns cfg with user_password encoded as
+// cfg.PasswordEncoding asks; cfg itself is not modified. The RSA key page,
+// if any, is fetched through s, or through opts when s is nil.
+func withPassword(ctx context.Context, cfg *config.PortalConfig, opts *Options, s *session) (*config.PortalConfig, error) {
+	enc := cfg.PasswordEncoding
+	if enc.Scheme == "" && enc.Prefix == "" {
+		return cfg, nil
+	}
+	pw, ok := cfg.Form["user_password"]
+	if !ok {
+		return cfg, nil
+	}
+	var key *rsa.PublicKey
+	if IsRSAScheme(enc.Scheme) {
+		var err error
+		if key, err = rsaKey(ctx, cfg, opts, s); err != nil {
+			return nil, err
+		}
+	}
+	encoded, err := EncodePassword(pw, enc, key)
+	if err != nil {
+		return nil, err
+	}
+	out := *cfg
+	out.Form = make(map[string]string, len(cfg.Form))
+	for k, v := range cfg.Form {
+		out.Form[k] = v
+	}
+	out.Form["user_password"] = encoded
+	return &out, nil
+}
+
+// EncodePassword applies enc to password; key is needed by the RSA schemes
+// only.
+func EncodePassword(password string, enc config.PasswordEncoding, key *rsa.PublicKey) (string, error) {
+	var out string
+	switch strings.ToLower(enc.Scheme) {
+	case "", SchemePlain:
+		out = password
+	case SchemeMD5:
+		sum := md5.Sum([]byte(password))
+		out = hex.EncodeToString(sum[:])
+	case SchemeSHA1:
+		sum := sha1.Sum([]byte(password))
+		out = hex.EncodeToString(sum[:])
+	case SchemeSHA256:
+		sum := sha256.Sum256([]byte(password))
+		out = hex.EncodeToString(sum[:])
+	case SchemeBase64:
+		out = base64.StdEncoding.EncodeToString([]byte(password))
+	case SchemeDrcomMD5:
+		sum := md5.Sum([]byte(password))
+		out = "{MD5}" + hex.EncodeToString(sum[:])
+	case SchemeDrcomBase64:
+		out = "{B}" + base64.StdEncoding.EncodeToString([]byte(password))
+	case SchemeRSA:
+		if key == nil {
+			return "", ErrNoRSAKey
+		}
+		c, err := rsa.EncryptPKCS1v15(rand.Reader, key, []byte(password))
+		if err != nil {
+			return "", err
+		}
+		out = base64.StdEncoding.EncodeToString(c)
+	case SchemeRSANoPadding:
+		if key == nil {
+			return "", ErrNoRSAKey
+		}
+		var err error
+		if out, err = rsaNoPadding(key, password); err != nil {
+			return "", err
+		}
+	default:
+		return "", fmt.Errorf("portal: unknown password scheme %q", enc.Scheme)
+	}
+	return enc.Prefix + out, nil
+}
+
+// rsaNoPadding mirrors RSAUtils.encryptedString(key, reversed password) as
+// used by Ruijie and similar portals: the reversed string is read little
+// endian, which is the password itself big endian, raised to e mod n, and
+// printed as hex in 16-bit digits. A password that does not fit below the
+// modulus would wrap around, so it is refused.
+func rsaNoPadding(key *rsa.PublicKey, password string) (string, error) {
+	m := new(big.Int).SetBytes([]byte(password))
+	if m.Cmp(key.N) >= 0 {
+		return "", errors.New("portal: password too long for the rsa key")
+	}
+	c := new(big.Int).Exp(m, big.NewInt(int64(key.E)), key.N)
+	h := c.Text(16)
+	if n := len(h) % 4; n != 0 {
+		h = strings.Repeat("0", 4-n) + h
+	}
+	return h, nil
+}
+
+// rsaKey returns the configured public key, fetching the key page when no
+// key is given inline. The page is fetched through the login flow's session
+// so that a key bound to the session cookie matches the submit.
+func rsaKey(ctx context.Context, cfg *config.PortalConfig, opts *Options, s *session) (*rsa.PublicKey, error) {
+	enc := cfg.PasswordEncoding
+	if enc.RSAKey != "" {
+		return ParseRSAKey(enc.RSAKey, enc.RSAExponent)
+	}
+	if enc.RSAKeyURL == "" {
+		return nil, ErrNoRSAKey
+	}
+	opts = opts.orDefault()
+	if s == nil {
+		s = &session{client: opts.httpClient()}
+	}
+	page, err := s.get(ctx, opts, enc.RSAKeyURL, cfg.Headers)
+	if err != nil {
+		return nil, fmt.Errorf("portal: fetch rsa key: %w", err)
+	}
+	return FindRSAKey(page, enc.RSAKeyPattern, enc.RSAExponent)
+}
+
+// FindRSAKey extracts a public key from a portal page or script: with
+// pattern, its first group; otherwise a PEM block, a bare base64 DER key,
+// RSAUtils.getKeyPair(e, d, n) or "modulus" / "exponent" assignments.
+func FindRSAKey(page, pattern, exponent string) (*rsa.PublicKey, error) {
+	page = strings.ReplaceAll(page, `\n`, "\n")
+	page = strings.ReplaceAll(page, `\/`, "/")
+	if pattern != "" {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("portal: rsa_key_pattern: %w", err)
+		}
+		m := re.FindStringSubmatch(page)
+		if len(m) < 2 {
+			return nil, ErrNoRSAKey
+		}
+		return ParseRSAKey(m[1], exponent)
+	}
+	if m := pemKeyRe.FindString(page); m != "" {
+		return ParseRSAKey(m, "")
+	}
+	if m := rsaSetKeyRe.FindStringSubmatch(page); m != nil {
+		return ParseRSAKey(m[2], m[1])
+	}
+	if m := modulusRe.FindStringSubmatch(page); m != nil {
+		if e := exponentRe.FindStringSubmatch(page); e != nil && exponent == "" {
+			exponent = e[1]
+		}
+		return ParseRSAKey(m[1], exponent)
+	}
+	if m := derKeyRe.FindString(page); m != "" {
+		return ParseRSAKey(m, "")
+	}
+	return nil, ErrNoRSAKey
+}
+
+// ParseRSAKey reads a PEM or base64 DER public key, or a hex modulus with
+// the hex exponent (default 10001).
+func ParseRSAKey(s, exponent string) (*rsa.PublicKey, error) {
+	s = strings.TrimSpace(s)
+	if block, _ := pem.Decode([]byte(s)); block != nil {
+		return parseDERKey(block.Bytes)
+	}
+	if der, err := base64.StdEncoding.DecodeString(s); err == nil && strings.HasPrefix(s, "MI") {
+		return parseDERKey(der)
+	}
+	n, ok := new(big.Int).SetString(strings.TrimPrefix(strings.ToLower(s), "0x"), 16)
+	if !ok || n.Sign() <= 0 {
+		return nil, errors.New("portal: rsa key is neither PEM, DER nor a hex modulus")
+	}
+	e := int64(0x10001)
+	if exponent != "" {
+		v, ok := new(big.Int).SetString(exponent, 16)
+		if !ok || !v.IsInt64() || v.Int64() < 3 {
+			return nil, fmt.Errorf("portal: bad rsa exponent %q", exponent)
+		}
+		e = v.Int64()
+	}
+	return &rsa.PublicKey{N: n, E: int(e)}, nil
+}
+
+func parseDERKey(der []byte) (*rsa.PublicKey, error) {
+	if k, err := x509.ParsePKCS1PublicKey(der); err == nil {
+		return k, nil
+	}
+	k, err := x509.ParsePKIXPublicKey(der)
+	if err != nil {
+		return nil, err
+	}
+	pub, ok := k.(*rsa.PublicKey)
+	if !ok {
+		return nil, errors.New("portal: public key is not rsa")
+	}
+	return pub, nil
+}
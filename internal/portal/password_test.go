@@ -0,0 +1,149 @@
+package portal
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+
+	"CUMT-autologin/internal/config"
+)
+
+func TestEncodePassword(t *testing.T) {
+	cases := []struct {
+		scheme, prefix string
+		want           string
+	}{
+		{"", "", "hunter22"},
+		{SchemePlain, "", "hunter22"},
+		{SchemeMD5, "", "cb95015a436fe976eb38e45455372032"},
+		{"MD5", "", "cb95015a436fe976eb38e45455372032"},
+		{SchemeSHA1, "", "60b3af8bfe3735623c7d4a5ef749bb6ac1a4413a"},
+		{SchemeSHA256, "", "20d2fe5e369db54ec7090639a9dc30ec4d608604936239d39e2de07fda09eb0b"},
+		{SchemeBase64, "", "aHVudGVyMjI="},
+		{SchemeDrcomMD5, "", "{MD5}cb95015a436fe976eb38e45455372032"},
+		{SchemeDrcomBase64, "", "{B}aHVudGVyMjI="},
+		{SchemeMD5, "{MD5}", "{MD5}cb95015a436fe976eb38e45455372032"},
+		{SchemePlain, "pre_", "pre_hunter22"},
+	}
+	for _, c := range cases {
+		got, err := EncodePassword("hunter22", config.PasswordEncoding{Scheme: c.scheme, Prefix: c.prefix}, nil)
+		if err != nil || got != c.want {
+			t.Errorf("EncodePassword(%q, %q) = %q, %v; want %q", c.scheme, c.prefix, got, err, c.want)
+		}
+	}
+	if _, err := EncodePassword("x", config.PasswordEncoding{Scheme: "rot13"}, nil); err == nil {
+		t.Error("unknown scheme accepted")
+	}
+	for _, s := range []string{SchemeRSA, SchemeRSANoPadding} {
+		if _, err := EncodePassword("x", config.PasswordEncoding{Scheme: s}, nil); err != ErrNoRSAKey {
+			t.Errorf("%s without key: %v, want ErrNoRSAKey", s, err)
+		}
+	}
+}
+
+// The expected values were produced under node by following
+// RSAUtils.encryptedString(getKeyPair("10001", "", n), reversed password)
+// step by step: 16-bit little endian digits, chunkSize 2*biHighIndex(n),
+// biToHex output.
+func TestRSANoPadding(t *testing.T) {
+	key := &testKey(t).PublicKey
+	cases := []struct{ pw, want string }{
+		{"a", "924d297abc55a09fd186eed22c03ed2bb11978287884d6771dc2bbbcde8d3ca6b6050f50eb5eddb73bd41da719af3c286b7577b5241a7d364d8b78b6e5ff71f10a574b269462a2d5cead51c55d9eb05ed629d9857aac49e36e627117493b982312bac10807d90c3c40c3a2799de50f904e7a3a2586a845351425473ace23fbd1"},
+		{"hunter22", "7bd7386c066237f997a0398dde454c2c6c62132540477f4b09e1ce32182c2c30b05e596741b677c42d502e1f71d3a0003980a21917c5e416d9119bfcc59e56f7f56eb9aa15c52e64c2f5f944dc0bdb74bb67cfb6c5482409aada53714a4b9c5b11a660ace22a2fcc7bd5308ce01c51886bebeb16137ef3e2c06c2d291c72e4c6"},
+		{"P@ssw0rd!2024", "86dc05edc1e8dfa0ba4dbfaee3c6b175f8c9783ba548cc5e8ca54cdb36027bcd444c01adfc3b61376b9e802f886e79f2ce90fe9b1477abbf50055f3bf4cb56823b9a6a18bc105b5b8840d9e3acf251208dd162b62708fc91f168f8f58b3b656730a047375673b1d18330078c3fb9ad2de69686e4b0d0a202ffba8c3ebe7b2f9f"},
+	}
+	for _, c := range cases {
+		got, err := EncodePassword(c.pw, config.PasswordEncoding{Scheme: SchemeRSANoPadding}, key)
+		if err != nil || got != c.want {
+			t.Errorf("rsa_no_padding(%q) = %q, %v\nwant %q", c.pw, got, err, c.want)
+		}
+	}
+
+	long := strings.Repeat("\xff", key.Size())
+	if _, err := rsaNoPadding(key, long); err == nil {
+		t.Error("password above the modulus accepted")
+	}
+}
+
+func TestRSAEncodeDecrypts(t *testing.T) {
+	priv := testKey(t)
+	got, err := EncodePassword("hunter22", config.PasswordEncoding{Scheme: SchemeRSA}, &priv.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := base64.StdEncoding.DecodeString(got)
+	if err != nil {
+		t.Fatal(err)
+	}
+	plain, err := rsa.DecryptPKCS1v15(rand.Reader, priv, c)
+	if err != nil || string(plain) != "hunter22" {
+		t.Errorf("decrypted %q, %v", plain, err)
+	}
+}
+
+func TestFindRSAKey(t *testing.T) {
+	pub := &testKey(t).PublicKey
+	pkix, err := x509.MarshalPKIXPublicKey(pub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pemKey := string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: pkix}))
+	pkcs1 := string(pem.EncodeToMemory(&pem.Block{Type: "RSA PUBLIC KEY", Bytes: x509.MarshalPKCS1PublicKey(pub)}))
+	der := base64.StdEncoding.EncodeToString(pkix)
+	n := pub.N.Text(16)
+
+	pages := []struct {
+		name, page, pattern, exponent string
+	}{
+		{"pem", `<script>var publicKey = "` + strings.ReplaceAll(pemKey, "\n", `\n`) + `";</script>`, "", ""},
+		{"pkcs1 pem", pkcs1, "", ""},
+		{"der", `{"code":0,"data":{"publicKey":"` + der + `"}}`, "", ""},
+		{"escaped der", `{"publicKey":"` + strings.ReplaceAll(der, "/", `\/`) + `"}`, "", ""},
+		{"getKeyPair", `key = RSAUtils.getKeyPair("10001", "", "` + n + `");`, "", ""},
+		{"modulus", `var publicKeyExponent="10001";var publicKeyModulus="` + n + `";`, "", ""},
+		{"json modulus", `{"publicKeyModulus":"` + n + `","publicKeyExponent":"10001"}`, "", ""},
+		{"pattern", `window.k='` + n + `'`, `window\.k='([0-9a-f]+)'`, "10001"},
+	}
+	for _, p := range pages {
+		got, err := FindRSAKey(p.page, p.pattern, p.exponent)
+		if err != nil {
+			t.Errorf("%s: %v", p.name, err)
+			continue
+		}
+		if !got.Equal(pub) {
+			t.Errorf("%s: got N=%x E=%d", p.name, got.N, got.E)
+		}
+	}
+
+	for _, page := range []string{"<html>no key here</html>", fmt.Sprintf("modulus=%x", 42)} {
+		if _, err := FindRSAKey(page, "", ""); err != ErrNoRSAKey {
+			t.Errorf("FindRSAKey(%q) = %v, want ErrNoRSAKey", page, err)
+		}
+	}
+	if _, err := FindRSAKey("x", "([", ""); err == nil {
+		t.Error("bad pattern accepted")
+	}
+}
+
+func testKey(t *testing.T) *rsa.PrivateKey {
+	t.Helper()
+	data, err := os.ReadFile("testdata/rsa.pem")
+	if err != nil {
+		t.Fatal(err)
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		t.Fatal("testdata/rsa.pem: no pem block")
+	}
+	k, err := x509.ParsePKCS1PrivateKey(block.Bytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return k
+}
@@ -2,12 +2,16 @@ package portal
 
 import (
 	"context"
+	"encoding/base64"
+	"encoding/json"
 	"errors"
+	"fmt"
 	"io"
 	"net/http"
 	"net/url"
 	"strings"
-	"time"
+	"unicode"
+	"unicode/utf8"
 
 	"CUMT-autologin/internal/config"
 )
@@ -24,33 +28,87 @@ func buildQuery(base string, params map[string]string) string {
 	return u.String()
 }
 
-func doGet(fullURL string, headers map[string]string) (string, error) {
-	req, err := http.NewRequest(http.MethodGet, fullURL, nil)
-	if err != nil {
-		return "", err
+// Form values may reference the credentials that the callers put in
+// user_account / user_password, for gateways that name the fields
+// differently, e.g. "username: {account}" for Srun.
+const (
+	placeholderAccount  = "{account}"
+	placeholderPassword = "{password}"
+)
+
+// withCredentials returns cfg with the credential placeholders expanded;
+// cfg itself is not modified.
+func withCredentials(cfg *config.PortalConfig) *config.PortalConfig {
+	needed := false
+	for _, v := range cfg.Form {
+		if strings.Contains(v, placeholderAccount) || strings.Contains(v, placeholderPassword) {
+			needed = true
+			break
+		}
 	}
-	for k, v := range headers {
-		req.Header.Set(k, v)
+	if !needed {
+		return cfg
 	}
+	out := *cfg
+	out.Form = make(map[string]string, len(cfg.Form))
+	r := strings.NewReplacer(placeholderAccount, cfg.Form["user_account"], placeholderPassword, cfg.Form["user_password"])
+	for k, v := range cfg.Form {
+		out.Form[k] = r.Replace(v)
+	}
+	return &out
+}
 
-	client := &http.Client{}
-	resp, err := client.Do(req)
+// Login submits the login form and returns the gateway's response body.
+func Login(ctx context.Context, cfg *config.PortalConfig, opts *Options) (string, error) {
+	r, err := Submit(ctx, cfg, opts)
 	if err != nil {
 		return "", err
 	}
-	defer resp.Body.Close()
+	return r.Body, nil
+}
+
+// Reply is the gateway's answer to a login.
+type Reply struct {
+	Status int
+	// URL is where the request ended up after redirects.
+	URL  string
+	Body string
+}
 
-	bodyBytes, err := io.ReadAll(resp.Body)
+// Submit is Login returning the whole reply, for CheckReply. With a login
+// flow configured it first fetches the login page for its cookies and
+// hidden fields.
+func Submit(ctx context.Context, cfg *config.PortalConfig, opts *Options) (*Reply, error) {
+	opts = opts.orDefault()
+	cfg, s, err := runFlow(ctx, cfg, opts)
 	if err != nil {
-		return "", err
+		return nil, err
+	}
+	if s.client != opts.httpClient() && opts.Client == nil && opts.Transport == nil {
+		// The flow built its own transport; don't leave its connections idle.
+		defer s.client.CloseIdleConnections()
+	}
+	if cfg, err = withPassword(ctx, cfg, opts, s); err != nil {
+		return nil, err
+	}
+	cfg = withAddresses(withCredentials(cfg), opts.Binding)
+	req, _, err := newLoginRequest(cfg)
+	if err != nil {
+		return nil, err
+	}
+	r, err := opts.sendVia(ctx, s.client, req, cfg.Headers)
+	if err != nil {
+		return nil, err
 	}
-	return string(bodyBytes), nil
+	return r, nil
 }
 
-func Login(cfg *config.PortalConfig) (string, error) {
+// newLoginRequest builds the login request of cfg without the headers that
+// Options.do adds; body is the encoded form of a POST.
+func newLoginRequest(cfg *config.PortalConfig) (req *http.Request, body string, err error) {
 	loginURL := cfg.LoginURL
 	if loginURL == "" {
-		return "", ErrEmptyURL
+		return nil, "", ErrEmptyURL
 	}
 
 	method := strings.ToUpper(cfg.Method)
@@ -65,9 +123,10 @@ func Login(cfg *config.PortalConfig) (string, error) {
 	}
 	encoded := values.Encode()
 
-	var body io.Reader
+	var reader io.Reader
 	if method == http.MethodPost {
-		body = strings.NewReader(encoded)
+		body = encoded
+		reader = strings.NewReader(encoded)
 	} else {
 		// GET：把参数拼到 URL 上
 		if strings.Contains(loginURL, "?") {
@@ -75,63 +134,113 @@ func Login(cfg *config.PortalConfig) (string, error) {
 		} else {
 			loginURL = loginURL + "?" + encoded
 		}
-		body = nil
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
-
-	req, err := http.NewRequestWithContext(ctx, method, loginURL, body)
+	req, err = http.NewRequest(method, loginURL, reader)
 	if err != nil {
-		return "", err
+		return nil, "", err
 	}
 
 	// 默认 header
 	if method == http.MethodPost {
 		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
 	}
-	req.Header.Set("User-Agent", "Mozilla/5.0 (campus-netlogin-win)")
+	return req, body, nil
+}
 
-	// 覆盖/追加用户配置的 header
-	for k, v := range cfg.Headers {
-		req.Header.Set(k, v)
-	}
+// IsLoginSuccess judges a login response body alone; rules on the status
+// code and redirect target are skipped. See CheckReply.
+func IsLoginSuccess(body string, cfg *config.PortalConfig) bool {
+	return CheckReply(&Reply{Body: body}, cfg) == nil
+}
 
-	resp, err := http.DefaultClient.Do(req)
-	if err != nil {
-		return "", err
-	}
-	defer resp.Body.Close()
+// ReasonDeviceLimit is the FailureReason for logins rejected because the
+// account already has the maximum number of devices online.
+const ReasonDeviceLimit = "登录设备数已达上限"
+
+// knownReasons maps Dr.COM ePortal error messages to user-facing text.
+// Order matters: the first matching entry wins.
+var knownReasons = []struct{ match, reason string }{
+	{"userid error1", "账号不存在"},
+	{"userid error2", "密码错误"},
+	{"userid error3", "账号已被禁用"},
+	{"ldap auth error", "密码错误"},
+	{"Rad:Oppp error", "运营商账号认证失败"},
+	{"Rad:Status_Err", "账号状态异常（可能已欠费）"},
+	{"Rad:Limit Users Err", ReasonDeviceLimit},
+	{"E2620", ReasonDeviceLimit},
+	{"E2553", "密码错误"},
+	{"E2531", "账号不存在"},
+	{"E2616", "账号状态异常（可能已欠费）"},
+	{"Rad:UserName_Err", "账号不存在"},
+	{"Rad:Password_Err", "密码错误"},
+	{"In use", "账号已在其他设备在线"},
+	{"inuse, login again", "账号已在线，请稍后重试"},
+	{"NO IP", "未获取到 IP 地址"},
+	{"Authentication Fail", "认证失败"},
+	{"bas no response", "网关无响应"},
+	{"mac_bind_fail", "MAC 绑定失败"},
+	{"no_response_data_error", "网关无响应"},
+}
 
-	respBody, err := io.ReadAll(io.LimitReader(resp.Body, 8192))
-	if err != nil {
-		return "", err
+// jsonpPayload extracts the JSON object from a JSONP response such as dr1003({...}).
+func jsonpPayload(body string) map[string]any {
+	start := strings.Index(body, "{")
+	end := strings.LastIndex(body, "}")
+	if start < 0 || end <= start {
+		return nil
 	}
-
-	return string(respBody), nil
+	var m map[string]any
+	if err := json.Unmarshal([]byte(body[start:end+1]), &m); err != nil {
+		return nil
+	}
+	return m
 }
 
-func Logout(p *config.PortalConfig) (string, error) {
-	if len(p.LogoutForm) == 0 {
-		// 没配置注销参数，就直接返回空
-		return "", nil
+// FailureReason returns a short human readable reason for a rejected login.
+// It understands Dr.COM and srun style JSONP responses and falls back to an
+// empty string.
+func FailureReason(body string) string {
+	m := jsonpPayload(body)
+	if m == nil {
+		return ""
+	}
+	msg, _ := m["msg"].(string)
+	if msg == "" {
+		// srun puts the reason in error_msg.
+		msg, _ = m["error_msg"].(string)
+	}
+	msg = strings.TrimSpace(decodeMsg(msg))
+	for _, r := range knownReasons {
+		if strings.Contains(msg, r.match) {
+			return r.reason
+		}
+	}
+	if msg != "" {
+		return msg
+	}
+	if code := fmt.Sprint(m["ret_code"]); code != "" && code != "<nil>" {
+		return "ret_code=" + code
 	}
-	fullURL := buildQuery(p.LoginURL, p.LogoutForm)
-	return doGet(fullURL, p.Headers)
+	return ""
 }
 
-func IsLoginSuccess(body string, cfg *config.PortalConfig) bool {
-	if len(cfg.SuccessKeywords) == 0 {
-		// 如果没配置关键字，就不做判断
-		return true
+// decodeMsg undoes the base64 Dr.COM wraps some messages in. Plain messages
+// that merely happen to be valid base64, like "ok12", are kept as they are.
+func decodeMsg(msg string) string {
+	decoded, err := base64.StdEncoding.DecodeString(msg)
+	if err != nil || msg == "" || !utf8.Valid(decoded) {
+		return msg
 	}
-	for _, kw := range cfg.SuccessKeywords {
-		if kw == "" {
-			continue
-		}
-		if strings.Contains(body, kw) {
-			return true
+	for _, r := range string(decoded) {
+		if !unicode.IsPrint(r) && !unicode.IsSpace(r) {
+			return msg
 		}
 	}
-	return false
+	return string(decoded)
+}
+
+// IsDeviceLimit reports whether a login response was rejected for the device limit.
+func IsDeviceLimit(body string) bool {
+	return FailureReason(body) == ReasonDeviceLimit
 }
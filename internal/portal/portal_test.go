@@ -0,0 +1,22 @@
+package portal
+
+import "testing"
+
+func TestFailureReason(t *testing.T) {
+	cases := []struct{ body, want string }{
+		{`dr1004({"result":0,"msg":"dXNlcmlkIGVycm9yMg==","ret_code":1})`, "密码错误"},
+		{`dr1004({"result":0,"msg":"6LSm5Y+35oiW5a+G56CB6ZSZ6K+v"})`, "账号或密码错误"},
+		{`dr1004({"result":0,"msg":"Rad:Limit Users Err"})`, ReasonDeviceLimit},
+		// Plain messages that happen to be valid base64 stay as they are.
+		{`dr1004({"result":0,"msg":"ok12"})`, "ok12"},
+		{`dr1004({"result":0,"msg":"user"})`, "user"},
+		{`dr1004({"result":0,"msg":"userid"})`, "userid"},
+		{`dr1004({"result":0,"ret_code":2})`, "ret_code=2"},
+		{`<html>502</html>`, ""},
+	}
+	for _, c := range cases {
+		if got := FailureReason(c.body); got != c.want {
+			t.Errorf("FailureReason(%s) = %q, want %q", c.body, got, c.want)
+		}
+	}
+}
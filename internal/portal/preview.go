@@ -0,0 +1,66 @@
+package portal
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"CUMT-autologin/internal/config"
+)
+
+// RequestPreview is the request Login would send, with passwords masked.
+type RequestPreview struct {
+	Method  string       `json:"method"`
+	URL     string       `json:"url"`
+	Headers []HARNameVal `json:"headers"`
+	Body    string       `json:"body,omitempty"`
+}
+
+// PreviewLogin renders the login request for cfg exactly as Login builds
+// it, after address placeholders are expanded, without contacting the
+// gateway, so the page step of a login flow is skipped. Password-like form
+// fields and secrets are masked as in a recording.
+func PreviewLogin(cfg *config.PortalConfig, opts *Options, secrets ...string) (*RequestPreview, error) {
+	opts = opts.orDefault()
+	// RSA needs the key page; the raw password stands in for it.
+	if !IsRSAScheme(cfg.PasswordEncoding.Scheme) {
+		encoded, err := withPassword(context.Background(), cfg, opts, nil)
+		if err != nil {
+			return nil, err
+		}
+		if encoded != cfg {
+			secrets = append(secrets, encoded.Form["user_password"])
+		}
+		cfg = encoded
+	}
+	cfg = withAddresses(withCredentials(cfg), opts.Binding)
+	req, body, err := newLoginRequest(cfg)
+	if err != nil {
+		return nil, err
+	}
+	opts.setHeaders(req, cfg.Headers)
+
+	var raw []byte
+	if body != "" {
+		raw = []byte(body)
+	}
+	hr := (&Recorder{Secrets: secrets}).request(req, raw)
+	p := &RequestPreview{Method: hr.Method, URL: hr.URL, Headers: hr.Headers}
+	if hr.PostData != nil {
+		p.Body = hr.PostData.Text
+	}
+	return p, nil
+}
+
+// String formats p like an HTTP request.
+func (p *RequestPreview) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s %s\n", p.Method, p.URL)
+	for _, h := range p.Headers {
+		fmt.Fprintf(&b, "%s: %s\n", h.Name, h.Value)
+	}
+	if p.Body != "" {
+		fmt.Fprintf(&b, "\n%s\n", p.Body)
+	}
+	return b.String()
+}
@@ -0,0 +1,123 @@
+package portal
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+
+	"CUMT-autologin/internal/config"
+	"CUMT-autologin/internal/fakeportal"
+)
+
+// sentLog keeps the URL and body of every request sent through it.
+type sentLog struct {
+	mu   sync.Mutex
+	reqs []sentRequest
+}
+
+type sentRequest struct {
+	method, url, body string
+}
+
+func (l *sentLog) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil {
+		body, _ = io.ReadAll(req.Body)
+		req.Body = io.NopCloser(bytes.NewReader(body))
+	}
+	l.mu.Lock()
+	l.reqs = append(l.reqs, sentRequest{req.Method, req.URL.String(), string(body)})
+	l.mu.Unlock()
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+func TestPreviewMatchesSubmit(t *testing.T) {
+	cases := []struct {
+		name   string
+		driver string
+		edit   func(cfg *config.PortalConfig)
+	}{
+		{"drcom", fakeportal.DriverDrcom, nil},
+		{"srun", fakeportal.DriverSrun, nil},
+		{"md5", fakeportal.DriverDrcom, func(cfg *config.PortalConfig) {
+			cfg.PasswordEncoding = config.PasswordEncoding{Scheme: SchemeDrcomMD5}
+		}},
+		{"post", fakeportal.DriverDrcom, func(cfg *config.PortalConfig) {
+			cfg.Method = http.MethodPost
+			cfg.Form["wlan_user_ip"] = "{ipv4}"
+		}},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			srv, cfg, _ := newFake(t, fakeportal.Options{Driver: c.driver})
+			if c.edit != nil {
+				c.edit(cfg)
+			}
+			log := &sentLog{}
+			opts := &Options{Transport: log}
+
+			p, err := PreviewLogin(cfg, opts, "secret")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(log.reqs) != 0 || srv.Logins() != 0 {
+				t.Fatalf("preview sent %d requests", len(log.reqs))
+			}
+			text := p.String()
+			encoded, _ := EncodePassword("secret", cfg.PasswordEncoding, nil)
+			for _, leak := range []string{"secret", url.QueryEscape(encoded)} {
+				if strings.Contains(text, leak) {
+					t.Errorf("preview contains %q:\n%s", leak, text)
+				}
+			}
+
+			if _, err := Submit(context.Background(), cfg, opts); err != nil {
+				t.Fatal(err)
+			}
+			if len(log.reqs) != 1 {
+				t.Fatalf("submit sent %d requests", len(log.reqs))
+			}
+			sent := log.reqs[0]
+			if p.Method != sent.method {
+				t.Errorf("method = %s, submit sent %s", p.Method, sent.method)
+			}
+			pu, _ := url.Parse(p.URL)
+			su, _ := url.Parse(sent.url)
+			if pu.Scheme+pu.Host+pu.Path != su.Scheme+su.Host+su.Path {
+				t.Errorf("url = %s, submit sent %s", p.URL, sent.url)
+			}
+			sameForm(t, "query", pu.Query(), su.Query())
+			pb, _ := url.ParseQuery(p.Body)
+			sb, _ := url.ParseQuery(sent.body)
+			if sent.method == http.MethodPost && len(sb) == 0 {
+				t.Errorf("post sent no form")
+			}
+			sameForm(t, "body", pb, sb)
+		})
+	}
+}
+
+// sameForm checks that preview equals sent except for masked passwords.
+func sameForm(t *testing.T, what string, preview, sent url.Values) {
+	t.Helper()
+	if len(preview) != len(sent) {
+		t.Errorf("%s fields = %v, submit sent %v", what, preview, sent)
+	}
+	for k := range sent {
+		got, want := preview.Get(k), sent.Get(k)
+		if isSecretField(k) {
+			if got != redacted {
+				t.Errorf("%s %s = %q, want it masked", what, k, got)
+			}
+			continue
+		}
+		if got != want {
+			t.Errorf("%s %s = %q, submit sent %q", what, k, got, want)
+		}
+	}
+}
@@ -0,0 +1,544 @@
+package portal
+
+import (
+	"bytes"
+	"crypto/md5"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"CUMT-autologin/internal/config"
+)
+
+const (
+	// maxRecordBody bounds each recorded body; gateway pages are far smaller.
+	maxRecordBody = 1 << 20
+	// maxRecordEntries keeps a recording file from growing without bound.
+	maxRecordEntries = 200
+	redacted         = "******"
+)
+
+// HAR is the subset of the HTTP Archive 1.2 format the recorder writes, so
+// recordings can also be opened in browser dev tools.
+type HAR struct {
+	Log HARLog `json:"log"`
+}
+
+type HARLog struct {
+	Version string     `json:"version"`
+	Creator HARCreator `json:"creator"`
+	Entries []HAREntry `json:"entries"`
+}
+
+type HARCreator struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+type HAREntry struct {
+	StartedDateTime time.Time   `json:"startedDateTime"`
+	Time            float64     `json:"time"` // milliseconds
+	Request         HARRequest  `json:"request"`
+	Response        HARResponse `json:"response"`
+	// Error is set instead of Response when the request failed.
+	Error string `json:"_error,omitempty"`
+}
+
+type HARRequest struct {
+	Method      string       `json:"method"`
+	URL         string       `json:"url"`
+	HTTPVersion string       `json:"httpVersion"`
+	Headers     []HARNameVal `json:"headers"`
+	QueryString []HARNameVal `json:"queryString"`
+	PostData    *HARPostData `json:"postData,omitempty"`
+}
+
+type HARResponse struct {
+	Status      int          `json:"status"`
+	StatusText  string       `json:"statusText"`
+	HTTPVersion string       `json:"httpVersion"`
+	Headers     []HARNameVal `json:"headers"`
+	Content     HARContent   `json:"content"`
+	RedirectURL string       `json:"redirectURL"`
+}
+
+type HARNameVal struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type HARPostData struct {
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text"`
+}
+
+type HARContent struct {
+	Size     int    `json:"size"`
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text"`
+	// Encoding is "base64" when browsers store a binary or compressed body.
+	Encoding string `json:"encoding,omitempty"`
+}
+
+// Decoded returns the body, undoing the base64 encoding browsers use for
+// some responses.
+func (c HARContent) Decoded() (string, error) {
+	if !strings.EqualFold(c.Encoding, "base64") {
+		return c.Text, nil
+	}
+	b, err := base64.StdEncoding.DecodeString(c.Text)
+	if err != nil {
+		return "", fmt.Errorf("portal: har content: %w", err)
+	}
+	return string(b), nil
+}
+
+// LoadHAR reads a recording written by a Recorder or exported by a browser.
+func LoadHAR(path string) (*HAR, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var h HAR
+	if err := json.Unmarshal(data, &h); err != nil {
+		return nil, fmt.Errorf("portal: parse %s: %w", path, err)
+	}
+	return &h, nil
+}
+
+// Recorder captures full request / response pairs into a HAR file. Form,
+// query and JSON values whose name looks like a password, cookie values and
+// any of the Secrets are replaced by "******" before anything is kept;
+// cookie names stay so that a replayed login flow still works. The file is
+// rewritten after every exchange so it survives a crash; only the latest
+// entries are kept.
+type Recorder struct {
+	// Path is the HAR file; empty keeps the entries in memory only.
+	Path string
+	// Secrets are redacted wherever they appear, e.g. the account password.
+	// Use SetSecrets once the recorder is in use.
+	Secrets []string
+
+	mu      sync.Mutex
+	entries []HAREntry
+}
+
+// NewRecorder returns a recorder that writes to path.
+func NewRecorder(path string, secrets ...string) *Recorder {
+	return &Recorder{Path: path, Secrets: secrets}
+}
+
+// SetSecrets replaces the Secrets, e.g. after the config was reloaded.
+func (r *Recorder) SetSecrets(secrets ...string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.Secrets = secrets
+}
+
+func (r *Recorder) secrets() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.Secrets
+}
+
+// Secrets returns what a recording of cfg's gateway traffic must not
+// contain: the account and self-service passwords and the forms they are
+// sent in.
+func Secrets(cfg *config.Config) []string {
+	var out []string
+	if pw := cfg.Account.Password; pw != "" {
+		out = append(out, pw)
+		if enc := cfg.Portal.PasswordEncoding; !IsRSAScheme(enc.Scheme) {
+			if encoded, err := EncodePassword(pw, enc, nil); err == nil && encoded != pw {
+				out = append(out, encoded)
+			}
+		}
+	}
+	if _, pw := cfg.SelfServiceCredentials(); pw != "" {
+		sum := md5.Sum([]byte(pw))
+		out = append(out, pw, hex.EncodeToString(sum[:]))
+	}
+	return out
+}
+
+// Entries returns a copy of the recorded exchanges.
+func (r *Recorder) Entries() []HAREntry {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]HAREntry(nil), r.entries...)
+}
+
+// Transport returns next wrapped so that every exchange is recorded; a nil
+// next means http.DefaultTransport.
+func (r *Recorder) Transport(next http.RoundTripper) http.RoundTripper {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return &recordingTransport{rec: r, next: next}
+}
+
+type recordingTransport struct {
+	rec  *Recorder
+	next http.RoundTripper
+}
+
+func (t *recordingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var reqBody []byte
+	if req.Body != nil && req.Body != http.NoBody {
+		data, err := io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		reqBody = data
+		// RoundTrip must not modify req, so send a copy with the buffered body.
+		req = req.Clone(req.Context())
+		req.Body = io.NopCloser(bytes.NewReader(data))
+	}
+
+	start := time.Now()
+	resp, err := t.next.RoundTrip(req)
+	entry := HAREntry{
+		StartedDateTime: start,
+		Request:         t.rec.request(req, reqBody),
+	}
+	if err != nil {
+		entry.Time = msSince(start)
+		entry.Error = t.rec.redact(err.Error())
+		t.rec.add(entry)
+		return nil, err
+	}
+
+	body, readErr := io.ReadAll(io.LimitReader(resp.Body, maxRecordBody))
+	resp.Body.Close()
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	entry.Time = msSince(start)
+	entry.Response = t.rec.response(resp, body)
+	if readErr != nil {
+		entry.Error = t.rec.redact(readErr.Error())
+	}
+	t.rec.add(entry)
+	return resp, readErr
+}
+
+func (r *Recorder) add(e HAREntry) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.entries = append(r.entries, e)
+	if n := len(r.entries) - maxRecordEntries; n > 0 {
+		r.entries = append(r.entries[:0:0], r.entries[n:]...)
+	}
+	if r.Path == "" {
+		return
+	}
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	err := enc.Encode(HAR{Log: HARLog{
+		Version: "1.2",
+		Creator: HARCreator{Name: "CUMT-autologin", Version: "1"},
+		Entries: r.entries,
+	}})
+	if err == nil {
+		_ = os.WriteFile(r.Path, buf.Bytes(), 0600)
+	}
+}
+
+func (r *Recorder) request(req *http.Request, body []byte) HARRequest {
+	u := *req.URL
+	u.RawQuery = r.redactQuery(u.RawQuery)
+	hr := HARRequest{
+		Method:      req.Method,
+		URL:         r.redact(u.String()),
+		HTTPVersion: req.Proto,
+		Headers:     r.headers(req.Header),
+		QueryString: nameValues(r.redactValues(req.URL.Query())),
+	}
+	if body != nil {
+		mime := req.Header.Get("Content-Type")
+		text := string(body)
+		if strings.HasPrefix(mime, "application/x-www-form-urlencoded") {
+			text = r.redactQuery(text)
+		} else {
+			text = redactJSON(text)
+		}
+		hr.PostData = &HARPostData{MimeType: mime, Text: r.redact(text)}
+	}
+	return hr
+}
+
+func (r *Recorder) response(resp *http.Response, body []byte) HARResponse {
+	text := r.redact(redactJSON(string(body)))
+	return HARResponse{
+		Status:      resp.StatusCode,
+		StatusText:  strings.TrimSpace(strings.TrimPrefix(resp.Status, fmt.Sprint(resp.StatusCode))),
+		HTTPVersion: resp.Proto,
+		Headers:     r.headers(resp.Header),
+		Content:     HARContent{Size: len(body), MimeType: resp.Header.Get("Content-Type"), Text: text},
+		RedirectURL: r.redactURL(resp.Header.Get("Location")),
+	}
+}
+
+func (r *Recorder) headers(h http.Header) []HARNameVal {
+	var out []HARNameVal
+	for name, values := range h {
+		for _, v := range values {
+			switch http.CanonicalHeaderKey(name) {
+			case "Authorization", "Proxy-Authorization":
+				v = redacted
+			case "Cookie":
+				v = redactCookies(v)
+			case "Set-Cookie":
+				// Only the first pair is the cookie; the rest are attributes.
+				first, attrs, ok := strings.Cut(v, ";")
+				v = redactCookies(first)
+				if ok {
+					v += ";" + attrs
+				}
+			case "Referer", "Location", "Content-Location":
+				v = r.redactURL(v)
+			default:
+				v = r.redact(v)
+			}
+			out = append(out, HARNameVal{Name: name, Value: v})
+		}
+	}
+	sortNameValues(out)
+	return out
+}
+
+// redactCookies masks the values of the "name=value" pairs in s, keeping
+// the names.
+func redactCookies(s string) string {
+	pairs := strings.Split(s, ";")
+	for i, pair := range pairs {
+		pair = strings.TrimSpace(pair)
+		if name, _, ok := strings.Cut(pair, "="); ok {
+			pair = name + "=" + redacted
+		}
+		pairs[i] = pair
+	}
+	return strings.Join(pairs, "; ")
+}
+
+// redactURL masks the password-like query fields and the secrets of a URL.
+func (r *Recorder) redactURL(s string) string {
+	base, rest, ok := strings.Cut(s, "?")
+	if ok {
+		query, frag, hasFrag := strings.Cut(rest, "#")
+		s = base + "?" + r.redactQuery(query)
+		if hasFrag {
+			s += "#" + frag
+		}
+	}
+	return r.redact(s)
+}
+
+// redactJSON masks password-like fields of a JSON or JSONP body. Bodies
+// without such a field are returned unchanged.
+func redactJSON(text string) string {
+	start := strings.IndexAny(text, "{[")
+	end := strings.LastIndexAny(text, "}]")
+	if start < 0 || end <= start {
+		return text
+	}
+	dec := json.NewDecoder(strings.NewReader(text[start : end+1]))
+	dec.UseNumber()
+	var v any
+	if dec.Decode(&v) != nil || !maskJSON(v) {
+		return text
+	}
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if enc.Encode(v) != nil {
+		return text
+	}
+	return text[:start] + strings.TrimSuffix(buf.String(), "\n") + text[end+1:]
+}
+
+// maskJSON replaces the password-like values in v and reports whether it
+// changed anything.
+func maskJSON(v any) bool {
+	changed := false
+	switch node := v.(type) {
+	case map[string]any:
+		for k, val := range node {
+			switch val.(type) {
+			case map[string]any, []any:
+				changed = maskJSON(val) || changed
+			case nil:
+			default:
+				if isSecretField(k) && fmt.Sprint(val) != "" {
+					node[k] = redacted
+					changed = true
+				}
+			}
+		}
+	case []any:
+		for _, val := range node {
+			changed = maskJSON(val) || changed
+		}
+	}
+	return changed
+}
+
+// redactValues returns v with password-like fields and secrets masked.
+func (r *Recorder) redactValues(v url.Values) url.Values {
+	out := make(url.Values, len(v))
+	for k, vals := range v {
+		for _, val := range vals {
+			if isSecretField(k) && val != "" {
+				val = redacted
+			}
+			out.Add(k, r.redact(val))
+		}
+	}
+	return out
+}
+
+// redactQuery masks password-like fields of an encoded query or form,
+// keeping the order and encoding of everything else.
+func (r *Recorder) redactQuery(raw string) string {
+	pairs := strings.Split(raw, "&")
+	for i, pair := range pairs {
+		key, val, _ := strings.Cut(pair, "=")
+		if name, err := url.QueryUnescape(key); err == nil && isSecretField(name) && val != "" {
+			pairs[i] = key + "=" + url.QueryEscape(redacted)
+		}
+	}
+	return strings.Join(pairs, "&")
+}
+
+// redact masks every secret in s, raw or URL encoded.
+func (r *Recorder) redact(s string) string {
+	for _, secret := range r.secrets() {
+		if len(secret) < 4 {
+			continue // too short to mask without mangling unrelated text
+		}
+		s = strings.ReplaceAll(s, secret, redacted)
+		s = strings.ReplaceAll(s, url.QueryEscape(secret), redacted)
+	}
+	return s
+}
+
+func isSecretField(name string) bool {
+	name = strings.ToLower(name)
+	for _, p := range []string{"pass", "pwd", "upass", "token", "chksum"} {
+		if strings.Contains(name, p) {
+			return true
+		}
+	}
+	return false
+}
+
+func nameValues(v url.Values) []HARNameVal {
+	var out []HARNameVal
+	for k, vals := range v {
+		for _, val := range vals {
+			out = append(out, HARNameVal{Name: k, Value: val})
+		}
+	}
+	sortNameValues(out)
+	return out
+}
+
+func sortNameValues(nv []HARNameVal) {
+	sort.SliceStable(nv, func(i, j int) bool { return nv[i].Name < nv[j].Name })
+}
+
+func msSince(t time.Time) float64 {
+	return float64(time.Since(t).Microseconds()) / 1000
+}
+
+// ErrNotRecorded is returned by a Replayer for requests the recording has
+// no answer for.
+var ErrNotRecorded = errors.New("portal: request not in recording")
+
+// Replayer is an http.RoundTripper that answers from a recording, to
+// reproduce a user's gateway in tests:
+//
+//	h, _ := portal.LoadHAR("testdata/issue42.har")
+//	opts := &portal.Options{Transport: portal.NewReplayer(h)}
+//
+// Requests are matched by method, host and path; query strings are ignored
+// since they carry redacted passwords and timestamps. Entries with the same
+// key are replayed in order and the last one repeats.
+type Replayer struct {
+	mu      sync.Mutex
+	entries map[string][]HAREntry
+}
+
+// NewReplayer returns a transport replaying h.
+func NewReplayer(h *HAR) *Replayer {
+	r := &Replayer{entries: make(map[string][]HAREntry)}
+	for _, e := range h.Log.Entries {
+		u, err := url.Parse(e.Request.URL)
+		if err != nil {
+			continue
+		}
+		k := replayKey(e.Request.Method, u)
+		r.entries[k] = append(r.entries[k], e)
+	}
+	return r
+}
+
+func replayKey(method string, u *url.URL) string {
+	path := u.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+	return strings.ToUpper(method) + " " + strings.ToLower(u.Host) + path
+}
+
+func (r *Replayer) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Body != nil {
+		req.Body.Close()
+	}
+	k := replayKey(req.Method, req.URL)
+	r.mu.Lock()
+	queue := r.entries[k]
+	if len(queue) == 0 {
+		r.mu.Unlock()
+		return nil, fmt.Errorf("%w: %s", ErrNotRecorded, k)
+	}
+	e := queue[0]
+	if len(queue) > 1 {
+		r.entries[k] = queue[1:]
+	}
+	r.mu.Unlock()
+
+	if e.Error != "" && e.Response.Status == 0 {
+		return nil, errors.New(e.Error)
+	}
+	body, err := e.Response.Content.Decoded()
+	if err != nil {
+		return nil, err
+	}
+	header := make(http.Header)
+	for _, h := range e.Response.Headers {
+		header.Add(h.Name, h.Value)
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", e.Response.Status, e.Response.StatusText),
+		StatusCode:    e.Response.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(strings.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}, nil
+}
@@ -0,0 +1,154 @@
+package portal
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"CUMT-autologin/internal/config"
+	"CUMT-autologin/internal/fakeportal"
+)
+
+func TestRecorderRedacts(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.Copy(io.Discard, r.Body)
+		http.SetCookie(w, &http.Cookie{Name: "JSESSIONID", Value: "abc123", Path: "/", HttpOnly: true})
+		w.Header().Set("Location", "/next?user_password=hunter22&ac_id=1")
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`jsonp({"ok":1,"token":"t0k3n-value","data":{"password":"p4ssw0rd","user":"08123456"}})`))
+	}))
+	defer srv.Close()
+
+	rec := NewRecorder("", "hunter22")
+	client := &http.Client{Transport: rec.Transport(nil)}
+	req, _ := http.NewRequest(http.MethodPost, srv.URL+"/login?upass=hunter22&name=x",
+		strings.NewReader(`{"user":"08123456","password":"hunter22","remember":true}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Referer", srv.URL+"/index?pwd=0ld-pw&lang=zh")
+	req.Header.Set("Cookie", "JSESSIONID=abc123; lang=zh")
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	entries := rec.Entries()
+	if len(entries) != 1 {
+		t.Fatalf("%d entries", len(entries))
+	}
+	e := entries[0]
+	all := e.Request.URL + e.Request.PostData.Text + e.Response.Content.Text + e.Response.RedirectURL
+	for _, h := range append(e.Request.Headers, e.Response.Headers...) {
+		all += "\n" + h.Name + ": " + h.Value
+	}
+	for _, leak := range []string{"hunter22", "abc123", "t0k3n-value", "p4ssw0rd", "0ld-pw"} {
+		if strings.Contains(all, leak) {
+			t.Errorf("recording contains %q:\n%s", leak, all)
+		}
+	}
+	for _, keep := range []string{
+		"Cookie: JSESSIONID=******; lang=******",
+		"Set-Cookie: JSESSIONID=******; Path=/; HttpOnly",
+		"Referer: " + srv.URL + "/index?pwd=%2A%2A%2A%2A%2A%2A&lang=zh",
+		`"user":"08123456"`,
+		`"remember":true`,
+		`jsonp({`,
+		`"ok":1`,
+		"/next?user_password=%2A%2A%2A%2A%2A%2A&ac_id=1",
+	} {
+		if !strings.Contains(all, keep) {
+			t.Errorf("recording lacks %q:\n%s", keep, all)
+		}
+	}
+}
+
+func TestRedactJSONLeavesOtherBodiesAlone(t *testing.T) {
+	for _, body := range []string{
+		"",
+		"<html><body>登录</body></html>",
+		`dr1003({"result":1,"msg":"ok"})`,
+		`{"broken":`,
+	} {
+		if got := redactJSON(body); got != body {
+			t.Errorf("redactJSON(%q) = %q", body, got)
+		}
+	}
+}
+
+func TestSecrets(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.Account.Password = "hunter22"
+	cfg.Portal.PasswordEncoding.Scheme = "md5"
+	cfg.Portal.SelfService.Password = "self-pw"
+	got := strings.Join(Secrets(cfg), " ")
+	pw := md5.Sum([]byte("hunter22"))
+	self := md5.Sum([]byte("self-pw"))
+	for _, want := range []string{"hunter22", hex.EncodeToString(pw[:]), "self-pw", hex.EncodeToString(self[:])} {
+		if !strings.Contains(got, want) {
+			t.Errorf("Secrets = %q, missing %q", got, want)
+		}
+	}
+}
+
+func TestRecordReplayRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	fake, ts := fakeportal.NewTestServer(fakeportal.Options{Account: "08123456", Password: "hunter22", UsedBytes: 1 << 30})
+	cfg := fake.PortalConfig(ts.URL)
+	path := filepath.Join(t.TempDir(), "record.har")
+
+	live := &Options{Proxy: Direct, Recorder: NewRecorder(path, "hunter22")}
+	reply, err := Submit(ctx, &cfg, live)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := CheckReply(reply, &cfg); err != nil {
+		t.Fatal(err)
+	}
+	info, err := Status(ctx, &cfg, live)
+	if err != nil || !info.Online {
+		t.Fatalf("live status = %+v, %v", info, err)
+	}
+	if res, err := Logout(ctx, &cfg, live); err != nil || !res.OK {
+		t.Fatalf("live logout = %+v, %v", res, err)
+	}
+	ts.Close()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), "hunter22") {
+		t.Error("recording contains the password")
+	}
+	h, err := LoadHAR(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The gateway is gone; the recording answers instead.
+	replay := &Options{Transport: NewReplayer(h)}
+	reply, err = Submit(ctx, &cfg, replay)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := CheckReply(reply, &cfg); err != nil {
+		t.Errorf("replayed login = %v", err)
+	}
+	got, err := Status(ctx, &cfg, replay)
+	if err != nil || !got.Online || got.Account != info.Account || got.UsedBytes != info.UsedBytes {
+		t.Errorf("replayed status = %+v, %v; live %+v", got, err, info)
+	}
+	if res, err := Logout(ctx, &cfg, replay); err != nil || !res.OK {
+		t.Errorf("replayed logout = %+v, %v", res, err)
+	}
+	if _, err := UnbindMAC(ctx, &cfg, "", replay); err == nil {
+		t.Error("request missing from the recording was answered")
+	}
+}
@@ -0,0 +1,243 @@
+package portal
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"CUMT-autologin/internal/config"
+)
+
+var (
+	// ErrSelfServiceNotConfigured is returned when self_service.url is empty.
+	ErrSelfServiceNotConfigured = errors.New("portal: self_service.url not configured")
+	// ErrSelfServiceLogin is returned when the self-service system rejects the credentials.
+	ErrSelfServiceLogin = errors.New("portal: self-service login failed")
+	// ErrNoKickableSession is returned by KickOldest when only this machine is online.
+	ErrNoKickableSession = errors.New("portal: no other session to kick")
+)
+
+// OnlineSession is one device logged in with the account.
+type OnlineSession struct {
+	ID        string    `json:"id"`
+	IP        string    `json:"ip"`
+	MAC       string    `json:"mac"`
+	Host      string    `json:"host"`
+	LoginTime time.Time `json:"login_time"`
+	UsedBytes int64     `json:"used_bytes"`
+	// Local is true when the MAC belongs to this machine.
+	Local bool `json:"local"`
+}
+
+// SelfService is a client of the Dr.COM self-service system (/Self). It keeps
+// the login cookie between calls.
+type SelfService struct {
+	base     string
+	username string
+	password string
+	opts     *Options
+	client   *http.Client
+	loggedIn bool
+}
+
+// NewSelfService returns a client for cfg using the given credentials. It
+// always keeps its own cookie jar unless opts provides one.
+func NewSelfService(cfg config.SelfServiceConfig, username, password string, opts *Options) (*SelfService, error) {
+	opts = opts.orDefault()
+	if cfg.URL == "" {
+		return nil, ErrSelfServiceNotConfigured
+	}
+	var jar http.CookieJar = opts.Jar
+	if jar == nil {
+		j, err := cookiejar.New(nil)
+		if err != nil {
+			return nil, err
+		}
+		jar = j
+	}
+	if !cfg.PlainPassword {
+		sum := md5.Sum([]byte(password))
+		password = hex.EncodeToString(sum[:])
+	}
+	return &SelfService{
+		base:     strings.TrimRight(cfg.URL, "/"),
+		username: username,
+		password: password,
+		opts:     opts,
+		client:   opts.newClient(jar),
+	}, nil
+}
+
+var checkcodeRe = regexp.MustCompile(`name="checkcode"\s+value="([^"]*)"`)
+
+// Login signs in to the self-service system.
+func (s *SelfService) Login(ctx context.Context) error {
+	page, _, err := s.do(ctx, http.MethodGet, s.base+"/login/", nil)
+	if err != nil {
+		return err
+	}
+	form := url.Values{
+		"account":  {s.username},
+		"password": {s.password},
+		"code":     {""},
+	}
+	if m := checkcodeRe.FindStringSubmatch(page); m != nil {
+		form.Set("checkcode", m[1])
+	}
+	_, final, err := s.do(ctx, http.MethodPost, s.base+"/login/verify", form)
+	if err != nil {
+		return err
+	}
+	// A successful login redirects to the dashboard, a failed one back to /login.
+	if strings.Contains(final, "/login") {
+		return ErrSelfServiceLogin
+	}
+	s.loggedIn = true
+	return nil
+}
+
+// Sessions lists the devices currently online with the account, oldest first.
+func (s *SelfService) Sessions(ctx context.Context) ([]OnlineSession, error) {
+	if err := s.ensureLogin(ctx); err != nil {
+		return nil, err
+	}
+	body, _, err := s.do(ctx, http.MethodGet, s.base+"/dashboard/getOnlineList", nil)
+	if err != nil {
+		return nil, err
+	}
+	var rows []map[string]any
+	if err := json.Unmarshal([]byte(body), &rows); err != nil {
+		return nil, fmt.Errorf("portal: unrecognized online list: %w", err)
+	}
+	local := localMACs()
+	sessions := make([]OnlineSession, 0, len(rows))
+	for _, r := range rows {
+		sess := OnlineSession{
+			ID:        strField(r, "sessionId"),
+			IP:        strField(r, "ip"),
+			MAC:       normalizeMAC(strField(r, "mac")),
+			Host:      strField(r, "hostName"),
+			UsedBytes: int64((numField(r, "upFlow") + numField(r, "downFlow")) * 1024),
+		}
+		sess.LoginTime, _ = time.ParseInLocation("2006-01-02 15:04:05", strField(r, "loginTime"), time.Local)
+		sess.Local = local[sess.MAC]
+		sessions = append(sessions, sess)
+	}
+	sort.SliceStable(sessions, func(i, j int) bool {
+		return sessions[i].LoginTime.Before(sessions[j].LoginTime)
+	})
+	return sessions, nil
+}
+
+// Kick forces the session with the given ID offline.
+func (s *SelfService) Kick(ctx context.Context, id string) error {
+	if err := s.ensureLogin(ctx); err != nil {
+		return err
+	}
+	body, _, err := s.do(ctx, http.MethodGet, s.base+"/dashboard/tooffline?sessionid="+url.QueryEscape(id), nil)
+	if err != nil {
+		return err
+	}
+	var reply struct {
+		Success *bool  `json:"success"`
+		Message string `json:"message"`
+	}
+	if json.Unmarshal([]byte(body), &reply) == nil && reply.Success != nil && !*reply.Success {
+		return fmt.Errorf("portal: kick rejected: %s", reply.Message)
+	}
+	return nil
+}
+
+// KickOldest kicks the longest running session that is not this machine.
+func (s *SelfService) KickOldest(ctx context.Context) (*OnlineSession, error) {
+	sessions, err := s.Sessions(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, sess := range sessions {
+		if sess.Local {
+			continue
+		}
+		if err := s.Kick(ctx, sess.ID); err != nil {
+			return nil, err
+		}
+		return &sess, nil
+	}
+	return nil, ErrNoKickableSession
+}
+
+func (s *SelfService) ensureLogin(ctx context.Context) error {
+	if s.loggedIn {
+		return nil
+	}
+	return s.Login(ctx)
+}
+
+// do performs a request and returns the body and the final URL after redirects.
+func (s *SelfService) do(ctx context.Context, method, target string, form url.Values) (string, string, error) {
+	var body io.Reader
+	if form != nil {
+		body = strings.NewReader(form.Encode())
+	}
+	ctx, cancel := context.WithTimeout(ctx, 2*s.opts.timeout())
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, method, target, body)
+	if err != nil {
+		return "", "", err
+	}
+	if form != nil {
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	}
+	req.Header.Set("User-Agent", s.opts.userAgent())
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return "", "", err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return "", "", err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", "", fmt.Errorf("portal: self-service http %d", resp.StatusCode)
+	}
+	return string(data), resp.Request.URL.Path, nil
+}
+
+// localMACs returns the normalized hardware addresses of this machine.
+func localMACs() map[string]bool {
+	macs := make(map[string]bool)
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		return macs
+	}
+	for _, ifc := range ifaces {
+		if len(ifc.HardwareAddr) > 0 {
+			macs[normalizeMAC(ifc.HardwareAddr.String())] = true
+		}
+	}
+	return macs
+}
+
+// KickOldestFor signs in with the credentials of cfg and kicks the oldest
+// other session. It implements the self_service.auto_kick policy.
+func KickOldestFor(ctx context.Context, cfg *config.Config, opts *Options) (*OnlineSession, error) {
+	username, password := cfg.SelfServiceCredentials()
+	ss, err := NewSelfService(cfg.Portal.SelfService, username, password, opts)
+	if err != nil {
+		return nil, err
+	}
+	return ss.KickOldest(ctx)
+}
@@ -0,0 +1,177 @@
+package portal
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"CUMT-autologin/internal/config"
+)
+
+// ErrStatusUnsupported is returned by Status when the configured driver has
+// no status endpoint.
+var ErrStatusUnsupported = errors.New("portal: status query not supported by driver")
+
+const (
+	DriverGeneric = "generic"
+	DriverDrcom   = "drcom"
+	DriverSrun    = "srun"
+)
+
+// SessionInfo is the logged-in session as reported by the gateway.
+type SessionInfo struct {
+	Online  bool   `json:"online"`
+	Account string `json:"account"`
+	IP      string `json:"ip"`
+	IPv6    string `json:"ipv6"`
+	MAC     string `json:"mac"`
+	// UsedBytes is the traffic consumed in the current accounting period.
+	UsedBytes int64 `json:"used_bytes"`
+	// OnlineSeconds is the accumulated online time reported by the gateway.
+	OnlineSeconds int64 `json:"online_seconds"`
+	// Balance is the account balance in yuan; HasBalance is false if unknown.
+	Balance    float64 `json:"balance"`
+	HasBalance bool    `json:"has_balance"`
+}
+
+// Driver returns the normalized driver name of cfg.
+func Driver(cfg *config.PortalConfig) string {
+	switch strings.ToLower(cfg.Driver) {
+	case DriverDrcom, "dr.com", "eportal":
+		return DriverDrcom
+	case DriverSrun, "srun4k":
+		return DriverSrun
+	}
+	return DriverGeneric
+}
+
+// StatusURL returns the status endpoint for cfg, deriving the well-known
+// path from login_url when status_url is not configured.
+func StatusURL(cfg *config.PortalConfig) (string, error) {
+	if cfg.StatusURL != "" {
+		return cfg.StatusURL, nil
+	}
+	if cfg.LoginURL == "" {
+		return "", ErrEmptyURL
+	}
+	u, err := url.Parse(cfg.LoginURL)
+	if err != nil {
+		return "", err
+	}
+	switch Driver(cfg) {
+	case DriverDrcom:
+		// ePortal serves login on :801 but chkstatus on the default port.
+		return u.Scheme + "://" + u.Hostname() + "/drcom/chkstatus?callback=dr1002", nil
+	case DriverSrun:
+		return u.Scheme + "://" + u.Host + "/cgi-bin/rad_user_info?callback=jsonp", nil
+	}
+	return "", ErrStatusUnsupported
+}
+
+// Status queries the gateway for the current session of this machine.
+func Status(ctx context.Context, cfg *config.PortalConfig, opts *Options) (*SessionInfo, error) {
+	opts = opts.orDefault()
+	driver := Driver(cfg)
+	if driver == DriverGeneric && cfg.StatusURL == "" {
+		return nil, ErrStatusUnsupported
+	}
+	statusURL, err := StatusURL(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := opts.get(ctx, statusURL, cfg.Headers)
+	if err != nil {
+		return nil, err
+	}
+
+	m := jsonpPayload(body)
+	if m == nil {
+		return nil, fmt.Errorf("portal: unrecognized status response")
+	}
+	switch driver {
+	case DriverSrun:
+		return parseSrunStatus(m), nil
+	default:
+		return parseDrcomStatus(m), nil
+	}
+}
+
+// parseDrcomStatus reads a chkstatus reply. Dr.COM reports time in minutes,
+// flow in KB and fee in 1/10000 yuan.
+func parseDrcomStatus(m map[string]any) *SessionInfo {
+	info := &SessionInfo{
+		Online:  numField(m, "result") == 1,
+		Account: strField(m, "uid"),
+		IP:      strField(m, "v46ip"),
+		MAC:     strField(m, "olmac"),
+	}
+	if info.IP == "" {
+		info.IP = strField(m, "v4ip")
+	}
+	info.IPv6 = strField(m, "v6ip")
+	info.UsedBytes = int64(numField(m, "flow") * 1024)
+	info.OnlineSeconds = int64(numField(m, "time") * 60)
+	if _, ok := m["fee"]; ok {
+		info.Balance = numField(m, "fee") / 10000
+		info.HasBalance = true
+	}
+	return info
+}
+
+// parseSrunStatus reads a rad_user_info reply.
+func parseSrunStatus(m map[string]any) *SessionInfo {
+	info := &SessionInfo{
+		Online:        strField(m, "error") == "ok",
+		Account:       strField(m, "user_name"),
+		IP:            strField(m, "online_ip"),
+		IPv6:          strField(m, "online_ip6"),
+		MAC:           strField(m, "user_mac"),
+		UsedBytes:     int64(numField(m, "sum_bytes")),
+		OnlineSeconds: int64(numField(m, "sum_seconds")),
+	}
+	if _, ok := m["user_balance"]; ok {
+		info.Balance = numField(m, "user_balance")
+		info.HasBalance = true
+	}
+	return info
+}
+
+func strField(m map[string]any, key string) string {
+	switch v := m[key].(type) {
+	case string:
+		return strings.TrimSpace(v)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	}
+	return ""
+}
+
+// numField accepts both JSON numbers and numeric strings, which gateways mix freely.
+func numField(m map[string]any, key string) float64 {
+	switch v := m[key].(type) {
+	case float64:
+		return v
+	case string:
+		f, _ := strconv.ParseFloat(strings.TrimSpace(v), 64)
+		return f
+	}
+	return 0
+}
+
+// FormatBytes renders a byte count as a short human readable string.
+func FormatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for v := n / unit; v >= unit; v /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.2f %cB", float64(n)/float64(div), "KMGTPE"[exp])
+}
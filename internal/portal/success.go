@@ -0,0 +1,195 @@
+package portal
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"CUMT-autologin/internal/config"
+	"CUMT-autologin/internal/netcheck"
+)
+
+// ErrNotAccepted wraps the rule a login reply failed in CheckReply.
+var ErrNotAccepted = errors.New("portal: login not accepted")
+
+// ErrNotVerified is returned by VerifyLogin when the network still does not
+// work after the gateway accepted the login.
+var ErrNotVerified = errors.New("portal: still offline after login")
+
+const defaultVerifyTimeout = 10 * time.Second
+
+// driverFields are the success fields used when a drcom or srun portal
+// configures no rule at all.
+var driverFields = map[string]map[string]string{
+	DriverDrcom: {"result": "1"},
+	DriverSrun:  {"error": "ok"},
+}
+
+// hasRules reports whether cfg says anything about what success looks like.
+func hasRules(cfg *config.PortalConfig) bool {
+	s := &cfg.Success
+	return len(cfg.SuccessKeywords) > 0 || len(s.Status) > 0 || len(s.Regex) > 0 ||
+		len(s.Fields) > 0 || len(s.FailureKeywords) > 0 || s.Location != ""
+}
+
+// CheckReply judges a login reply against the success rules of cfg and
+// returns nil when the gateway accepted it. Without any rule, drcom and srun
+// portals check their usual result field and other portals accept anything,
+// leaving the verdict to VerifyLogin. Rules whose input r lacks (a zero
+// Status, an empty URL) are skipped.
+func CheckReply(r *Reply, cfg *config.PortalConfig) error {
+	s := cfg.Success
+	if !hasRules(cfg) {
+		s.Fields = driverFields[Driver(cfg)]
+	}
+	for _, kw := range s.FailureKeywords {
+		if kw != "" && strings.Contains(r.Body, kw) {
+			return fmt.Errorf("%w: reply contains failure keyword %q", ErrNotAccepted, kw)
+		}
+	}
+	if len(s.Status) > 0 && r.Status != 0 && !containsInt(s.Status, r.Status) {
+		return fmt.Errorf("%w: http %d, want %v", ErrNotAccepted, r.Status, s.Status)
+	}
+	if s.Location != "" && r.URL != "" {
+		re, err := regexp.Compile(s.Location)
+		if err != nil {
+			return fmt.Errorf("portal: success.location: %w", err)
+		}
+		if !re.MatchString(r.URL) {
+			// The query of a GET login carries the password.
+			shown, _, _ := strings.Cut(r.URL, "?")
+			return fmt.Errorf("%w: ended at %s, want %s", ErrNotAccepted, shown, s.Location)
+		}
+	}
+	if len(cfg.SuccessKeywords) > 0 && !containsAny(r.Body, cfg.SuccessKeywords) {
+		return fmt.Errorf("%w: no success keyword in reply", ErrNotAccepted)
+	}
+	for _, expr := range s.Regex {
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return fmt.Errorf("portal: success.regex: %w", err)
+		}
+		if !re.MatchString(r.Body) {
+			return fmt.Errorf("%w: reply does not match %s", ErrNotAccepted, expr)
+		}
+	}
+	if len(s.Fields) > 0 {
+		payload := jsonpPayload(r.Body)
+		if payload == nil {
+			return fmt.Errorf("%w: reply is not JSON", ErrNotAccepted)
+		}
+		for path, want := range s.Fields {
+			got, ok := lookupField(payload, path)
+			if !ok {
+				return fmt.Errorf("%w: no field %s in reply", ErrNotAccepted, path)
+			}
+			if got != want {
+				return fmt.Errorf("%w: %s is %q, want %q", ErrNotAccepted, path, got, want)
+			}
+		}
+	}
+	return nil
+}
+
+// ValidateRules reports the first malformed regular expression in cfg.
+func ValidateRules(cfg *config.PortalConfig) error {
+	exprs := append([]string{cfg.Success.Location}, cfg.Success.Regex...)
+	for _, expr := range cfg.Flow.Extract {
+		exprs = append(exprs, expr)
+	}
+	for _, expr := range exprs {
+		if _, err := regexp.Compile(expr); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// NeedsVerify reports whether VerifyLogin checks connectivity for cfg.
+func NeedsVerify(cfg *config.PortalConfig) bool {
+	return cfg.Success.Verify || !hasRules(cfg) && driverFields[Driver(cfg)] == nil
+}
+
+// VerifyLogin waits until the network works through the interface of opts,
+// over both families when cfg is dual stack, giving up after
+// success.verify_timeout. It returns nil at once when cfg
+// does not ask for verification.
+func VerifyLogin(ctx context.Context, cfg *config.PortalConfig, opts *Options) error {
+	if !NeedsVerify(cfg) {
+		return nil
+	}
+	timeout := defaultVerifyTimeout
+	if cfg.Success.VerifyTimeout > 0 {
+		timeout = time.Duration(cfg.Success.VerifyTimeout) * time.Second
+	}
+	deadline := time.Now().Add(timeout)
+	binding := opts.orDefault().Binding
+	for {
+		if netcheck.Check(binding).Online(cfg.DualStack) {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return ErrNotVerified
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(time.Second):
+		}
+	}
+}
+
+// lookupField follows a dotted path such as "data.list.0.ip" through a
+// decoded JSON object and renders the value the way it reads in JSON,
+// without quotes for strings.
+func lookupField(v any, path string) (string, bool) {
+	for _, key := range strings.Split(path, ".") {
+		switch node := v.(type) {
+		case map[string]any:
+			var ok bool
+			if v, ok = node[key]; !ok {
+				return "", false
+			}
+		case []any:
+			i, err := strconv.Atoi(key)
+			if err != nil || i < 0 || i >= len(node) {
+				return "", false
+			}
+			v = node[i]
+		default:
+			return "", false
+		}
+	}
+	switch val := v.(type) {
+	case string:
+		return val, true
+	case float64:
+		return strconv.FormatFloat(val, 'f', -1, 64), true
+	case nil:
+		return "null", true
+	default:
+		return fmt.Sprint(val), true
+	}
+}
+
+func containsAny(s string, keywords []string) bool {
+	for _, kw := range keywords {
+		if kw != "" && strings.Contains(s, kw) {
+			return true
+		}
+	}
+	return false
+}
+
+func containsInt(list []int, v int) bool {
+	for _, x := range list {
+		if x == v {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,155 @@
+package portal
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"CUMT-autologin/internal/config"
+)
+
+func TestLookupField(t *testing.T) {
+	payload := jsonpPayload(`dr1003({"result":1,"msg":"ok","ok":true,"none":null,"ratio":0.5,
+		"data":{"list":[{"ip":"10.1.2.3"},{"ip":"10.1.2.4"}]}})`)
+	if payload == nil {
+		t.Fatal("JSONP reply not decoded")
+	}
+	cases := []struct {
+		path, want string
+		ok         bool
+	}{
+		{"result", "1", true},
+		{"msg", "ok", true},
+		{"ok", "true", true},
+		{"none", "null", true},
+		{"ratio", "0.5", true},
+		{"data.list.1.ip", "10.1.2.4", true},
+		{"data.list.2.ip", "", false},
+		{"data.list.x", "", false},
+		{"data.list.-1", "", false},
+		{"msg.len", "", false},
+		{"missing", "", false},
+	}
+	for _, c := range cases {
+		got, ok := lookupField(payload, c.path)
+		if got != c.want || ok != c.ok {
+			t.Errorf("lookupField(%s) = %q, %v; want %q, %v", c.path, got, ok, c.want, c.ok)
+		}
+	}
+}
+
+func TestCheckReply(t *testing.T) {
+	cases := []struct {
+		name   string
+		portal config.PortalConfig
+		reply  Reply
+		ok     bool
+	}{
+		// Without rules the driver's result field decides.
+		{"drcom default", config.PortalConfig{Driver: DriverDrcom}, Reply{Body: `dr1003({"result":1})`}, true},
+		{"drcom default string", config.PortalConfig{Driver: DriverDrcom}, Reply{Body: `drSTATIC({"result":"1"})`}, true},
+		{"drcom default rejected", config.PortalConfig{Driver: DriverDrcom}, Reply{Body: `dr1003({"result":0,"msg":"x"})`}, false},
+		{"drcom default not json", config.PortalConfig{Driver: DriverDrcom}, Reply{Body: `<html>认证成功</html>`}, false},
+		{"srun default", config.PortalConfig{Driver: DriverSrun}, Reply{Body: `jQuery1({"error":"ok"})`}, true},
+		{"generic default", config.PortalConfig{Driver: DriverGeneric}, Reply{Body: `anything`}, true},
+
+		{"keyword", config.PortalConfig{SuccessKeywords: []string{"认证成功"}}, Reply{Body: `<p>认证成功</p>`}, true},
+		{"keyword missing", config.PortalConfig{SuccessKeywords: []string{"认证成功"}}, Reply{Body: `<p>密码错误</p>`}, false},
+
+		{"regex", config.PortalConfig{Success: config.SuccessRules{Regex: []string{`"result":\s*"?1`}}},
+			Reply{Body: `{"result": "1"}`}, true},
+		{"regex all must match", config.PortalConfig{Success: config.SuccessRules{Regex: []string{`result`, `^ok`}}},
+			Reply{Body: `{"result":1}`}, false},
+		{"bad regex", config.PortalConfig{Success: config.SuccessRules{Regex: []string{`(`}}},
+			Reply{Body: `(`}, false},
+
+		{"json path", config.PortalConfig{Success: config.SuccessRules{Fields: map[string]string{"data.list.0.ip": "10.1.2.3"}}},
+			Reply{Body: `{"data":{"list":[{"ip":"10.1.2.3"}]}}`}, true},
+		{"jsonp field", config.PortalConfig{Success: config.SuccessRules{Fields: map[string]string{"res": "ok", "code": "0"}}},
+			Reply{Body: `cb_123({"res":"ok","code":0})`}, true},
+		{"field mismatch", config.PortalConfig{Success: config.SuccessRules{Fields: map[string]string{"code": "0"}}},
+			Reply{Body: `cb({"code":1})`}, false},
+		{"field missing", config.PortalConfig{Success: config.SuccessRules{Fields: map[string]string{"data.ip": "x"}}},
+			Reply{Body: `{"data":{}}`}, false},
+
+		{"status", config.PortalConfig{Success: config.SuccessRules{Status: []int{200, 204}}}, Reply{Status: 204}, true},
+		{"status rejected", config.PortalConfig{Success: config.SuccessRules{Status: []int{200}}}, Reply{Status: 302}, false},
+		{"status unknown", config.PortalConfig{Success: config.SuccessRules{Status: []int{200}}}, Reply{}, true},
+
+		{"location", config.PortalConfig{Success: config.SuccessRules{Location: `/success\.jsp`}},
+			Reply{URL: "http://10.2.5.251/eportal/success.jsp?userIndex=1"}, true},
+		{"location rejected", config.PortalConfig{Success: config.SuccessRules{Location: `/success\.jsp`}},
+			Reply{URL: "http://10.2.5.251/eportal/index.jsp?user_password=hunter22"}, false},
+		{"location unknown", config.PortalConfig{Success: config.SuccessRules{Location: `/success\.jsp`}}, Reply{}, true},
+
+		// A failure keyword rejects the reply even when a success keyword,
+		// a regex and a field all match.
+		{"failure wins", config.PortalConfig{
+			SuccessKeywords: []string{`"result":1`},
+			Success: config.SuccessRules{
+				FailureKeywords: []string{"已在线"},
+				Regex:           []string{`result`},
+				Fields:          map[string]string{"result": "1"},
+			},
+		}, Reply{Body: `dr1003({"result":1,"msg":"已在线"})`}, false},
+		{"failure absent", config.PortalConfig{
+			SuccessKeywords: []string{`"result":1`},
+			Success:         config.SuccessRules{FailureKeywords: []string{"已在线"}},
+		}, Reply{Body: `dr1003({"result":1,"msg":"认证成功"})`}, true},
+	}
+	for _, c := range cases {
+		err := CheckReply(&c.reply, &c.portal)
+		if c.ok && err != nil {
+			t.Errorf("%s: %v", c.name, err)
+		}
+		if !c.ok && err == nil {
+			t.Errorf("%s: reply accepted", c.name)
+		}
+	}
+}
+
+func TestCheckReplyErrors(t *testing.T) {
+	cfg := &config.PortalConfig{
+		SuccessKeywords: []string{`"result":1`},
+		Success:         config.SuccessRules{FailureKeywords: []string{"已在线"}},
+	}
+	err := CheckReply(&Reply{Body: `{"result":1,"msg":"已在线"}`}, cfg)
+	if !errors.Is(err, ErrNotAccepted) || !strings.Contains(err.Error(), "已在线") {
+		t.Errorf("failure keyword: %v", err)
+	}
+
+	// A bad rule is a config error, not a rejected login.
+	cfg = &config.PortalConfig{Success: config.SuccessRules{Regex: []string{`[`}}}
+	if err := CheckReply(&Reply{Body: "x"}, cfg); err == nil || errors.Is(err, ErrNotAccepted) {
+		t.Errorf("bad regex: %v", err)
+	}
+
+	// The password in a GET query stays out of the message.
+	cfg = &config.PortalConfig{Success: config.SuccessRules{Location: `/ok`}}
+	err = CheckReply(&Reply{URL: "http://gw/login?user_password=hunter22"}, cfg)
+	if err == nil || strings.Contains(err.Error(), "hunter22") {
+		t.Errorf("location: %v", err)
+	}
+}
+
+func TestValidateRules(t *testing.T) {
+	cases := []struct {
+		name string
+		cfg  config.PortalConfig
+		ok   bool
+	}{
+		{"empty", config.PortalConfig{}, true},
+		{"good", config.PortalConfig{
+			Success: config.SuccessRules{Location: `/ok$`, Regex: []string{`"result":\s*1`}},
+			Flow:    config.LoginFlow{Extract: map[string]string{"token": `token=(\w+)`}},
+		}, true},
+		{"bad regex", config.PortalConfig{Success: config.SuccessRules{Regex: []string{`ok`, `(unclosed`}}}, false},
+		{"bad location", config.PortalConfig{Success: config.SuccessRules{Location: `*`}}, false},
+		{"bad extract", config.PortalConfig{Flow: config.LoginFlow{Extract: map[string]string{"token": `[`}}}, false},
+	}
+	for _, c := range cases {
+		if err := ValidateRules(&c.cfg); (err == nil) != c.ok {
+			t.Errorf("%s: ValidateRules = %v", c.name, err)
+		}
+	}
+}
@@ -0,0 +1,289 @@
+package quota
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"CUMT-autologin/internal/config"
+	"CUMT-autologin/internal/history"
+	"CUMT-autologin/internal/notify"
+	"CUMT-autologin/internal/portal"
+)
+
+const gb = 1 << 30
+
+// Report is the usage picture for the current accounting period.
+type Report struct {
+	PeriodStart time.Time `json:"period_start"`
+	PeriodEnd   time.Time `json:"period_end"`
+
+	UsedBytes   int64   `json:"used_bytes"`
+	QuotaBytes  int64   `json:"quota_bytes"`
+	UsedPercent float64 `json:"used_percent"`
+
+	// ProjectedBytes is the expected usage at PeriodEnd at the current rate.
+	ProjectedBytes int64 `json:"projected_bytes"`
+	// ExhaustAt is when the quota runs out at the current rate; zero if it
+	// lasts the whole period or no quota is configured.
+	ExhaustAt time.Time `json:"exhaust_at"`
+
+	Balance    float64 `json:"balance"`
+	HasBalance bool    `json:"has_balance"`
+}
+
+// Alert is a threshold crossing worth notifying about. Pass it to
+// Tracker.Delivered once the user has been told.
+type Alert struct {
+	Event notify.Event
+	Title string
+	Body  string
+
+	period time.Time
+	// marks are the quota thresholds the alert covers; nil for low balance.
+	marks []int
+}
+
+// Period returns the accounting period containing now for the given reset day.
+func Period(now time.Time, resetDay int) (start, end time.Time) {
+	if resetDay < 1 {
+		resetDay = 1
+	}
+	y, m, d := now.Date()
+	if d < resetDay {
+		m--
+	}
+	start = time.Date(y, m, resetDay, 0, 0, 0, 0, now.Location())
+	end = start.AddDate(0, 1, 0)
+	return start, end
+}
+
+// Project builds a report from the latest session info.
+func Project(cfg config.QuotaConfig, info *portal.SessionInfo, now time.Time) Report {
+	start, end := Period(now, cfg.ResetDay)
+	r := Report{
+		PeriodStart: start,
+		PeriodEnd:   end,
+		UsedBytes:   info.UsedBytes,
+		QuotaBytes:  int64(cfg.MonthlyGB * gb),
+		Balance:     info.Balance,
+		HasBalance:  info.HasBalance,
+	}
+	elapsed := now.Sub(start)
+	if elapsed > 0 {
+		rate := float64(r.UsedBytes) / elapsed.Seconds()
+		r.ProjectedBytes = int64(rate * end.Sub(start).Seconds())
+		if r.QuotaBytes > 0 && rate > 0 {
+			at := start.Add(time.Duration(float64(r.QuotaBytes) / rate * float64(time.Second)))
+			if at.Before(end) {
+				r.ExhaustAt = at
+			}
+		}
+	}
+	if r.QuotaBytes > 0 {
+		r.UsedPercent = float64(r.UsedBytes) * 100 / float64(r.QuotaBytes)
+	}
+	return r
+}
+
+// Summary is a one-line description used in notifications and tooltips.
+func (r Report) Summary() string {
+	s := "本期已用 " + portal.FormatBytes(r.UsedBytes)
+	if r.QuotaBytes > 0 {
+		s += fmt.Sprintf(" / %s (%.0f%%)", portal.FormatBytes(r.QuotaBytes), r.UsedPercent)
+	}
+	if r.HasBalance {
+		s += fmt.Sprintf("，余额 %.2f 元", r.Balance)
+	}
+	return s
+}
+
+// Tracker records session samples into the history and raises each quota
+// threshold and the low balance warning until it has been delivered once per
+// period. What was delivered is kept next to the history, so a restart does
+// not repeat it.
+type Tracker struct {
+	rec       *history.Recorder
+	statePath string
+
+	mu         sync.Mutex
+	loaded     bool
+	period     time.Time
+	warned     map[int]bool
+	lowBalance bool
+	lastReport Report
+	haveReport bool
+}
+
+// alertState is the on-disk form of what a Tracker has delivered.
+type alertState struct {
+	Period     time.Time `json:"period"`
+	Warned     []int     `json:"warned,omitempty"`
+	LowBalance bool      `json:"low_balance,omitempty"`
+}
+
+// NewTracker returns a tracker writing samples to the history file at path.
+func NewTracker(path string) *Tracker {
+	return &Tracker{
+		rec: &history.Recorder{
+			Path:   path,
+			MinGap: 10 * time.Minute,
+			Keep:   90 * 24 * time.Hour,
+		},
+		statePath: filepath.Join(filepath.Dir(path), "quota_alerts.json"),
+		warned:    make(map[int]bool),
+	}
+}
+
+// LastReport returns the most recent report, if any session was observed.
+func (t *Tracker) LastReport() (Report, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.lastReport, t.haveReport
+}
+
+// Observe records info and returns the alerts that are due. An alert stays
+// due on later calls until it is passed to Delivered.
+func (t *Tracker) Observe(cfg config.QuotaConfig, info *portal.SessionInfo, now time.Time) (Report, []Alert, error) {
+	var recErr error
+	if info.Online {
+		_, recErr = t.rec.Record(history.Sample{
+			Time:          now,
+			Online:        info.Online,
+			UsedBytes:     info.UsedBytes,
+			OnlineSeconds: info.OnlineSeconds,
+			Balance:       info.Balance,
+			HasBalance:    info.HasBalance,
+		})
+	}
+
+	r := Project(cfg, info, now)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.load()
+	t.lastReport, t.haveReport = r, true
+	dirty := false
+	if !t.period.Equal(r.PeriodStart) {
+		t.period = r.PeriodStart
+		t.warned = make(map[int]bool)
+		t.lowBalance = false
+		dirty = true
+	}
+
+	var alerts []Alert
+	if r.QuotaBytes > 0 {
+		percents := append([]int(nil), cfg.WarnPercents...)
+		sort.Sort(sort.Reverse(sort.IntSlice(percents)))
+		for _, p := range percents {
+			if r.UsedPercent < float64(p) {
+				continue
+			}
+			// Crossing a high threshold implies the lower ones.
+			var marks []int
+			for _, q := range percents {
+				if q <= p {
+					marks = append(marks, q)
+				}
+			}
+			if !t.warned[p] {
+				body := r.Summary()
+				if !r.ExhaustAt.IsZero() {
+					body += "\n预计 " + r.ExhaustAt.Format("01-02 15:04") + " 用尽"
+				}
+				alerts = append(alerts, Alert{
+					Event:  notify.EventQuota,
+					Title:  fmt.Sprintf("校园网流量已用 %d%%", p),
+					Body:   body,
+					period: t.period,
+					marks:  marks,
+				})
+			} else {
+				for _, q := range marks {
+					dirty = dirty || !t.warned[q]
+					t.warned[q] = true
+				}
+			}
+			break
+		}
+	}
+
+	if cfg.MinBalance > 0 && r.HasBalance {
+		switch {
+		case r.Balance < cfg.MinBalance && !t.lowBalance:
+			alerts = append(alerts, Alert{
+				Event:  notify.EventLowBalance,
+				Title:  "校园网余额不足",
+				Body:   fmt.Sprintf("当前余额 %.2f 元，低于 %.2f 元", r.Balance, cfg.MinBalance),
+				period: t.period,
+			})
+		case r.Balance >= cfg.MinBalance && t.lowBalance:
+			t.lowBalance = false
+			dirty = true
+		}
+	}
+	if dirty {
+		recErr = errors.Join(recErr, t.save())
+	}
+	return r, alerts, recErr
+}
+
+// Delivered records that a has reached the user, so Observe stops raising it
+// for the rest of the period.
+func (t *Tracker) Delivered(a Alert) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if !t.period.Equal(a.period) {
+		return nil
+	}
+	if a.Event == notify.EventLowBalance {
+		t.lowBalance = true
+	}
+	for _, q := range a.marks {
+		t.warned[q] = true
+	}
+	return t.save()
+}
+
+// load reads the delivered alerts once; a missing or damaged file starts over.
+func (t *Tracker) load() {
+	if t.loaded {
+		return
+	}
+	t.loaded = true
+	data, err := os.ReadFile(t.statePath)
+	if err != nil {
+		return
+	}
+	var st alertState
+	if json.Unmarshal(data, &st) != nil {
+		return
+	}
+	t.period = st.Period
+	t.lowBalance = st.LowBalance
+	for _, p := range st.Warned {
+		t.warned[p] = true
+	}
+}
+
+func (t *Tracker) save() error {
+	st := alertState{Period: t.period, LowBalance: t.lowBalance}
+	for p := range t.warned {
+		st.Warned = append(st.Warned, p)
+	}
+	sort.Ints(st.Warned)
+	data, err := json.MarshalIndent(st, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := t.statePath + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, t.statePath)
+}
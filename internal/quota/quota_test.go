@@ -0,0 +1,92 @@
+package quota
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"CUMT-autologin/internal/config"
+	"CUMT-autologin/internal/portal"
+)
+
+var testQuota = config.QuotaConfig{MonthlyGB: 10, ResetDay: 1, WarnPercents: []int{80, 95}}
+
+func usage(percent float64) *portal.SessionInfo {
+	return &portal.SessionInfo{Online: true, UsedBytes: int64(percent / 100 * 10 * gb)}
+}
+
+func TestAlertRepeatsUntilDelivered(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.jsonl")
+	now := time.Date(2024, 3, 20, 12, 0, 0, 0, time.Local)
+
+	tr := NewTracker(path)
+	_, alerts, err := tr.Observe(testQuota, usage(85), now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(alerts) != 1 || alerts[0].Title != "校园网流量已用 80%" {
+		t.Fatalf("alerts = %+v", alerts)
+	}
+	// Held back by the rate limit: still due on the next tick.
+	_, again, _ := tr.Observe(testQuota, usage(85), now.Add(time.Minute))
+	if len(again) != 1 {
+		t.Fatalf("undelivered alert not raised again: %+v", again)
+	}
+	if err := tr.Delivered(again[0]); err != nil {
+		t.Fatal(err)
+	}
+	if _, after, _ := tr.Observe(testQuota, usage(86), now.Add(2*time.Minute)); len(after) != 0 {
+		t.Fatalf("delivered alert raised again: %+v", after)
+	}
+
+	// A restarted process reads what was delivered.
+	tr = NewTracker(path)
+	if _, after, _ := tr.Observe(testQuota, usage(86), now.Add(3*time.Minute)); len(after) != 0 {
+		t.Fatalf("alert repeated after restart: %+v", after)
+	}
+	_, high, _ := tr.Observe(testQuota, usage(96), now.Add(4*time.Minute))
+	if len(high) != 1 || high[0].Title != "校园网流量已用 95%" {
+		t.Fatalf("alerts = %+v", high)
+	}
+
+	// The next period starts over.
+	if _, next, _ := tr.Observe(testQuota, usage(85), now.AddDate(0, 1, 0)); len(next) != 1 {
+		t.Fatalf("new period alerts = %+v", next)
+	}
+}
+
+func TestCrossingHighThresholdCoversLowerOnes(t *testing.T) {
+	tr := NewTracker(filepath.Join(t.TempDir(), "history.jsonl"))
+	now := time.Date(2024, 3, 20, 12, 0, 0, 0, time.Local)
+	_, alerts, _ := tr.Observe(testQuota, usage(97), now)
+	if len(alerts) != 1 || alerts[0].Title != "校园网流量已用 95%" {
+		t.Fatalf("alerts = %+v", alerts)
+	}
+	tr.Delivered(alerts[0])
+	// Usage reported lower again (e.g. after a correction) stays quiet.
+	if _, again, _ := tr.Observe(testQuota, usage(85), now.Add(time.Minute)); len(again) != 0 {
+		t.Fatalf("alerts = %+v", again)
+	}
+}
+
+func TestLowBalanceRearmsAfterTopUp(t *testing.T) {
+	cfg := config.QuotaConfig{MinBalance: 5}
+	tr := NewTracker(filepath.Join(t.TempDir(), "history.jsonl"))
+	now := time.Date(2024, 3, 20, 12, 0, 0, 0, time.Local)
+	info := &portal.SessionInfo{Online: true, Balance: 2, HasBalance: true}
+
+	_, alerts, _ := tr.Observe(cfg, info, now)
+	if len(alerts) != 1 {
+		t.Fatalf("alerts = %+v", alerts)
+	}
+	tr.Delivered(alerts[0])
+	if _, again, _ := tr.Observe(cfg, info, now.Add(time.Minute)); len(again) != 0 {
+		t.Fatalf("alerts = %+v", again)
+	}
+	info.Balance = 20
+	tr.Observe(cfg, info, now.Add(2*time.Minute))
+	info.Balance = 1
+	if _, again, _ := tr.Observe(cfg, info, now.Add(3*time.Minute)); len(again) != 1 {
+		t.Fatalf("low balance not raised after top-up: %+v", again)
+	}
+}
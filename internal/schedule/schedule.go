@@ -0,0 +1,191 @@
+package schedule
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"CUMT-autologin/internal/config"
+)
+
+// Clock abstracts time.Now so the login loops can be driven by a fake clock.
+type Clock interface {
+	Now() time.Time
+}
+
+// SystemClock is the real wall clock.
+type SystemClock struct{}
+
+func (SystemClock) Now() time.Time { return time.Now() }
+
+// Decision is what the schedule says the login loop should do right now.
+type Decision struct {
+	// Active is false during quiet hours: no probing and no login attempts.
+	Active bool
+	// ForceLogin allows the periodic keepalive action while online.
+	ForceLogin bool
+	// Interval overrides auto_login_interval when non-zero.
+	Interval time.Duration
+	// Window is the name of the matching window, empty if none matched.
+	Window string
+}
+
+// Evaluate returns the decision for now. The first matching window wins;
+// without a match auto-login is active with the default behaviour.
+// Invalid windows are skipped, see Validate.
+func Evaluate(cfg config.ScheduleConfig, now time.Time) Decision {
+	d := Decision{Active: true, ForceLogin: true}
+	for i, w := range cfg.Windows {
+		ok, err := matches(w, now)
+		if err != nil || !ok {
+			continue
+		}
+		d.Window = w.Name
+		if d.Window == "" {
+			d.Window = fmt.Sprintf("#%d", i+1)
+		}
+		if w.AutoLogin != nil {
+			d.Active = *w.AutoLogin
+		}
+		if w.ForceLogin != nil {
+			d.ForceLogin = *w.ForceLogin
+		}
+		if w.Interval > 0 {
+			d.Interval = time.Duration(w.Interval) * time.Second
+		}
+		return d
+	}
+	return d
+}
+
+// Validate reports the first malformed window, if any.
+func Validate(cfg config.ScheduleConfig) error {
+	for i, w := range cfg.Windows {
+		if _, err := matches(w, time.Time{}); err != nil {
+			return fmt.Errorf("schedule window %d (%s): %w", i+1, w.Name, err)
+		}
+	}
+	return nil
+}
+
+// Describe renders a decision for the status line.
+func (d Decision) Describe() string {
+	if d.Active {
+		return "自动登录运行中"
+	}
+	if d.Window != "" {
+		return "计划停用中（" + d.Window + "）"
+	}
+	return "计划停用中"
+}
+
+func matches(w config.ScheduleWindow, now time.Time) (bool, error) {
+	start, err := parseClock(w.Start, 0)
+	if err != nil {
+		return false, fmt.Errorf("start: %w", err)
+	}
+	end, err := parseClock(w.End, 24*60)
+	if err != nil {
+		return false, fmt.Errorf("end: %w", err)
+	}
+	days, err := parseDays(w.Days)
+	if err != nil {
+		return false, err
+	}
+
+	minute := now.Hour()*60 + now.Minute()
+	day := now.Weekday()
+	if start <= end {
+		return days[day] && minute >= start && minute < end, nil
+	}
+	// Overnight window such as 23:30-06:00: the part after midnight belongs
+	// to the day the window started on.
+	if minute >= start {
+		return days[day], nil
+	}
+	if minute < end {
+		return days[(day+6)%7], nil
+	}
+	return false, nil
+}
+
+// parseClock parses "HH:MM" into minutes after midnight; empty means def.
+func parseClock(s string, def int) (int, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return def, nil
+	}
+	hh, mm, ok := strings.Cut(s, ":")
+	if !ok || len(hh) < 1 || len(hh) > 2 || len(mm) != 2 || !isDigits(hh) || !isDigits(mm) {
+		return 0, fmt.Errorf("invalid time %q, want HH:MM", s)
+	}
+	h, _ := strconv.Atoi(hh)
+	m, _ := strconv.Atoi(mm)
+	if h > 24 || m > 59 || (h == 24 && m != 0) {
+		return 0, fmt.Errorf("invalid time %q, out of range", s)
+	}
+	return h*60 + m, nil
+}
+
+func isDigits(s string) bool {
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+var dayNames = map[string]time.Weekday{
+	"sun": time.Sunday, "mon": time.Monday, "tue": time.Tuesday, "wed": time.Wednesday,
+	"thu": time.Thursday, "fri": time.Friday, "sat": time.Saturday,
+}
+
+// parseDays accepts day names (mon, tue...), ranges (mon-fri) and the
+// shortcuts "weekdays" / "weekends". An empty list means every day.
+func parseDays(list []string) ([7]bool, error) {
+	var days [7]bool
+	if len(list) == 0 {
+		for i := range days {
+			days[i] = true
+		}
+		return days, nil
+	}
+	for _, item := range list {
+		item = strings.ToLower(strings.TrimSpace(item))
+		switch item {
+		case "weekdays":
+			item = "mon-fri"
+		case "weekends":
+			item = "sat-sun"
+		case "daily", "everyday", "*":
+			item = "sun-sat"
+		}
+		from, to, isRange := strings.Cut(item, "-")
+		a, ok := dayNames[shortDay(from)]
+		if !ok {
+			return days, fmt.Errorf("invalid day %q", item)
+		}
+		b := a
+		if isRange {
+			if b, ok = dayNames[shortDay(to)]; !ok {
+				return days, fmt.Errorf("invalid day %q", item)
+			}
+		}
+		for d := a; ; d = (d + 1) % 7 {
+			days[d] = true
+			if d == b {
+				break
+			}
+		}
+	}
+	return days, nil
+}
+
+func shortDay(s string) string {
+	if len(s) > 3 {
+		return s[:3]
+	}
+	return s
+}
@@ -0,0 +1,125 @@
+package schedule
+
+import (
+	"testing"
+	"time"
+
+	"CUMT-autologin/internal/config"
+)
+
+// fakeClock is a Clock that returns a fixed time.
+type fakeClock struct{ t time.Time }
+
+func (c *fakeClock) Now() time.Time { return c.t }
+
+func boolPtr(b bool) *bool { return &b }
+
+func at(loc *time.Location, year int, month time.Month, day, hour, min int) *fakeClock {
+	return &fakeClock{time.Date(year, month, day, hour, min, 0, 0, loc)}
+}
+
+func TestOvernightWindow(t *testing.T) {
+	cfg := config.ScheduleConfig{Windows: []config.ScheduleWindow{
+		{Name: "night", Start: "23:30", End: "06:00", Days: []string{"mon-thu"}, AutoLogin: boolPtr(false)},
+	}}
+	// 2024-03-04 is a Monday.
+	tests := []struct {
+		clock  *fakeClock
+		active bool
+	}{
+		{at(time.UTC, 2024, 3, 4, 23, 29), true},
+		{at(time.UTC, 2024, 3, 4, 23, 30), false},
+		{at(time.UTC, 2024, 3, 5, 5, 59), false}, // Tuesday morning, started Monday
+		{at(time.UTC, 2024, 3, 5, 6, 0), true},
+		{at(time.UTC, 2024, 3, 4, 3, 0), true},  // Monday morning, started Sunday
+		{at(time.UTC, 2024, 3, 8, 2, 0), false}, // Friday morning, started Thursday
+		{at(time.UTC, 2024, 3, 8, 23, 45), true},
+	}
+	for _, tt := range tests {
+		d := Evaluate(cfg, tt.clock.Now())
+		if d.Active != tt.active {
+			t.Errorf("%s: Active = %v, want %v", tt.clock.Now().Format("Mon 15:04"), d.Active, tt.active)
+		}
+		if !d.Active && d.Window != "night" {
+			t.Errorf("%s: Window = %q", tt.clock.Now().Format("Mon 15:04"), d.Window)
+		}
+	}
+}
+
+func TestWeekdayShortcuts(t *testing.T) {
+	cfg := config.ScheduleConfig{Windows: []config.ScheduleWindow{
+		{Start: "08:00", End: "12:00", Days: []string{"weekdays"}, Interval: 30, ForceLogin: boolPtr(false)},
+		{Days: []string{"weekends"}, AutoLogin: boolPtr(false)},
+	}}
+	clock := at(time.UTC, 2024, 3, 6, 9, 0) // Wednesday
+	d := Evaluate(cfg, clock.Now())
+	if !d.Active || d.ForceLogin || d.Interval != 30*time.Second || d.Window != "#1" {
+		t.Errorf("weekday morning: %+v", d)
+	}
+	clock.t = clock.t.Add(4 * time.Hour)
+	if d := Evaluate(cfg, clock.Now()); !d.Active || !d.ForceLogin || d.Window != "" {
+		t.Errorf("weekday afternoon: %+v", d)
+	}
+	clock.t = clock.t.AddDate(0, 0, 3) // Saturday
+	if d := Evaluate(cfg, clock.Now()); d.Active || d.Window != "#2" {
+		t.Errorf("weekend: %+v", d)
+	}
+}
+
+func TestWallClockAcrossDST(t *testing.T) {
+	loc, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skip("no tz database:", err)
+	}
+	cfg := config.ScheduleConfig{Windows: []config.ScheduleWindow{
+		{Start: "01:00", End: "03:00", AutoLogin: boolPtr(false)},
+	}}
+	// On 2024-03-10 clocks jump from 02:00 to 03:00 local time.
+	clock := at(loc, 2024, 3, 10, 1, 30)
+	if Evaluate(cfg, clock.Now()).Active {
+		t.Error("01:30 should be inside the window")
+	}
+	clock.t = clock.t.Add(30 * time.Minute) // 03:00 EDT
+	if got := clock.Now().Format("15:04"); got != "03:00" {
+		t.Fatalf("clock reads %s", got)
+	}
+	if !Evaluate(cfg, clock.Now()).Active {
+		t.Error("03:00 after the jump should be outside the window")
+	}
+
+	// On 2024-11-03 01:00-02:00 repeats; both passes are inside.
+	clock = at(loc, 2024, 11, 3, 1, 30)
+	clock.t = clock.t.Add(time.Hour)
+	if got := clock.Now().Format("15:04"); got != "01:30" {
+		t.Fatalf("clock reads %s", got)
+	}
+	if Evaluate(cfg, clock.Now()).Active {
+		t.Error("repeated 01:30 should be inside the window")
+	}
+}
+
+func TestParseClockStrict(t *testing.T) {
+	valid := map[string]int{"": 7, "0:00": 0, "07:05": 425, "23:59": 1439, "24:00": 1440, " 6:30 ": 390}
+	for s, want := range valid {
+		got, err := parseClock(s, 7)
+		if err != nil || got != want {
+			t.Errorf("parseClock(%q) = %d, %v; want %d", s, got, err, want)
+		}
+	}
+	for _, s := range []string{"7", "7:5", "07:60", "25:00", "24:01", "-1:00", "+7:00", "07:00am", "07:00:00", "07h00", "0x7:00", "123:00"} {
+		if _, err := parseClock(s, 0); err == nil {
+			t.Errorf("parseClock(%q) accepted", s)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	bad := config.ScheduleConfig{Windows: []config.ScheduleWindow{{Name: "x", Start: "8:00pm"}}}
+	if err := Validate(bad); err == nil {
+		t.Error("Validate accepted 8:00pm")
+	}
+	bad.Windows[0] = config.ScheduleWindow{Days: []string{"funday"}}
+	if err := Validate(bad); err == nil {
+		t.Error("Validate accepted funday")
+	}
+}
@@ -0,0 +1,191 @@
+package trayicon
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"image"
+	"image/color"
+	"image/png"
+	"sync"
+)
+
+// State is the connection state shown by the tray icon.
+type State int
+
+const (
+	StateUnknown State = iota
+	StateOnline
+	StateCaptive   // connected to campus network but not authenticated
+	StateLoggingIn // a login request is in flight
+	StateFailed
+	StatePaused
+)
+
+func (s State) String() string {
+	switch s {
+	case StateOnline:
+		return "在线"
+	case StateCaptive:
+		return "未认证"
+	case StateLoggingIn:
+		return "登录中"
+	case StateFailed:
+		return "登录失败"
+	case StatePaused:
+		return "已暂停"
+	}
+	return "检测中"
+}
+
+const iconSize = 64
+
+var errBadICO = errors.New("trayicon: no PNG image in icon")
+
+var badgeColors = map[State]color.NRGBA{
+	StateOnline:    {R: 0x22, G: 0xc5, B: 0x5e, A: 0xff},
+	StateCaptive:   {R: 0xf5, G: 0x9e, B: 0x0b, A: 0xff},
+	StateLoggingIn: {R: 0x38, G: 0xbd, B: 0xf8, A: 0xff},
+	StateFailed:    {R: 0xef, G: 0x44, B: 0x44, A: 0xff},
+	StatePaused:    {R: 0x9c, G: 0xa3, B: 0xaf, A: 0xff},
+}
+
+var (
+	cacheMu sync.Mutex
+	cache   = map[State][]byte{}
+)
+
+// Icon returns an .ico image for the given state: the base icon scaled down
+// with a coloured status badge in the bottom-right corner. base must be an
+// .ico file with a PNG payload (as shipped in assets/icon.ico); on failure the
+// base bytes are returned unchanged. Results are cached per state, so callers
+// must always pass the same base icon.
+func Icon(base []byte, s State) []byte {
+	cacheMu.Lock()
+	defer cacheMu.Unlock()
+	if data, ok := cache[s]; ok {
+		return data
+	}
+
+	src, err := decodeICO(base)
+	if err != nil {
+		return base
+	}
+	img := scale(src, iconSize)
+	if c, ok := badgeColors[s]; ok {
+		drawBadge(img, c)
+	}
+	data, err := encodeICO(img)
+	if err != nil {
+		return base
+	}
+	cache[s] = data
+	return data
+}
+
+// decodeICO returns the largest PNG image stored in an .ico file.
+func decodeICO(data []byte) (image.Image, error) {
+	if len(data) < 6 {
+		return nil, errBadICO
+	}
+	count := int(binary.LittleEndian.Uint16(data[4:6]))
+	var best image.Image
+	bestSize := 0
+	for i := 0; i < count; i++ {
+		off := 6 + i*16
+		if off+16 > len(data) {
+			break
+		}
+		size := int(binary.LittleEndian.Uint32(data[off+8 : off+12]))
+		start := int(binary.LittleEndian.Uint32(data[off+12 : off+16]))
+		if start < 0 || size <= 0 || start+size > len(data) {
+			continue
+		}
+		img, err := png.Decode(bytes.NewReader(data[start : start+size]))
+		if err != nil {
+			continue // BMP payloads are not supported
+		}
+		if w := img.Bounds().Dx(); w > bestSize {
+			best, bestSize = img, w
+		}
+	}
+	if best == nil {
+		return nil, errBadICO
+	}
+	return best, nil
+}
+
+func encodeICO(img image.Image) ([]byte, error) {
+	var pngBuf bytes.Buffer
+	if err := png.Encode(&pngBuf, img); err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	w := img.Bounds().Dx()
+	h := img.Bounds().Dy()
+	hdr := []any{
+		uint16(0), uint16(1), uint16(1), // reserved, type=icon, count
+		uint8(w % 256), uint8(h % 256), uint8(0), uint8(0), // 0 means 256
+		uint16(1), uint16(32), uint32(pngBuf.Len()), uint32(6 + 16),
+	}
+	for _, v := range hdr {
+		_ = binary.Write(&buf, binary.LittleEndian, v)
+	}
+	buf.Write(pngBuf.Bytes())
+	return buf.Bytes(), nil
+}
+
+// scale box-filters src down to a size x size image.
+func scale(src image.Image, size int) *image.NRGBA {
+	b := src.Bounds()
+	dst := image.NewNRGBA(image.Rect(0, 0, size, size))
+	for y := 0; y < size; y++ {
+		y0 := b.Min.Y + y*b.Dy()/size
+		y1 := b.Min.Y + (y+1)*b.Dy()/size
+		for x := 0; x < size; x++ {
+			x0 := b.Min.X + x*b.Dx()/size
+			x1 := b.Min.X + (x+1)*b.Dx()/size
+			var r, g, bl, a, n uint64
+			for sy := y0; sy < max(y1, y0+1); sy++ {
+				for sx := x0; sx < max(x1, x0+1); sx++ {
+					c := color.NRGBA64Model.Convert(src.At(sx, sy)).(color.NRGBA64)
+					r += uint64(c.R)
+					g += uint64(c.G)
+					bl += uint64(c.B)
+					a += uint64(c.A)
+					n++
+				}
+			}
+			dst.SetNRGBA(x, y, color.NRGBA{
+				R: uint8(r / n >> 8),
+				G: uint8(g / n >> 8),
+				B: uint8(bl / n >> 8),
+				A: uint8(a / n >> 8),
+			})
+		}
+	}
+	return dst
+}
+
+// drawBadge paints a filled circle with a white ring in the bottom-right corner.
+func drawBadge(img *image.NRGBA, c color.NRGBA) {
+	size := img.Bounds().Dx()
+	radius := float64(size) * 0.22
+	ring := radius + float64(size)*0.05
+	cx := float64(size) - ring
+	cy := float64(size) - ring
+	white := color.NRGBA{R: 0xff, G: 0xff, B: 0xff, A: 0xff}
+	for y := 0; y < size; y++ {
+		for x := 0; x < size; x++ {
+			dx := float64(x) + 0.5 - cx
+			dy := float64(y) + 0.5 - cy
+			d2 := dx*dx + dy*dy
+			switch {
+			case d2 <= radius*radius:
+				img.SetNRGBA(x, y, c)
+			case d2 <= ring*ring:
+				img.SetNRGBA(x, y, white)
+			}
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package trayicon
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestIconPerState(t *testing.T) {
+	src := image.NewNRGBA(image.Rect(0, 0, 128, 128))
+	for i := range src.Pix {
+		src.Pix[i] = 0x80
+	}
+	base, err := encodeICO(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	states := []State{StateUnknown, StateOnline, StateCaptive, StateLoggingIn, StateFailed, StatePaused}
+	icons := make(map[string]State)
+	names := make(map[string]State)
+	for _, s := range states {
+		data := Icon(base, s)
+		if len(data) == 0 || bytes.Equal(data, base) {
+			t.Errorf("%v: icon not rendered", s)
+		}
+		img, err := decodeICO(data)
+		if err != nil {
+			t.Errorf("%v: %v", s, err)
+		} else if img.Bounds().Dx() != iconSize {
+			t.Errorf("%v: icon is %dpx, want %d", s, img.Bounds().Dx(), iconSize)
+		}
+		if prev, dup := icons[string(data)]; dup {
+			t.Errorf("%v and %v share an icon", s, prev)
+		}
+		icons[string(data)] = s
+		if prev, dup := names[s.String()]; dup || s.String() == "" {
+			t.Errorf("%v: name %q clashes with %v", int(s), s.String(), prev)
+		}
+		names[s.String()] = s
+	}
+}
+
+func TestBadgeColorsDistinct(t *testing.T) {
+	seen := make(map[color.NRGBA]State)
+	for s, c := range badgeColors {
+		if prev, dup := seen[c]; dup {
+			t.Errorf("%v and %v share badge colour %v", s, prev, c)
+		}
+		seen[c] = s
+	}
+}
+
+func TestDecodeICOBad(t *testing.T) {
+	for _, data := range [][]byte{nil, []byte("not an icon"), {0, 0, 1, 0, 1, 0}} {
+		if _, err := decodeICO(data); err != errBadICO {
+			t.Errorf("decodeICO(%q) = %v, want errBadICO", data, err)
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package trayicon
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// maxTooltipRunes matches the 128 UTF-16 unit szTip buffer of NOTIFYICONDATA.
+const maxTooltipRunes = 127
+
+// Info is what the tray shows about the current connection.
+type Info struct {
+	State       State
+	Text        string
+	SSID        string
+	Account     string
+	OnlineSince time.Time
+	LastCheck   time.Time
+}
+
+// Update records a new status, keeping OnlineSince across re-logins and
+// pauses but resetting it whenever the connection is actually lost.
+func (i *Info) Update(s State, text string, now time.Time) {
+	switch s {
+	case StateOnline:
+		if i.OnlineSince.IsZero() {
+			i.OnlineSince = now
+		}
+	case StateCaptive, StateFailed:
+		i.OnlineSince = time.Time{}
+	}
+	i.State = s
+	i.Text = text
+	i.LastCheck = now
+}
+
+// Tooltip renders info as a multi-line tray tooltip.
+func Tooltip(info Info, now time.Time) string {
+	lines := []string{"CUMT 校园网自动登录"}
+
+	status := info.State.String()
+	if info.Text != "" && info.Text != status {
+		status = info.Text
+	}
+	lines = append(lines, "状态: "+status)
+	if info.SSID != "" {
+		lines = append(lines, "WiFi: "+info.SSID)
+	}
+	if info.Account != "" {
+		lines = append(lines, "账号: "+info.Account)
+	}
+	if info.State == StateOnline && !info.OnlineSince.IsZero() {
+		lines = append(lines, "在线时长: "+formatDuration(now.Sub(info.OnlineSince)))
+	}
+	if !info.LastCheck.IsZero() {
+		lines = append(lines, "上次检测: "+info.LastCheck.Format("15:04:05"))
+	}
+
+	tip := strings.Join(lines, "\n")
+	if r := []rune(tip); len(r) > maxTooltipRunes {
+		tip = string(r[:maxTooltipRunes])
+	}
+	return tip
+}
+
+func formatDuration(d time.Duration) string {
+	d = d.Round(time.Minute)
+	h := int(d.Hours())
+	m := int(d.Minutes()) % 60
+	if h > 0 {
+		return fmt.Sprintf("%d 小时 %d 分", h, m)
+	}
+	return fmt.Sprintf("%d 分钟", m)
+}
@@ -0,0 +1,189 @@
+package wifi
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	netshPair   = regexp.MustCompile(`^\s*([^:]+?)\s*:\s?(.*)$`)
+	netshGUID   = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+	netshSignal = regexp.MustCompile(`^(\d{1,3})\s*%$`)
+)
+
+// netshStates maps the localized "State" values of netsh to our states.
+var netshStates = map[string]string{
+	"connected":      StateConnected,
+	"已连接":            StateConnected,
+	"已連線":            StateConnected,
+	"disconnected":   StateDisconnected,
+	"已断开连接":          StateDisconnected,
+	"已中斷連線":          StateDisconnected,
+	"associating":    StateConnecting,
+	"authenticating": StateConnecting,
+	"discovering":    StateConnecting,
+	"正在关联":           StateConnecting,
+	"正在进行身份验证":       StateConnecting,
+}
+
+// parseNetsh reads "netsh wlan show interfaces". Only SSID and BSSID keep
+// their English names in every locale, so the rest is found by shape: each
+// adapter is a block of "key : value" lines holding a GUID, whose first
+// two values are the name and the description.
+func parseNetsh(out string) []Interface {
+	out = strings.ReplaceAll(out, "\r\n", "\n")
+	var list []Interface
+	for _, block := range strings.Split(out, "\n\n") {
+		var (
+			ifc     Interface
+			values  []string
+			hasGUID bool
+			state   string
+		)
+		for _, line := range strings.Split(block, "\n") {
+			m := netshPair.FindStringSubmatch(line)
+			if m == nil {
+				continue
+			}
+			key, value := strings.TrimSpace(m[1]), strings.TrimSpace(m[2])
+			values = append(values, value)
+			switch {
+			case strings.EqualFold(key, "SSID"):
+				ifc.SSID = value
+			case strings.HasSuffix(strings.ToUpper(key), "BSSID"):
+				ifc.BSSID = strings.ToLower(value)
+			case netshGUID.MatchString(value):
+				hasGUID = true
+			case netshSignal.MatchString(value) && ifc.Signal == 0:
+				ifc.Signal, _ = strconv.Atoi(netshSignal.FindStringSubmatch(value)[1])
+			}
+			if s, ok := netshStates[strings.ToLower(value)]; ok && state == "" {
+				state = s
+			}
+		}
+		if !hasGUID || len(values) < 2 {
+			continue
+		}
+		ifc.Name, ifc.Description = values[0], values[1]
+		switch {
+		case ifc.SSID != "":
+			ifc.State = StateConnected
+		case state != "":
+			ifc.State = state
+		default:
+			ifc.State = StateDisconnected
+		}
+		list = append(list, ifc)
+	}
+	return list
+}
+
+// parseIwDev reads "iw dev": the wireless interfaces and, for connected
+// ones, the SSID.
+func parseIwDev(out string) []Interface {
+	var list []Interface
+	for _, line := range strings.Split(out, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			continue
+		}
+		switch fields[0] {
+		case "Interface":
+			list = append(list, Interface{Name: fields[1], State: StateDisconnected})
+		case "ssid":
+			if len(list) > 0 {
+				cur := &list[len(list)-1]
+				cur.SSID = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(line), "ssid"))
+				cur.State = StateConnected
+			}
+		}
+	}
+	return list
+}
+
+// parseIwLink fills BSSID, SSID and signal from "iw dev <name> link".
+func parseIwLink(out string, ifc *Interface) {
+	for _, line := range strings.Split(out, "\n") {
+		line = strings.TrimSpace(line)
+		switch {
+		case strings.HasPrefix(line, "Not connected"):
+			ifc.State = StateDisconnected
+			return
+		case strings.HasPrefix(line, "Connected to "):
+			if fields := strings.Fields(line); len(fields) >= 3 {
+				ifc.BSSID = strings.ToLower(fields[2])
+			}
+			ifc.State = StateConnected
+		case strings.HasPrefix(line, "SSID:"):
+			ifc.SSID = strings.TrimSpace(strings.TrimPrefix(line, "SSID:"))
+		case strings.HasPrefix(line, "signal:"):
+			fields := strings.Fields(strings.TrimPrefix(line, "signal:"))
+			if len(fields) > 0 {
+				if dbm, err := strconv.Atoi(fields[0]); err == nil {
+					ifc.Signal = qualityFromDBM(dbm)
+				}
+			}
+		}
+	}
+}
+
+// parseNmcli reads the terse output of "nmcli -t -f DEVICE,TYPE,STATE device
+// status" and "nmcli -t -f ACTIVE,SSID,BSSID,SIGNAL,DEVICE device wifi list",
+// both run in the C locale.
+func parseNmcli(devices, aps string) []Interface {
+	var list []Interface
+	for _, line := range strings.Split(devices, "\n") {
+		f := splitTerse(line)
+		if len(f) < 3 || f[1] != "wifi" {
+			continue
+		}
+		state := StateDisconnected
+		switch {
+		case strings.HasPrefix(f[2], "connected"):
+			state = StateConnected
+		case strings.HasPrefix(f[2], "connecting"):
+			state = StateConnecting
+		}
+		list = append(list, Interface{Name: f[0], State: state})
+	}
+	for _, line := range strings.Split(aps, "\n") {
+		f := splitTerse(line)
+		if len(f) < 5 || f[0] != "yes" {
+			continue
+		}
+		for i := range list {
+			if list[i].Name == f[4] {
+				list[i].SSID, list[i].BSSID = f[1], strings.ToLower(f[2])
+				list[i].Signal, _ = strconv.Atoi(f[3])
+			}
+		}
+	}
+	return list
+}
+
+// splitTerse splits a line of nmcli -t output, which escapes ':' and '\\'
+// inside values with a backslash.
+func splitTerse(line string) []string {
+	line = strings.TrimRight(line, "\r")
+	if line == "" {
+		return nil
+	}
+	var (
+		fields []string
+		cur    strings.Builder
+	)
+	for i := 0; i < len(line); i++ {
+		switch c := line[i]; {
+		case c == '\\' && i+1 < len(line):
+			i++
+			cur.WriteByte(line[i])
+		case c == ':':
+			fields = append(fields, cur.String())
+			cur.Reset()
+		default:
+			cur.WriteByte(c)
+		}
+	}
+	return append(fields, cur.String())
+}
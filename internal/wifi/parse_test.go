@@ -0,0 +1,80 @@
+package wifi
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func readTestdata(t *testing.T, name string) string {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestParseNetsh(t *testing.T) {
+	tests := []struct {
+		file string
+		want []Interface
+	}{
+		{"netsh_en-US.txt", []Interface{
+			{Name: "Wi-Fi", Description: "Intel(R) Wi-Fi 6 AX201 160MHz", SSID: "CUMT_Stu", BSSID: "74:ea:cb:11:22:33", Signal: 88, State: StateConnected},
+			{Name: "Wi-Fi 2", Description: "Realtek RTL8811CU Wireless LAN 802.11ac USB NIC", State: StateDisconnected},
+		}},
+		{"netsh_zh-CN.txt", []Interface{
+			{Name: "WLAN", Description: "Intel(R) Wi-Fi 6 AX201 160MHz", SSID: "CUMT_Stu", BSSID: "74:ea:cb:11:22:33", Signal: 72, State: StateConnected},
+			{Name: "WLAN 2", Description: "Realtek RTL8811CU Wireless LAN 802.11ac USB NIC", State: StateConnecting},
+		}},
+	}
+	for _, tt := range tests {
+		got := parseNetsh(readTestdata(t, tt.file))
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s:\n got %+v\nwant %+v", tt.file, got, tt.want)
+		}
+	}
+}
+
+func TestParseIw(t *testing.T) {
+	list := parseIwDev(readTestdata(t, "iw_dev.txt"))
+	want := []Interface{
+		{Name: "wlx00e04c123456", State: StateDisconnected},
+		{Name: "wlp0s20f3", SSID: "CUMT Guest", State: StateConnected},
+	}
+	if !reflect.DeepEqual(list, want) {
+		t.Fatalf("iw dev:\n got %+v\nwant %+v", list, want)
+	}
+
+	parseIwLink(readTestdata(t, "iw_link.txt"), &list[1])
+	want[1].BSSID, want[1].Signal = "74:ea:cb:11:22:33", 88
+	if list[1] != want[1] {
+		t.Errorf("iw link:\n got %+v\nwant %+v", list[1], want[1])
+	}
+
+	parseIwLink(readTestdata(t, "iw_link_disconnected.txt"), &list[0])
+	if list[0].State != StateDisconnected || list[0].Connected() {
+		t.Errorf("disconnected link: %+v", list[0])
+	}
+}
+
+func TestParseNmcli(t *testing.T) {
+	got := parseNmcli(readTestdata(t, "nmcli_device.txt"), readTestdata(t, "nmcli_wifi.txt"))
+	want := []Interface{
+		{Name: "wlp0s20f3", SSID: `Lab:5G \ 2`, BSSID: "74:ea:cb:11:22:33", Signal: 81, State: StateConnected},
+		{Name: "wlx00e04c123456", State: StateConnecting},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("\n got %+v\nwant %+v", got, want)
+	}
+}
+
+func TestQualityFromDBM(t *testing.T) {
+	for dbm, want := range map[int]int{-30: 100, -50: 100, -56: 88, -75: 50, -100: 0, -110: 0} {
+		if got := qualityFromDBM(dbm); got != want {
+			t.Errorf("qualityFromDBM(%d) = %d, want %d", dbm, got, want)
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package wifi
+
+// Interface states, normalized across platforms.
+const (
+	StateConnected    = "connected"
+	StateConnecting   = "connecting"
+	StateDisconnected = "disconnected"
+	StateUnknown      = "unknown"
+)
+
+// Interface is one wireless adapter and its current association.
+type Interface struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	SSID        string `json:"ssid"`
+	BSSID       string `json:"bssid"`
+	Signal      int    `json:"signal"` // quality 0-100
+	State       string `json:"state"`
+}
+
+// Connected reports whether the interface is associated with a network.
+func (i Interface) Connected() bool {
+	return i.State == StateConnected && i.SSID != ""
+}
+
+// Interfaces lists the wireless interfaces of this machine. A machine
+// without Wi-Fi returns an empty list, not an error.
+func Interfaces() ([]Interface, error) {
+	return interfaces()
+}
+
+// CurrentSSID returns the SSID of a connected interface, or "" when none is
+// connected. With several connected interfaces want wins if one of them is
+// on it, so a second adapter cannot hide the campus network.
+func CurrentSSID(want string) (string, error) {
+	list, err := Interfaces()
+	if err != nil {
+		return "", err
+	}
+	first := ""
+	for _, ifc := range list {
+		if !ifc.Connected() {
+			continue
+		}
+		if want != "" && ifc.SSID == want {
+			return ifc.SSID, nil
+		}
+		if first == "" {
+			first = ifc.SSID
+		}
+	}
+	return first, nil
+}
+
+// qualityFromDBM maps a signal level to the 0-100 scale Windows uses:
+// -100 dBm and below is 0, -50 dBm and above is 100.
+func qualityFromDBM(dbm int) int {
+	q := 2 * (dbm + 100)
+	if q < 0 {
+		return 0
+	}
+	if q > 100 {
+		return 100
+	}
+	return q
+}
@@ -0,0 +1,122 @@
+//go:build linux
+
+package wifi
+
+import (
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/godbus/dbus/v5"
+)
+
+const (
+	nmDest       = "org.freedesktop.NetworkManager"
+	nmPath       = "/org/freedesktop/NetworkManager"
+	nmDevice     = nmDest + ".Device"
+	nmWireless   = nmDest + ".Device.Wireless"
+	nmAP         = nmDest + ".AccessPoint"
+	nmTypeWifi   = 2
+	nmActivated  = 100
+	nmDisconnect = 30
+)
+
+// interfaces asks NetworkManager over D-Bus, then through nmcli, and falls
+// back to iw when it is not running.
+func interfaces() ([]Interface, error) {
+	list, err := fromNetworkManager()
+	if err == nil {
+		return list, nil
+	}
+	if list, nmErr := fromNmcli(); nmErr == nil {
+		return list, nil
+	}
+	if list, iwErr := fromIw(); iwErr == nil {
+		return list, nil
+	}
+	return nil, err
+}
+
+func fromNetworkManager() ([]Interface, error) {
+	conn, err := dbus.SystemBus()
+	if err != nil {
+		return nil, err
+	}
+	var devices []dbus.ObjectPath
+	if err := conn.Object(nmDest, nmPath).Call(nmDest+".GetDevices", 0).Store(&devices); err != nil {
+		return nil, err
+	}
+
+	var list []Interface
+	for _, path := range devices {
+		dev := conn.Object(nmDest, path)
+		var devType uint32
+		if err := dev.StoreProperty(nmDevice+".DeviceType", &devType); err != nil || devType != nmTypeWifi {
+			continue
+		}
+		ifc := Interface{State: StateUnknown}
+		_ = dev.StoreProperty(nmDevice+".Interface", &ifc.Name)
+		var state uint32
+		if err := dev.StoreProperty(nmDevice+".State", &state); err == nil {
+			ifc.State = nmState(state)
+		}
+
+		var apPath dbus.ObjectPath
+		if err := dev.StoreProperty(nmWireless+".ActiveAccessPoint", &apPath); err == nil && apPath != "/" {
+			ap := conn.Object(nmDest, apPath)
+			var ssid []byte
+			var strength byte
+			_ = ap.StoreProperty(nmAP+".Ssid", &ssid)
+			_ = ap.StoreProperty(nmAP+".HwAddress", &ifc.BSSID)
+			_ = ap.StoreProperty(nmAP+".Strength", &strength)
+			ifc.SSID, ifc.BSSID, ifc.Signal = string(ssid), strings.ToLower(ifc.BSSID), int(strength)
+		}
+		list = append(list, ifc)
+	}
+	return list, nil
+}
+
+// nmState maps NMDeviceState.
+func nmState(s uint32) string {
+	switch {
+	case s == nmActivated:
+		return StateConnected
+	case s > nmDisconnect && s < nmActivated:
+		return StateConnecting
+	}
+	return StateDisconnected
+}
+
+func fromNmcli() ([]Interface, error) {
+	devices, err := nmcli("-t", "-f", "DEVICE,TYPE,STATE", "device", "status")
+	if err != nil {
+		return nil, err
+	}
+	aps, err := nmcli("-t", "-f", "ACTIVE,SSID,BSSID,SIGNAL,DEVICE", "device", "wifi", "list", "--rescan", "no")
+	if err != nil {
+		return nil, err
+	}
+	return parseNmcli(devices, aps), nil
+}
+
+// nmcli runs nmcli in the C locale, whose state names parseNmcli knows.
+func nmcli(args ...string) (string, error) {
+	cmd := exec.Command("nmcli", args...)
+	cmd.Env = append(os.Environ(), "LC_ALL=C")
+	out, err := cmd.Output()
+	return string(out), err
+}
+
+func fromIw() ([]Interface, error) {
+	out, err := exec.Command("iw", "dev").Output()
+	if err != nil {
+		return nil, err
+	}
+	list := parseIwDev(string(out))
+	for i := range list {
+		if link, err := exec.Command("iw", "dev", list[i].Name, "link").Output(); err == nil {
+			parseIwLink(string(link), &list[i])
+		}
+	}
+	return list, nil
+}
@@ -0,0 +1,9 @@
+//go:build !linux && !windows
+
+package wifi
+
+import "errors"
+
+func interfaces() ([]Interface, error) {
+	return nil, errors.New("wifi: not supported on this platform")
+}
@@ -0,0 +1,183 @@
+//go:build windows
+
+package wifi
+
+import (
+	"bytes"
+	"errors"
+	"net"
+	"os/exec"
+	"strings"
+	"syscall"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+var (
+	wlanapi                = windows.NewLazySystemDLL("wlanapi.dll")
+	procWlanOpenHandle     = wlanapi.NewProc("WlanOpenHandle")
+	procWlanCloseHandle    = wlanapi.NewProc("WlanCloseHandle")
+	procWlanEnumInterfaces = wlanapi.NewProc("WlanEnumInterfaces")
+	procWlanQueryInterface = wlanapi.NewProc("WlanQueryInterface")
+	procWlanFreeMemory     = wlanapi.NewProc("WlanFreeMemory")
+)
+
+const (
+	wlanClientVersion           = 2
+	wlanOpcodeCurrentConnection = 7
+
+	wlanStateConnected      = 1
+	wlanStateDisconnected   = 4
+	wlanStateAssociating    = 5
+	wlanStateDiscovering    = 6
+	wlanStateAuthenticating = 7
+)
+
+// wlanInterfaceInfo is WLAN_INTERFACE_INFO.
+type wlanInterfaceInfo struct {
+	GUID        windows.GUID
+	Description [256]uint16
+	State       uint32
+}
+
+// wlanInterfaceInfoList is WLAN_INTERFACE_INFO_LIST.
+type wlanInterfaceInfoList struct {
+	Count uint32
+	Index uint32
+	Items [1]wlanInterfaceInfo
+}
+
+// wlanConnectionAttributes is WLAN_CONNECTION_ATTRIBUTES up to the
+// association attributes; the security attributes are not needed.
+type wlanConnectionAttributes struct {
+	State       uint32
+	Mode        uint32
+	ProfileName [256]uint16
+	SSIDLength  uint32
+	SSID        [32]byte
+	BSSType     uint32
+	BSSID       [6]byte
+	PhyType     uint32
+	PhyIndex    uint32
+	Signal      uint32
+	RxRate      uint32
+	TxRate      uint32
+}
+
+// interfaces uses the Native Wifi API and falls back to netsh when
+// wlanapi.dll is missing (Server editions without the WLAN feature).
+func interfaces() ([]Interface, error) {
+	if err := wlanapi.Load(); err != nil {
+		return fromNetsh()
+	}
+	return fromWlanAPI()
+}
+
+func fromWlanAPI() ([]Interface, error) {
+	var negotiated uint32
+	var handle windows.Handle
+	if r, _, _ := procWlanOpenHandle.Call(wlanClientVersion, 0,
+		uintptr(unsafe.Pointer(&negotiated)), uintptr(unsafe.Pointer(&handle))); r != 0 {
+		if syscall.Errno(r) == windows.ERROR_SERVICE_NOT_ACTIVE {
+			return nil, nil // WLAN AutoConfig stopped: no usable Wi-Fi
+		}
+		return nil, syscall.Errno(r)
+	}
+	defer procWlanCloseHandle.Call(uintptr(handle), 0)
+
+	var infoList *wlanInterfaceInfoList
+	if r, _, _ := procWlanEnumInterfaces.Call(uintptr(handle), 0, uintptr(unsafe.Pointer(&infoList))); r != 0 {
+		return nil, syscall.Errno(r)
+	}
+	defer procWlanFreeMemory.Call(uintptr(unsafe.Pointer(infoList)))
+	if infoList.Count == 0 {
+		return nil, nil
+	}
+	infos := unsafe.Slice(&infoList.Items[0], infoList.Count)
+	names := adapterNames()
+
+	list := make([]Interface, 0, len(infos))
+	for i := range infos {
+		info := &infos[i]
+		ifc := Interface{
+			Description: windows.UTF16ToString(info.Description[:]),
+			State:       wlanState(info.State),
+		}
+		ifc.Name = names[strings.ToLower(info.GUID.String())]
+		if ifc.Name == "" {
+			ifc.Name = ifc.Description
+		}
+		if info.State == wlanStateConnected {
+			queryConnection(handle, &info.GUID, &ifc)
+		}
+		list = append(list, ifc)
+	}
+	return list, nil
+}
+
+func queryConnection(handle windows.Handle, guid *windows.GUID, ifc *Interface) {
+	var size uint32
+	var attr *wlanConnectionAttributes
+	r, _, _ := procWlanQueryInterface.Call(uintptr(handle), uintptr(unsafe.Pointer(guid)),
+		wlanOpcodeCurrentConnection, 0, uintptr(unsafe.Pointer(&size)), uintptr(unsafe.Pointer(&attr)), 0)
+	if r != 0 || attr == nil {
+		return
+	}
+	defer procWlanFreeMemory.Call(uintptr(unsafe.Pointer(attr)))
+	if uintptr(size) < unsafe.Sizeof(*attr) {
+		return
+	}
+	n := attr.SSIDLength
+	if n > uint32(len(attr.SSID)) {
+		n = uint32(len(attr.SSID))
+	}
+	ifc.SSID = string(attr.SSID[:n])
+	ifc.BSSID = net.HardwareAddr(attr.BSSID[:]).String()
+	ifc.Signal = int(attr.Signal)
+}
+
+func wlanState(s uint32) string {
+	switch s {
+	case wlanStateConnected:
+		return StateConnected
+	case wlanStateAssociating, wlanStateDiscovering, wlanStateAuthenticating:
+		return StateConnecting
+	case wlanStateDisconnected:
+		return StateDisconnected
+	}
+	return StateUnknown
+}
+
+// adapterNames maps lower-case "{guid}" adapter names to the friendly names
+// ("WLAN", "Wi-Fi") that net.Interfaces and the config use.
+func adapterNames() map[string]string {
+	names := make(map[string]string)
+	size := uint32(15 * 1024)
+	for {
+		buf := make([]byte, size)
+		aa := (*windows.IpAdapterAddresses)(unsafe.Pointer(&buf[0]))
+		err := windows.GetAdaptersAddresses(windows.AF_UNSPEC, 0, 0, aa, &size)
+		if errors.Is(err, windows.ERROR_BUFFER_OVERFLOW) {
+			continue
+		}
+		if err != nil {
+			return names
+		}
+		for ; aa != nil; aa = aa.Next {
+			guid := strings.ToLower(windows.BytePtrToString(aa.AdapterName))
+			names[guid] = windows.UTF16PtrToString(aa.FriendlyName)
+		}
+		return names
+	}
+}
+
+func fromNetsh() ([]Interface, error) {
+	cmd := exec.Command("netsh", "wlan", "show", "interfaces")
+	cmd.SysProcAttr = &syscall.SysProcAttr{HideWindow: true}
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, err
+	}
+	return parseNetsh(string(bytes.ToValidUTF8(out, nil))), nil
+}